@@ -0,0 +1,148 @@
+package tmcl
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// analogFullScale is the raw value of an analog input of the TMCM-351 at full scale (10 bit)
+const analogFullScale = 1023
+
+// analogCalibrationBank is the global parameter bank of the user variables holding calibrations
+const analogCalibrationBank = 2
+
+// AnalogConfig describes the calibration of an analog input
+type AnalogConfig struct {
+	// Gain and Offset convert the raw ADC value to engineering units:
+	// value = raw * Gain + Offset. A Gain of 0 is treated as 1.
+	Gain   float64
+	Offset float64
+
+	// Unit is the engineering unit of the converted value, e.g. "bar" or "°C"
+	Unit string
+
+	// Samples is the number of readings averaged, 0 or 1 for a single reading
+	Samples int
+
+	// SampleInterval is the pause between two readings when averaging
+	SampleInterval time.Duration
+}
+
+// VoltageInput returns the configuration of an input measuring 0 to fullScale volts
+func VoltageInput(fullScale float64) AnalogConfig {
+	return AnalogConfig{Gain: fullScale / analogFullScale, Unit: "V"}
+}
+
+// CurrentInput returns the configuration of an input measuring the current of a current loop
+// in mA over a shunt resistor, the input measuring 0 to fullScale volts
+func CurrentInput(fullScale float64, shuntOhms float64) AnalogConfig {
+	return AnalogConfig{Gain: fullScale / analogFullScale / shuntOhms * 1000, Unit: "mA"}
+}
+
+// Calibrate returns the configuration with Gain and Offset set from two reference points,
+// the raw readings raw1 and raw2 of the known values value1 and value2
+func (c AnalogConfig) Calibrate(raw1, value1, raw2, value2 float64) (AnalogConfig, error) {
+	if raw1 == raw2 {
+		return c, errors.New("calibration needs two different raw readings")
+	}
+	c.Gain = (value2 - value1) / (raw2 - raw1)
+	c.Offset = value1 - raw1*c.Gain
+	return c, nil
+}
+
+// Convert converts a raw ADC value to engineering units
+func (c AnalogConfig) Convert(raw float64) float64 {
+	gain := c.Gain
+	if gain == 0 {
+		gain = 1
+	}
+	return raw*gain + c.Offset
+}
+
+// ReadAnalogRaw reads an analog input of AnalogInputBank, averaged over the given number of
+// samples. Without interval all samples are read holding the command lock only once.
+func (q *TMCL) ReadAnalogRaw(port byte, samples int, interval time.Duration) (float64, error) {
+	if samples < 1 {
+		samples = 1
+	}
+	if samples > 1 && interval <= 0 {
+		params := make([]PollParam, samples)
+		for i := range params {
+			params[i] = IOPoll(port, AnalogInputBank)
+		}
+		key, frames := q.encodePollParams(params)
+		values := make([]int, samples)
+		if err := q.pollBulk(key, frames, values, make([]byte, samples)); err != nil {
+			return 0, err
+		}
+		var sum int
+		for _, v := range values {
+			sum += v
+		}
+		return float64(sum) / float64(samples), nil
+	}
+
+	var sum int
+	for i := 0; i < samples; i++ {
+		if i != 0 && interval > 0 {
+			time.Sleep(interval)
+		}
+		v, err := q.GIO(port, AnalogInputBank)
+		if err != nil {
+			return 0, err
+		}
+		sum += v
+	}
+	return float64(sum) / float64(samples), nil
+}
+
+// ReadAnalog reads an analog input of AnalogInputBank and converts it to engineering units
+func (q *TMCL) ReadAnalog(port byte, cfg AnalogConfig) (float64, error) {
+	raw, err := q.ReadAnalogRaw(port, cfg.Samples, cfg.SampleInterval)
+	if err != nil {
+		return 0, err
+	}
+	return cfg.Convert(raw), nil
+}
+
+// StoreAnalogCalibration writes Gain and Offset of the configuration to the two user variables
+// starting at variable, as float32 bit patterns, and stores them in the EEPROM
+func (q *TMCL) StoreAnalogCalibration(variable byte, cfg AnalogConfig) error {
+	if int(variable)+1 >= userVariables {
+		return errors.Errorf("user variable %d out of range", variable)
+	}
+	for i, v := range []float64{cfg.Gain, cfg.Offset} {
+		index := variable + byte(i)
+		if err := q.SGP(index, analogCalibrationBank, int(int32(math.Float32bits(float32(v))))); err != nil {
+			return err
+		}
+		if _, err := q.STGP(index, analogCalibrationBank); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadAnalogCalibration returns the configuration with Gain and Offset read from the user
+// variables written by StoreAnalogCalibration. If both variables are 0, as when they were
+// never written, no calibration is stored and the configuration is returned unchanged.
+func (q *TMCL) LoadAnalogCalibration(variable byte, cfg AnalogConfig) (AnalogConfig, error) {
+	if int(variable)+1 >= userVariables {
+		return cfg, errors.Errorf("user variable %d out of range", variable)
+	}
+	var values [2]float64
+	for i := range values {
+		v, err := q.GGPUnsigned(variable+byte(i), analogCalibrationBank)
+		if err != nil {
+			return cfg, err
+		}
+		values[i] = float64(math.Float32frombits(v))
+	}
+	if values[0] == 0 && values[1] == 0 {
+		return cfg, nil
+	}
+	cfg.Gain, cfg.Offset = values[0], values[1]
+	return cfg, nil
+}
@@ -0,0 +1,206 @@
+// Package assembler translates TMCL assembly as written in the TMCL-IDE into instructions that
+// can be downloaded to a module with DownloadProgram
+package assembler
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// statement is an instruction whose operands are not resolved yet
+type statement struct {
+	line     int
+	mnemonic string
+	syntax   syntax
+	operands []string
+}
+
+// Assemble translates TMCL assembly into instructions. Each line holds one instruction, e.g.
+// "MVP ABS, 0, 1000", optionally preceded by a label ("Loop:"). Constants are defined with
+// "Name = value". Comments start with "//" or ";". Numbers are decimal or hexadecimal with
+// prefix "$" or "0x". The address of an instruction is its index in the result.
+func Assemble(src string) ([]protocol.Instruction, error) {
+	labels := make(map[string]int)
+	constants := make(map[string]int)
+	var statements []statement
+
+	// first pass: collect labels and constants
+	sc := bufio.NewScanner(strings.NewReader(src))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := stripComment(sc.Text())
+
+		// labels
+		for {
+			i := strings.IndexByte(line, ':')
+			if i < 0 || strings.ContainsAny(line[:i], " \t,") {
+				break
+			}
+			name := line[:i]
+			if !validName(name) {
+				return nil, errors.Errorf("line %d: invalid label %q", lineNo, name)
+			}
+			if _, ok := labels[name]; ok {
+				return nil, errors.Errorf("line %d: label %q defined twice", lineNo, name)
+			}
+			labels[name] = len(statements)
+			line = strings.TrimSpace(line[i+1:])
+		}
+		if line == "" {
+			continue
+		}
+
+		// constants
+		if i := strings.IndexByte(line, '='); i >= 0 {
+			name := strings.TrimSpace(line[:i])
+			if !validName(name) {
+				return nil, errors.Errorf("line %d: invalid constant %q", lineNo, name)
+			}
+			v, err := parseNumber(strings.TrimSpace(line[i+1:]), constants)
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", lineNo)
+			}
+			constants[name] = v
+			continue
+		}
+
+		// instructions
+		st, err := parseStatement(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNo)
+		}
+		st.line = lineNo
+		statements = append(statements, st)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	// second pass: resolve operands
+	program := make([]protocol.Instruction, len(statements))
+	for i, st := range statements {
+		in, err := st.resolve(labels, constants)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", st.line)
+		}
+		program[i] = in
+	}
+	return program, nil
+}
+
+// parseStatement splits an instruction line into mnemonic and operands
+func parseStatement(line string) (statement, error) {
+	mnemonic := line
+	var rest string
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		mnemonic, rest = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	mnemonic = strings.ToUpper(mnemonic)
+	syn, ok := syntaxes[mnemonic]
+	if !ok {
+		return statement{}, errors.Errorf("unknown instruction %q", mnemonic)
+	}
+
+	var operands []string
+	if rest != "" {
+		for _, s := range strings.Split(rest, ",") {
+			operands = append(operands, strings.TrimSpace(s))
+		}
+	}
+	if len(operands) != len(syn.operands) {
+		return statement{}, errors.Errorf("%s expects %d operands, got %d", mnemonic, len(syn.operands), len(operands))
+	}
+	return statement{mnemonic: mnemonic, syntax: syn, operands: operands}, nil
+}
+
+// resolve converts the operands of a statement into an instruction
+func (st statement) resolve(labels map[string]int, constants map[string]int) (protocol.Instruction, error) {
+	in := protocol.Instruction{Cmd: st.syntax.cmd}
+	for i, op := range st.syntax.operands {
+		s := st.operands[i]
+		v, ok := op.symbols[strings.ToUpper(s)]
+		if !ok && op.label {
+			v, ok = labels[s]
+		}
+		if !ok {
+			var err error
+			if v, err = parseNumber(s, constants); err != nil && op.label && validName(s) {
+				return in, errors.Errorf("%s: unknown label %q", st.mnemonic, s)
+			} else if err != nil {
+				return in, errors.Wrapf(err, "%s operand %d", st.mnemonic, i+1)
+			}
+		}
+
+		switch op.field {
+		case fieldType, fieldMotor:
+			if v < 0 || v > 255 {
+				return in, errors.Errorf("%s operand %d: %d out of range 0..255", st.mnemonic, i+1, v)
+			}
+			if op.field == fieldType {
+				in.Type = byte(v)
+			} else {
+				in.MotorBank = byte(v)
+			}
+		case fieldValue:
+			in.Value = v
+		}
+	}
+	return in, nil
+}
+
+// parseNumber parses a decimal or hexadecimal number or a constant
+func parseNumber(s string, constants map[string]int) (int, error) {
+	if v, ok := constants[s]; ok {
+		return v, nil
+	}
+	neg := strings.HasPrefix(s, "-")
+	t := strings.TrimPrefix(s, "-")
+	base := 10
+	switch {
+	case strings.HasPrefix(t, "$"):
+		t, base = t[1:], 16
+	case strings.HasPrefix(t, "0x"), strings.HasPrefix(t, "0X"):
+		t, base = t[2:], 16
+	}
+	v, err := strconv.ParseInt(t, base, 64)
+	if err != nil || v > 1<<32-1 {
+		return 0, errors.Errorf("invalid number %q", s)
+	}
+	if neg {
+		v = -v
+	}
+	if v < -1<<31 {
+		return 0, errors.Errorf("invalid number %q", s)
+	}
+	return int(int32(v)), nil
+}
+
+// stripComment removes comments and surrounding white space from a line
+func stripComment(line string) string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// validName returns true for names of labels and constants
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package assembler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []protocol.Instruction
+		err  string
+	}{
+		{
+			name: "move modes",
+			src:  "MVP ABS, 0, 1000\nMVP REL, 1, -20\nMVP COORD, 2, 3",
+			want: []protocol.Instruction{
+				{Cmd: 4, Type: 0, MotorBank: 0, Value: 1000},
+				{Cmd: 4, Type: 1, MotorBank: 1, Value: -20},
+				{Cmd: 4, Type: 2, MotorBank: 2, Value: 3},
+			},
+		},
+		{
+			name: "labels and jumps",
+			src:  "Loop: GAP 1, 0\n\tCOMP 100\n\tJC LT, Loop\n\tJA End\nEnd: STOP",
+			want: []protocol.Instruction{
+				{Cmd: 6, Type: 1},
+				{Cmd: 20, Value: 100},
+				{Cmd: 21, Type: 6, Value: 0},
+				{Cmd: 22, Value: 4},
+				{Cmd: 28},
+			},
+		},
+		{
+			name: "label on a line of its own",
+			src:  "Start:\nA: B: RSUB\nCSUB Start\nCSUB B",
+			want: []protocol.Instruction{
+				{Cmd: 24},
+				{Cmd: 23, Value: 0},
+				{Cmd: 23, Value: 0},
+			},
+		},
+		{
+			name: "constants and numbers",
+			src:  "Speed = $100\nMask = 0xFF\nNeg = -5\nSAP 4, 0, Speed\nCALC AND, Mask\nROL 1, Neg\nSGP 0, 2, 4294967295",
+			want: []protocol.Instruction{
+				{Cmd: 5, Type: 4, Value: 256},
+				{Cmd: 19, Type: 5, Value: 255},
+				{Cmd: 2, MotorBank: 1, Value: -5},
+				{Cmd: 9, MotorBank: 2, Value: -1},
+			},
+		},
+		{
+			name: "comments and case",
+			src:  "; header\nmst 2 // stop\n  wait ticks, 0, 10 ; wait\n\n// end",
+			want: []protocol.Instruction{
+				{Cmd: 3, MotorBank: 2},
+				{Cmd: 27, Type: 0, Value: 10},
+			},
+		},
+		{
+			name: "symbols",
+			src:  "RFS STATUS, 0\nCALCX SWAP\nCLE EDV\nWAIT POS, 1, 0",
+			want: []protocol.Instruction{
+				{Cmd: 13, Type: 2},
+				{Cmd: 33, Type: 10},
+				{Cmd: 36, Type: 3},
+				{Cmd: 27, Type: 1, MotorBank: 1},
+			},
+		},
+		{
+			name: "jump on deviation error",
+			src:  "L: JC EDV, L",
+			want: []protocol.Instruction{{Cmd: 21, Type: 10, Value: 0}},
+		},
+		{
+			name: "jump on position error",
+			src:  "L: JC EPO, L",
+			want: []protocol.Instruction{{Cmd: 21, Type: 11, Value: 0}},
+		},
+		{name: "unknown instruction", src: "FOO 1", err: `line 1: unknown instruction "FOO"`},
+		{name: "operand count", src: "STOP\nSAP 4, 0", err: "line 2: SAP expects 3 operands, got 2"},
+		{name: "unknown label", src: "JA Nowhere", err: `line 1: JA: unknown label "Nowhere"`},
+		{name: "label twice", src: "A: STOP\nA: STOP", err: `line 2: label "A" defined twice`},
+		{name: "invalid label", src: "1x: STOP", err: `line 1: invalid label "1x"`},
+		{name: "invalid constant", src: "a b = 1", err: `line 1: invalid constant "a b"`},
+		{name: "motor out of range", src: "MST 256", err: "line 1: MST operand 1: 256 out of range 0..255"},
+		{name: "invalid number", src: "COMP 12z", err: `line 1: COMP operand 1: invalid number "12z"`},
+		{name: "number too large", src: "COMP 4294967296", err: `line 1: COMP operand 1: invalid number "4294967296"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Assemble(tt.src)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package assembler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// mnemonics maps command numbers to mnemonics, built from syntaxes
+var mnemonics = func() map[byte]string {
+	m := make(map[byte]string, len(syntaxes))
+	for name, syn := range syntaxes {
+		m[syn.cmd] = name
+	}
+	return m
+}()
+
+// Decode reads a program from concatenated request telegrams, e.g. recorded while a program
+// was downloaded with the TMCL-IDE
+func Decode(blob []byte) ([]protocol.Instruction, error) {
+	if len(blob)%protocol.FrameSize != 0 {
+		return nil, errors.Errorf("length %d is not a multiple of %d", len(blob), protocol.FrameSize)
+	}
+	program := make([]protocol.Instruction, 0, len(blob)/protocol.FrameSize)
+	for i := 0; i < len(blob); i += protocol.FrameSize {
+		t := blob[i : i+protocol.FrameSize]
+		if !protocol.ValidChecksum(t) {
+			return nil, errors.Errorf("instruction %d: checksum invalid", i/protocol.FrameSize)
+		}
+		program = append(program, protocol.DecodeInstruction(t))
+	}
+	return program, nil
+}
+
+// Disassemble renders a program as TMCL assembly which Assemble translates back into the same
+// instructions. Jump targets get labels named after their address, unknown commands are
+// written as comment.
+func Disassemble(program []protocol.Instruction) string {
+	// collect jump targets
+	labels := make(map[int]string)
+	for _, in := range program {
+		if syn, ok := syntaxes[mnemonics[in.Cmd]]; ok && hasLabel(syn) && in.Value >= 0 && in.Value < len(program) {
+			labels[in.Value] = "L" + strconv.Itoa(in.Value)
+		}
+	}
+
+	var sb strings.Builder
+	for addr, in := range program {
+		if label, ok := labels[addr]; ok {
+			sb.WriteString(label)
+			sb.WriteString(":\n")
+		}
+		sb.WriteString("\t")
+		sb.WriteString(Format(in, labels))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Format renders one instruction as TMCL assembly, jump addresses found in labels are
+// replaced by the label
+func Format(in protocol.Instruction, labels map[int]string) string {
+	name, ok := mnemonics[in.Cmd]
+	if !ok {
+		return fmt.Sprintf("// unknown command %d, type %d, motor/bank %d, value %d", in.Cmd, in.Type, in.MotorBank, in.Value)
+	}
+
+	syn := syntaxes[name]
+	ops := make([]string, len(syn.operands))
+	for i, op := range syn.operands {
+		var v int
+		switch op.field {
+		case fieldType:
+			v = int(in.Type)
+		case fieldMotor:
+			v = int(in.MotorBank)
+		case fieldValue:
+			v = in.Value
+		}
+		ops[i] = strconv.Itoa(v)
+		if s, ok := symbolName(op.symbols, v); ok {
+			ops[i] = s
+		} else if label, ok := labels[v]; ok && op.label {
+			ops[i] = label
+		}
+	}
+	if len(ops) == 0 {
+		return name
+	}
+	return name + " " + strings.Join(ops, ", ")
+}
+
+// hasLabel returns true if an operand of the syntax is a jump address
+func hasLabel(syn syntax) bool {
+	for _, op := range syn.operands {
+		if op.label {
+			return true
+		}
+	}
+	return false
+}
+
+// symbolName returns the symbol of a value, the alphabetically first one if there are several
+func symbolName(symbols map[string]int, v int) (string, bool) {
+	var names []string
+	for name, x := range symbols {
+		if x == v {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
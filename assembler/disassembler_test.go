@@ -0,0 +1,118 @@
+package assembler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+func TestFormat(t *testing.T) {
+	labels := map[int]string{3: "L3"}
+	tests := []struct {
+		in   protocol.Instruction
+		want string
+	}{
+		{protocol.Instruction{Cmd: 4, Type: 1, MotorBank: 2, Value: -3}, "MVP REL, 2, -3"},
+		{protocol.Instruction{Cmd: 5, Type: 4, MotorBank: 0, Value: 100}, "SAP 4, 0, 100"},
+		{protocol.Instruction{Cmd: 19, Type: 0, Value: 1}, "CALC ADD, 1"},
+		{protocol.Instruction{Cmd: 21, Type: 2, Value: 3}, "JC EQ, L3"},
+		{protocol.Instruction{Cmd: 22, Value: 7}, "JA 7"},
+		{protocol.Instruction{Cmd: 28}, "STOP"},
+		{protocol.Instruction{Cmd: 4, Type: 9, Value: 1}, "MVP 9, 0, 1"},
+		{protocol.Instruction{Cmd: 200, Type: 1, MotorBank: 2, Value: 3}, "// unknown command 200, type 1, motor/bank 2, value 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Format(tt.in, labels); got != tt.want {
+				t.Errorf("got %q", got)
+			}
+		})
+	}
+}
+
+func TestDisassembleRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"straight", "SAP 4, 0, 500\nMVP ABS, 0, 1000\nWAIT POS, 0, 0\nSTOP"},
+		{"loop", "Loop: GIO 0, 0\nCOMP 0\nJC EQ, Loop\nSIO 1, 2, 1\nJA Loop"},
+		{"subroutine", "CSUB Sub\nSTOP\nSub: CALC SUB, 1\nRSUB"},
+		{"interrupt", "VECT 3, Handler\nEI 3\nEI 255\nIdle: JA Idle\nHandler: MST 0\nRETI"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Assemble(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := Disassemble(program)
+			again, err := Assemble(text)
+			if err != nil {
+				t.Fatalf("%v in\n%s", err, text)
+			}
+			if !reflect.DeepEqual(again, program) {
+				t.Errorf("got %+v from\n%s\nwant %+v", again, text, program)
+			}
+		})
+	}
+}
+
+func TestDisassembleLabels(t *testing.T) {
+	program := []protocol.Instruction{
+		{Cmd: 22, Value: 1},
+		{Cmd: 22, Value: 1},
+		{Cmd: 22, Value: 9},
+	}
+	want := "\tJA L1\nL1:\n\tJA L1\n\tJA 9\n"
+	if got := Disassemble(program); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	frames := func(program ...protocol.Instruction) []byte {
+		blob := make([]byte, len(program)*protocol.FrameSize)
+		for i, in := range program {
+			in.Encode(blob[i*protocol.FrameSize:], 1)
+		}
+		return blob
+	}
+	program := []protocol.Instruction{
+		{Cmd: 4, Type: 0, MotorBank: 1, Value: -1000},
+		{Cmd: 28},
+	}
+	corrupt := frames(program...)
+	corrupt[protocol.FrameSize+3]++
+
+	tests := []struct {
+		name string
+		blob []byte
+		want []protocol.Instruction
+		err  string
+	}{
+		{name: "empty", blob: nil, want: []protocol.Instruction{}},
+		{name: "program", blob: frames(program...), want: program},
+		{name: "length", blob: frames(program...)[:10], err: "length 10 is not a multiple of 9"},
+		{name: "checksum", blob: corrupt, err: "instruction 1: checksum invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.blob)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
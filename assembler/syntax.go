@@ -0,0 +1,78 @@
+package assembler
+
+// field is the part of an instruction an operand is written to
+type field int
+
+const (
+	fieldType field = iota
+	fieldMotor
+	fieldValue
+)
+
+// operand describes one operand of a mnemonic
+type operand struct {
+	field   field
+	symbols map[string]int
+	// label is set for operands that may be given as label, i.e. jump addresses
+	label bool
+}
+
+// syntax describes the operands of a mnemonic
+type syntax struct {
+	cmd      byte
+	operands []operand
+}
+
+var (
+	moveModes     = map[string]int{"ABS": 0, "REL": 1, "COORD": 2}
+	rfsTypes      = map[string]int{"START": 0, "STOP": 1, "STATUS": 2}
+	calcOps       = map[string]int{"ADD": 0, "SUB": 1, "MUL": 2, "DIV": 3, "MOD": 4, "AND": 5, "OR": 6, "XOR": 7, "NOT": 8, "LOAD": 9}
+	calcxOps      = map[string]int{"ADD": 0, "SUB": 1, "MUL": 2, "DIV": 3, "MOD": 4, "AND": 5, "OR": 6, "XOR": 7, "NOT": 8, "LOAD": 9, "SWAP": 10}
+	conditions    = map[string]int{"ZE": 0, "NZ": 1, "EQ": 2, "NE": 3, "GT": 4, "GE": 5, "LT": 6, "LE": 7, "ETO": 8, "EAL": 9, "EDV": 10, "EPO": 11, "ESD": 12}
+	waitTypes     = map[string]int{"TICKS": 0, "POS": 1, "REFSW": 2, "LIMSW": 3, "RFS": 4}
+	errorFlags    = map[string]int{"ALL": 0, "ETO": 1, "EAL": 2, "EDV": 3, "EPO": 4, "ESD": 5}
+	typeOperand   = operand{field: fieldType}
+	motorOperand  = operand{field: fieldMotor}
+	valueOperand  = operand{field: fieldValue}
+	targetOperand = operand{field: fieldValue, label: true}
+)
+
+// syntaxes are the mnemonics known to the assembler
+var syntaxes = map[string]syntax{
+	"ROR":   {1, []operand{motorOperand, valueOperand}},
+	"ROL":   {2, []operand{motorOperand, valueOperand}},
+	"MST":   {3, []operand{motorOperand}},
+	"MVP":   {4, []operand{{field: fieldType, symbols: moveModes}, motorOperand, valueOperand}},
+	"SAP":   {5, []operand{typeOperand, motorOperand, valueOperand}},
+	"GAP":   {6, []operand{typeOperand, motorOperand}},
+	"STAP":  {7, []operand{typeOperand, motorOperand}},
+	"RSAP":  {8, []operand{typeOperand, motorOperand}},
+	"SGP":   {9, []operand{typeOperand, motorOperand, valueOperand}},
+	"GGP":   {10, []operand{typeOperand, motorOperand}},
+	"STGP":  {11, []operand{typeOperand, motorOperand}},
+	"RSGP":  {12, []operand{typeOperand, motorOperand}},
+	"RFS":   {13, []operand{{field: fieldType, symbols: rfsTypes}, motorOperand}},
+	"SIO":   {14, []operand{typeOperand, motorOperand, valueOperand}},
+	"GIO":   {15, []operand{typeOperand, motorOperand}},
+	"CALC":  {19, []operand{{field: fieldType, symbols: calcOps}, valueOperand}},
+	"COMP":  {20, []operand{valueOperand}},
+	"JC":    {21, []operand{{field: fieldType, symbols: conditions}, targetOperand}},
+	"JA":    {22, []operand{targetOperand}},
+	"CSUB":  {23, []operand{targetOperand}},
+	"RSUB":  {24, nil},
+	"EI":    {25, []operand{typeOperand}},
+	"DI":    {26, []operand{typeOperand}},
+	"WAIT":  {27, []operand{{field: fieldType, symbols: waitTypes}, motorOperand, valueOperand}},
+	"STOP":  {28, nil},
+	"SAC":   {29, []operand{typeOperand, motorOperand, valueOperand}},
+	"SCO":   {30, []operand{typeOperand, motorOperand, valueOperand}},
+	"GCO":   {31, []operand{typeOperand, motorOperand}},
+	"CCO":   {32, []operand{typeOperand, motorOperand}},
+	"CALCX": {33, []operand{{field: fieldType, symbols: calcxOps}}},
+	"AAP":   {34, []operand{typeOperand, motorOperand}},
+	"AGP":   {35, []operand{typeOperand, motorOperand}},
+	"CLE":   {36, []operand{{field: fieldType, symbols: errorFlags}}},
+	"VECT":  {37, []operand{typeOperand, targetOperand}},
+	"RETI":  {38, nil},
+	"ACO":   {39, []operand{typeOperand, motorOperand}},
+}
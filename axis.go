@@ -0,0 +1,22 @@
+package tmcl
+
+import "github.com/raceresult/go-tmcl/motion"
+
+// Mechanics describes the drive train of a motor, used to convert physical units
+type Mechanics = motion.Mechanics
+
+// Axis is a motor whose positions and velocities are given in physical units. The conversions
+// are those of motion.Axis, Axis adds the configuration of the motor.
+type Axis struct {
+	*motion.Axis
+}
+
+// NewAxis creates an axis for a motor of a board
+func NewAxis(b Board, motor byte, m Mechanics) *Axis {
+	return &Axis{motion.NewAxis(b, motor, m)}
+}
+
+// Axis returns a motor of the board whose positions and velocities are given in physical units
+func (q *TMCL) Axis(motor byte, m Mechanics) *Axis {
+	return NewAxis(q, motor, m)
+}
@@ -0,0 +1,118 @@
+package tmcl
+
+import (
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// AxisConfig is the motion setup of a motor as typed data, values are in the units of the
+// corresponding axis parameters
+type AxisConfig struct {
+	MaxSpeed          int  `json:"maxSpeed"`
+	MaxAccel          int  `json:"maxAccel"`
+	MinSpeed          int  `json:"minSpeed"`
+	RunCurrent        int  `json:"runCurrent"`
+	StandbyCurrent    int  `json:"standbyCurrent"`
+	Microsteps        int  `json:"microsteps"` // microsteps per full step, a power of 2
+	RampDivisor       int  `json:"rampDivisor"`
+	PulseDivisor      int  `json:"pulseDivisor"`
+	FreewheelingDelay int  `json:"freewheelingDelay"`
+	SoftStop          bool `json:"softStop"`
+	RightLimitDisable bool `json:"rightLimitDisable"`
+	LeftLimitDisable  bool `json:"leftLimitDisable"`
+}
+
+// params returns the axis parameter values of the configuration in the order they are set
+func (c AxisConfig) params() ([]AxisParam, []int, error) {
+	if c.Microsteps <= 0 || c.Microsteps > 256 || bits.OnesCount(uint(c.Microsteps)) != 1 {
+		return nil, nil, errors.Wrapf(ErrParamRange, "microsteps %d is no power of 2 up to 256", c.Microsteps)
+	}
+	for _, f := range []struct {
+		name  string
+		value int
+		max   int
+	}{
+		{"max speed", c.MaxSpeed, 2047},
+		{"max acceleration", c.MaxAccel, 2047},
+		{"min speed", c.MinSpeed, 2047},
+		{"run current", c.RunCurrent, 255},
+		{"standby current", c.StandbyCurrent, 255},
+		{"ramp divisor", c.RampDivisor, 13},
+		{"pulse divisor", c.PulseDivisor, 13},
+		{"freewheeling delay", c.FreewheelingDelay, 65535},
+	} {
+		if f.value < 0 || f.value > f.max {
+			return nil, nil, errors.Wrapf(ErrParamRange, "%s %d not in 0..%d", f.name, f.value, f.max)
+		}
+	}
+
+	// divisors and resolution first, as they change the meaning of speeds and accelerations
+	params := []AxisParam{
+		MicrostepResolution, PulseDivisor, RampDivisor, MaxSpeed, MaxAcceleration, MinSpeed,
+		RunCurrent, StandbyCurrent, FreewheelingDelay, SoftStop, RightLimitDisable, LeftLimitDisable,
+	}
+	values := []int{
+		bits.TrailingZeros(uint(c.Microsteps)), c.PulseDivisor, c.RampDivisor, c.MaxSpeed, c.MaxAccel, c.MinSpeed,
+		c.RunCurrent, c.StandbyCurrent, c.FreewheelingDelay, boolValue(c.SoftStop), boolValue(c.RightLimitDisable), boolValue(c.LeftLimitDisable),
+	}
+	return params, values, nil
+}
+
+// ApplyAxisConfig is a.Apply(c)
+func ApplyAxisConfig(a *Axis, c AxisConfig) error {
+	return a.Apply(c)
+}
+
+// ReadAxisConfig is a.ReadConfig()
+func ReadAxisConfig(a *Axis) (AxisConfig, error) {
+	return a.ReadConfig()
+}
+
+// Apply validates the configuration and writes it to the motor, the conversions are reloaded
+// afterwards. The parameters are not stored in the EEPROM.
+func (a *Axis) Apply(c AxisConfig) error {
+	params, values, err := c.params()
+	if err != nil {
+		return err
+	}
+	for i, p := range params {
+		if err := a.Board.SAP(byte(p), a.Motor, values[i]); err != nil {
+			return errors.Wrap(err, AxisParamName(byte(p)))
+		}
+	}
+
+	// the conversions depend on microsteps and pulse divisor
+	return a.Reload()
+}
+
+// ReadConfig reads the configuration of the motor
+func (a *Axis) ReadConfig() (AxisConfig, error) {
+	var c AxisConfig
+	var err error
+	get := func(p AxisParam) int {
+		if err != nil {
+			return 0
+		}
+		var v int
+		v, err = a.Board.GAP(byte(p), a.Motor)
+		if err != nil {
+			err = errors.Wrap(err, AxisParamName(byte(p)))
+		}
+		return v
+	}
+
+	c.Microsteps = 1 << uint(get(MicrostepResolution))
+	c.PulseDivisor = get(PulseDivisor)
+	c.RampDivisor = get(RampDivisor)
+	c.MaxSpeed = get(MaxSpeed)
+	c.MaxAccel = get(MaxAcceleration)
+	c.MinSpeed = get(MinSpeed)
+	c.RunCurrent = get(RunCurrent)
+	c.StandbyCurrent = get(StandbyCurrent)
+	c.FreewheelingDelay = get(FreewheelingDelay)
+	c.SoftStop = get(SoftStop) != 0
+	c.RightLimitDisable = get(RightLimitDisable) != 0
+	c.LeftLimitDisable = get(LeftLimitDisable) != 0
+	return c, err
+}
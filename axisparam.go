@@ -0,0 +1,207 @@
+package tmcl
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// ErrParamRange is returned for SAP with a value outside of the range of the parameter
+var ErrParamRange = errors.New("parameter value out of range")
+
+// AxisParam is the number of an axis parameter
+type AxisParam byte
+
+// axis parameters common to most TMCL modules
+const (
+	TargetPosition          AxisParam = 0
+	ActualPosition          AxisParam = 1
+	TargetSpeed             AxisParam = 2
+	ActualSpeed             AxisParam = 3
+	MaxSpeed                AxisParam = 4
+	MaxAcceleration         AxisParam = 5
+	RunCurrent              AxisParam = 6
+	StandbyCurrent          AxisParam = 7
+	TargetPositionReached   AxisParam = 8
+	ReferenceSwitch         AxisParam = 9
+	RightLimitSwitch        AxisParam = 10
+	LeftLimitSwitch         AxisParam = 11
+	RightLimitDisable       AxisParam = 12
+	LeftLimitDisable        AxisParam = 13
+	MinSpeed                AxisParam = 130
+	ActualAcceleration      AxisParam = 135
+	RampMode                AxisParam = 138
+	MicrostepResolution     AxisParam = 140
+	SoftStop                AxisParam = 149
+	RampDivisor             AxisParam = 153
+	PulseDivisor            AxisParam = 154
+	StallGuardThreshold     AxisParam = 174
+	ReferenceSearchMode     AxisParam = 193
+	ReferenceSearchSpeed    AxisParam = 194
+	ReferenceSwitchSpeed    AxisParam = 195
+	FreewheelingDelay       AxisParam = 204
+	StallDetectionThreshold AxisParam = 205 // stallGuard threshold of the TMCM-351
+	ActualLoad              AxisParam = 206
+	EncoderPosition         AxisParam = 209
+	EncoderPrescaler        AxisParam = 210
+	MaxEncoderDeviation     AxisParam = 212
+	PowerDownDelay          AxisParam = 214
+)
+
+// String returns the name of the parameter
+func (p AxisParam) String() string {
+	return AxisParamName(byte(p))
+}
+
+// ParamRange is the range of values an axis parameter accepts
+type ParamRange struct {
+	Min, Max int
+	ReadOnly bool
+}
+
+// contains returns true if v is within the range
+func (r ParamRange) contains(v int) bool {
+	return v >= r.Min && v <= r.Max
+}
+
+// ParamTable are the axis parameters of a module type and their ranges
+type ParamTable map[AxisParam]ParamRange
+
+// extend returns a copy of the table with the given entries added or replaced
+func (t ParamTable) extend(entries ParamTable) ParamTable {
+	res := make(ParamTable, len(t)+len(entries))
+	for p, r := range t {
+		res[p] = r
+	}
+	for p, r := range entries {
+		res[p] = r
+	}
+	return res
+}
+
+var (
+	fullRange     = ParamRange{Min: math.MinInt32, Max: math.MaxInt32}
+	readOnlyRange = ParamRange{Min: math.MinInt32, Max: math.MaxInt32, ReadOnly: true}
+	flagRange     = ParamRange{Min: 0, Max: 1}
+	readOnlyFlag  = ParamRange{Min: 0, Max: 1, ReadOnly: true}
+	speedRange    = ParamRange{Min: 0, Max: 2047}
+	currentRange  = ParamRange{Min: 0, Max: 255}
+	divisorRange  = ParamRange{Min: 0, Max: 13}
+)
+
+// tmclBaseParams are the parameters of the modules with TMC428/429 motion controller
+var tmclBaseParams = ParamTable{
+	TargetPosition:        fullRange,
+	ActualPosition:        fullRange,
+	TargetSpeed:           {Min: -2047, Max: 2047},
+	ActualSpeed:           {Min: -2047, Max: 2047, ReadOnly: true},
+	MaxSpeed:              speedRange,
+	MaxAcceleration:       speedRange,
+	RunCurrent:            currentRange,
+	StandbyCurrent:        currentRange,
+	TargetPositionReached: readOnlyFlag,
+	ReferenceSwitch:       readOnlyFlag,
+	RightLimitSwitch:      readOnlyFlag,
+	LeftLimitSwitch:       readOnlyFlag,
+	RightLimitDisable:     flagRange,
+	LeftLimitDisable:      flagRange,
+	MinSpeed:              speedRange,
+	ActualAcceleration:    readOnlyRange,
+	RampMode:              {Min: 0, Max: 2},
+	SoftStop:              flagRange,
+	RampDivisor:           divisorRange,
+	PulseDivisor:          divisorRange,
+	ReferenceSearchMode:   fullRange,
+	ReferenceSearchSpeed:  speedRange,
+	ReferenceSwitchSpeed:  speedRange,
+	FreewheelingDelay:     {Min: 0, Max: 65535},
+	EncoderPosition:       fullRange,
+}
+
+// tmc26xParams are the parameters of modules with TMC26x driver and stallGuard2
+var tmc26xParams = tmclBaseParams.extend(ParamTable{
+	MicrostepResolution: {Min: 0, Max: 8},
+	coolStepMinCurrent:  flagRange,
+	coolStepDownStep:    {Min: 0, Max: 3},
+	coolStepHysteresis:  {Min: 0, Max: 15},
+	coolStepUpStep:      {Min: 0, Max: 3},
+	coolStepStart:       {Min: 0, Max: 15},
+	stallGuardFilter:    flagRange,
+	StallGuardThreshold: {Min: -64, Max: 63},
+	stopOnStall:         speedRange,
+	coolStepSpeed:       speedRange,
+	coolStepSlowCurrent: currentRange,
+	ActualLoad:          {Min: 0, Max: 1023, ReadOnly: true},
+})
+
+// ParamTables are the axis parameter tables of known module types, keyed by the module type
+// reported by GetFirmwareVersion. PD modules report the type of the module they are built on.
+var ParamTables = map[int]ParamTable{
+	351: tmclBaseParams.extend(ParamTable{
+		MicrostepResolution:     {Min: 0, Max: 6},
+		141:                     fullRange, // reference switch tolerance
+		203:                     fullRange, // mixed decay threshold
+		StallDetectionThreshold: {Min: 0, Max: 7},
+		ActualLoad:              {Min: 0, Max: 7, ReadOnly: true},
+		EncoderPrescaler:        fullRange,
+		211:                     fullRange, // fullstep threshold
+		MaxEncoderDeviation:     fullRange,
+		213:                     fullRange, // group index
+		PowerDownDelay:          {Min: 1, Max: 65535},
+	}),
+	1140: tmc26xParams,
+	1141: tmc26xParams,
+	1260: tmc26xParams,
+	6110: tmc26xParams,
+}
+
+// SetModuleType sets the module type, disabling the automatic detection. It selects the
+// number of motors and the table used to check axis parameter values.
+func (q *TMCL) SetModuleType(moduleType int) {
+	q.do(globalKey, func() {
+		q.moduleType = moduleType
+		q.axisCount = moduleAxes[moduleType]
+		q.moduleDetected = true
+	})
+}
+
+// ModuleType returns the module type, detecting it if not done yet. 0 means unknown.
+func (q *TMCL) ModuleType() int {
+	var t int
+	q.do(globalKey, func() {
+		q.detectModule()
+		t = q.moduleType
+	})
+	return t
+}
+
+// SetAxisParam sets an axis parameter, the value is checked against the range of the
+// parameter on the detected module type
+func (q *TMCL) SetAxisParam(p AxisParam, motor byte, value int) error {
+	return q.SAP(byte(p), motor, value)
+}
+
+// GetAxisParam reads an axis parameter
+func (q *TMCL) GetAxisParam(p AxisParam, motor byte) (int, error) {
+	return q.GAP(byte(p), motor)
+}
+
+// checkParamRange returns ErrParamRange if SAP sets a value the module does not accept, must
+// be called with the command lock held
+func (q *TMCL) checkParamRange(cmd byte, typeNo byte, value int) error {
+	if cmd != 5 {
+		return nil
+	}
+	q.detectModule()
+	r, ok := ParamTables[q.moduleType][AxisParam(typeNo)]
+	if !ok {
+		return nil
+	}
+	if r.ReadOnly {
+		return errors.Wrapf(ErrParamRange, "SAP %q: parameter is read only", AxisParamName(typeNo))
+	}
+	if !r.contains(value) {
+		return errors.Wrapf(ErrParamRange, "SAP %q: %d not in %d..%d", AxisParamName(typeNo), value, r.Min, r.Max)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package tmcl
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// GetAxisParams reads several axis parameters of a motor, holding the bus once and sending the
+// requests back to back. Unlike DumpAxisParams it fails if any parameter cannot be read.
+func (q *TMCL) GetAxisParams(motor byte, params []AxisParam) (map[AxisParam]int, error) {
+	reqs := make([]Request, len(params))
+	for i, p := range params {
+		reqs[i] = Request{Cmd: 6, Type: byte(p), MotorBank: motor}
+	}
+	values, err := q.execBatch(int(motor), reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[AxisParam]int, len(params))
+	for i, p := range params {
+		m[p] = values[i]
+	}
+	return m, nil
+}
+
+// SetAxisParams sets several axis parameters of a motor, holding the bus once and sending the
+// requests back to back in the order of the parameter numbers. All values are checked before
+// the first one is sent.
+func (q *TMCL) SetAxisParams(motor byte, values map[AxisParam]int) error {
+	params := make([]AxisParam, 0, len(values))
+	for p := range values {
+		params = append(params, p)
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i] < params[j] })
+
+	reqs := make([]Request, len(params))
+	for i, p := range params {
+		reqs[i] = Request{Cmd: 5, Type: byte(p), MotorBank: motor, Value: values[p]}
+	}
+	_, err := q.execBatch(int(motor), reqs)
+	return err
+}
+
+// execBatch checks and sends several requests as one bulk operation and returns their values,
+// failing with the error of the first request the board rejected
+func (q *TMCL) execBatch(key int, reqs []Request) ([]int, error) {
+	if err := q.checkUsable(); err != nil {
+		return nil, err
+	}
+	var values []int
+	var err error
+	q.do(key, func() {
+		values, err = q.batch(reqs)
+	})
+	return values, err
+}
+
+// batch implements execBatch, must be called with the command lock held
+func (q *TMCL) batch(reqs []Request) ([]int, error) {
+	frames := make([]byte, len(reqs)*frameSize)
+	for i, req := range reqs {
+		req, err := q.checkRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		reqs[i] = req
+		q.encodeFrame(frames[i*frameSize:(i+1)*frameSize], req.Cmd, req.Type, req.MotorBank, req.Value)
+	}
+
+	values := make([]int, len(reqs))
+	statuses := make([]byte, len(reqs))
+	if err := q.bulk(frames, values, statuses, nil); err != nil {
+		return nil, err
+	}
+	for i, req := range reqs {
+		if err := q.statusError(statuses[i], req); err != nil {
+			return nil, errors.Wrapf(err, "request %d of %d", i+1, len(reqs))
+		}
+	}
+	return values, nil
+}
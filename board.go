@@ -0,0 +1,89 @@
+package tmcl
+
+import "context"
+
+// MotionController is implemented by boards that can move motors
+type MotionController interface {
+	ROR(motor byte, velocity int) error
+	ROL(motor byte, velocity int) error
+	MST(motor byte) error
+	MVP(mode byte, motor byte, value int) error
+	RFSStart(motor byte) error
+	RFSStop(motor byte) error
+	RFSStatus(motor byte) (bool, error)
+}
+
+// ParameterAccess is implemented by boards with axis and global parameters
+type ParameterAccess interface {
+	SAP(index byte, motor byte, value int) error
+	GAP(index byte, motor byte) (int, error)
+	STAP(index byte, motor byte) error
+	RSAP(index byte, motor byte) error
+	SGP(index byte, bank byte, value int) error
+	GGP(index byte, bank byte) (int, error)
+	STGP(index byte, bank byte) (int, error)
+	RSGP(index byte, bank byte) (int, error)
+}
+
+// IOController is implemented by boards with inputs and outputs
+type IOController interface {
+	SIO(port byte, bank byte, value bool) error
+	GIO(port byte, bank byte) (int, error)
+}
+
+// ApplicationControl is implemented by boards that can run TMCL standalone applications
+type ApplicationControl interface {
+	StopApplication() error
+	RunApplication() error
+	RunApplicationAt(address int) error
+	StepApplication() error
+	ResetApplication() error
+	GetApplicationStatus() (int, error)
+}
+
+// Board is the full command set of a TMCL module
+type Board interface {
+	MotionController
+	ParameterAccess
+	IOController
+	ApplicationControl
+
+	Exec(cmd byte, typeNo byte, motorOrBank byte, value int) (int, error)
+	GetFirmwareVersion() (string, error)
+}
+
+var _ Board = (*TMCL)(nil)
+
+// BoardContext is the full command set of a TMCL module with context aware methods, so that
+// callers can cancel a command or give it a deadline. A blocking read of the port can only
+// be interrupted once it returns, so the port should be opened with a read timeout.
+type BoardContext interface {
+	RORContext(ctx context.Context, motor byte, velocity int) error
+	ROLContext(ctx context.Context, motor byte, velocity int) error
+	MSTContext(ctx context.Context, motor byte) error
+	MVPContext(ctx context.Context, mode byte, motor byte, value int) error
+	RFSStartContext(ctx context.Context, motor byte) error
+	RFSStopContext(ctx context.Context, motor byte) error
+	RFSStatusContext(ctx context.Context, motor byte) (bool, error)
+	SAPContext(ctx context.Context, index byte, motor byte, value int) error
+	GAPContext(ctx context.Context, index byte, motor byte) (int, error)
+	STAPContext(ctx context.Context, index byte, motor byte) error
+	RSAPContext(ctx context.Context, index byte, motor byte) error
+	SGPContext(ctx context.Context, index byte, bank byte, value int) error
+	GGPContext(ctx context.Context, index byte, bank byte) (int, error)
+	STGPContext(ctx context.Context, index byte, bank byte) (int, error)
+	RSGPContext(ctx context.Context, index byte, bank byte) (int, error)
+	SIOContext(ctx context.Context, port byte, bank byte, value bool) error
+	GIOContext(ctx context.Context, port byte, bank byte) (int, error)
+	StopApplicationContext(ctx context.Context) error
+	RunApplicationContext(ctx context.Context) error
+	RunApplicationAtContext(ctx context.Context, address int) error
+	StepApplicationContext(ctx context.Context) error
+	ResetApplicationContext(ctx context.Context) error
+	GetApplicationStatusContext(ctx context.Context) (int, error)
+
+	ExecContext(ctx context.Context, cmd byte, typeNo byte, motorOrBank byte, value int) (int, error)
+	GetFirmwareVersionContext(ctx context.Context) (string, error)
+}
+
+var _ BoardContext = (*TMCL)(nil)
@@ -0,0 +1,139 @@
+package tmcl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// tickDuration is the unit of WAIT TICKS in standalone programs
+const tickDuration = 10 * time.Millisecond
+
+// BoardWatchdogConfig configures the watchdog running as standalone program on the module
+type BoardWatchdogConfig struct {
+	// Variable is the user variable (bank 2) the host increments
+	Variable byte
+
+	// Timeout is the time without increment after which the program fires, at least 20ms
+	Timeout time.Duration
+
+	// Interval is the time between two increments, 0 meaning a quarter of the timeout
+	Interval time.Duration
+
+	// Motors are stopped when the watchdog fires
+	Motors []byte
+
+	// Outputs are set when the watchdog fires, after the motors were stopped
+	Outputs []OutputState
+
+	// OnError is called if an increment failed, the next one is tried anyway
+	OnError func(err error)
+}
+
+// BoardWatchdog is a running board watchdog
+type BoardWatchdog struct {
+	q    *TMCL
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// WatchdogProgram returns the standalone program of the board watchdog: it checks every
+// timeout whether the user variable changed and, if not, applies the safe state. It is armed
+// again as soon as the variable changes.
+func WatchdogProgram(cfg BoardWatchdogConfig) ([]Instruction, error) {
+	ticks := int(cfg.Timeout / tickDuration)
+	if ticks < 2 {
+		return nil, errors.Errorf("watchdog timeout %v too short", cfg.Timeout)
+	}
+
+	p := NewProgram()
+	p.GGP(cfg.Variable, 2).CALCX(CalcLoad)
+
+	// armed: fire if the variable did not change within the timeout
+	p.Label("armed").
+		WaitTicks(ticks).
+		GGP(cfg.Variable, 2).
+		CALCX(CalcSub).
+		COMP(0).
+		JC(IfEqual, "fire").
+		GGP(cfg.Variable, 2).
+		CALCX(CalcLoad).
+		JA("armed")
+
+	// fired: apply the safe state and wait for the variable to change again
+	p.Label("fire")
+	for _, motor := range cfg.Motors {
+		p.MST(motor)
+	}
+	for _, o := range cfg.Outputs {
+		p.SIO(o.Port, o.Bank, o.Value)
+	}
+	p.Label("fired").
+		WaitTicks(ticks/2).
+		GGP(cfg.Variable, 2).
+		CALCX(CalcSub).
+		COMP(0).
+		JC(IfEqual, "fired").
+		GGP(cfg.Variable, 2).
+		CALCX(CalcLoad).
+		JA("armed")
+	return p.Build()
+}
+
+// StartBoardWatchdog downloads the watchdog program, starts it and increments the user
+// variable from a goroutine until Stop is called. If the host dies or the connection breaks,
+// the module applies the safe state by itself. The program replaces any standalone program
+// stored on the module.
+func (q *TMCL) StartBoardWatchdog(cfg BoardWatchdogConfig) (*BoardWatchdog, error) {
+	program, err := WatchdogProgram(cfg)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = cfg.Timeout / 4
+	}
+
+	if err := q.StopApplication(); err != nil {
+		return nil, err
+	}
+	if err := q.DownloadProgram(program, nil); err != nil {
+		return nil, err
+	}
+	if err := q.RunApplicationAt(0); err != nil {
+		return nil, err
+	}
+
+	w := &BoardWatchdog{q: q, stop: make(chan struct{})}
+	w.wg.Add(1)
+	go w.run(cfg, interval)
+	return w, nil
+}
+
+// run increments the user variable until Stop is called
+func (w *BoardWatchdog) run(cfg BoardWatchdogConfig, interval time.Duration) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var counter int
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		counter++
+		if err := w.q.SGP(cfg.Variable, 2, counter); err != nil && cfg.OnError != nil {
+			cfg.OnError(err)
+		}
+	}
+}
+
+// Stop stops incrementing the variable and the watchdog program
+func (w *BoardWatchdog) Stop() error {
+	close(w.stop)
+	w.wg.Wait()
+	return w.q.StopApplication()
+}
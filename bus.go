@@ -0,0 +1,128 @@
+package tmcl
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// Bus is a serial port shared by several modules, e.g. on an RS485 bus. It hands out one TMCL
+// object per module address. Their commands are serialized, and replies of other modules are
+// ignored, so the handles can be used from different goroutines.
+type Bus struct {
+	comPort  string
+	baudRate int
+	opts     []Option
+
+	mutex    sync.Mutex
+	port     io.ReadWriteCloser
+	ownsPort bool
+	cmdLock  *scheduler
+	modules  map[byte]*TMCL
+	closed   bool
+}
+
+// NewBus creates a bus on a serial port, which is opened with the first command
+func NewBus(comPort string, baudRate int, opts ...Option) *Bus {
+	return &Bus{
+		comPort:  comPort,
+		baudRate: baudRate,
+		opts:     opts,
+		ownsPort: true,
+		cmdLock:  newScheduler(),
+		modules:  make(map[byte]*TMCL),
+	}
+}
+
+// NewBusWithPort creates a bus on an already open port. The caller stays responsible for
+// closing it.
+func NewBusWithPort(port io.ReadWriteCloser, opts ...Option) *Bus {
+	return &Bus{
+		port:    port,
+		opts:    opts,
+		cmdLock: newScheduler(),
+		modules: make(map[byte]*TMCL),
+	}
+}
+
+// Module returns the TMCL object of the module with the given serial address. The options
+// passed to the bus are applied first, then the ones given here.
+func (b *Bus) Module(address byte, opts ...Option) *TMCL {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if q, ok := b.modules[address]; ok {
+		return q
+	}
+	q := b.newModule(address, opts)
+	q.watchShutdown()
+	b.modules[address] = q
+	return q
+}
+
+// newModule creates a handle for a module address, must be called with the mutex held
+func (b *Bus) newModule(address byte, opts []Option) *TMCL {
+	q := newTMCL(append(append([]Option{}, b.opts...), opts...))
+	q.ComPort = b.comPort
+	q.baudRate = b.baudRate
+	q.address = address
+	q.cmdLock = b.cmdLock
+	q.dial = func() (io.ReadWriteCloser, error) {
+		return b.open(q.readTimeout)
+	}
+	if b.closed {
+		q.closed = stateClosed
+	}
+	return q
+}
+
+// open returns the shared port, opening it with the given read timeout if necessary
+func (b *Bus) open(readTimeout time.Duration) (io.ReadWriteCloser, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	if b.port != nil {
+		return b.port, nil
+	}
+	if !b.ownsPort || b.comPort == "" {
+		return nil, ErrNoPort
+	}
+
+	port, err := transport.OpenSerial(b.comPort, b.baudRate, readTimeout)
+	if err != nil {
+		return nil, err
+	}
+	b.port = port
+	return port, nil
+}
+
+// Close closes all module handles, applying their fail-safe states, and then the port
+func (b *Bus) Close() error {
+	b.mutex.Lock()
+	modules := make([]*TMCL, 0, len(b.modules))
+	for _, q := range b.modules {
+		modules = append(modules, q)
+	}
+	b.mutex.Unlock()
+
+	for _, q := range modules {
+		_ = q.Close()
+	}
+
+	// on the writer, so that no command of a module is in flight
+	var err error
+	_ = b.cmdLock.run(context.Background(), globalKey, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		b.closed = true
+		if b.port != nil && b.ownsPort {
+			err = b.port.Close()
+		}
+		b.port = nil
+	})
+	return err
+}
@@ -0,0 +1,132 @@
+package tmcl
+
+import "github.com/pkg/errors"
+
+// ErrUnsupported is returned for features the module does not have or whose parameters are not
+// known for its module type
+var ErrUnsupported = errors.New("not supported by the module")
+
+// Capabilities describes the features of a module, derived from its module type. Counts of 0
+// and false flags may also mean that the module type is not known in detail.
+type Capabilities struct {
+	Version Version
+
+	// Known is true if the module type is in the table of known modules
+	Known bool
+
+	Motors       int
+	Inputs       int
+	Outputs      int
+	AnalogInputs int
+	Encoder      bool
+	StallGuard   bool
+	CoolStep     bool
+	StealthChop  bool
+	ParamTable   ParamTable
+
+	// commands are the commands of the firmware, nil if not known
+	commands map[byte]bool
+}
+
+// SupportsCommand returns true if the firmware of the module implements a command. For module
+// types whose commands are not known it returns true, the module then reports commands it
+// lacks by StatusInvalidCommand.
+func (c Capabilities) SupportsCommand(cmd byte) bool {
+	if c.commands == nil {
+		return true
+	}
+	return c.commands[cmd]
+}
+
+// moduleFeatures are the IO and driver features and the commands of a module type
+type moduleFeatures struct {
+	inputs, outputs, analogInputs int
+	encoder, stallGuard, coolStep bool
+	commands                      map[byte]bool
+}
+
+// knownFeatures are the features of modules whose manual is at hand
+var knownFeatures = map[int]moduleFeatures{
+	351: {
+		inputs: 8, outputs: 8, analogInputs: 8, encoder: true, stallGuard: true,
+		// there are no commands 16 to 18
+		commands: commandSet([2]byte{1, 15}, [2]byte{19, 39}, [2]byte{128, 139}),
+	},
+}
+
+// commandSet returns the commands of the given ranges, first and last included
+func commandSet(ranges ...[2]byte) map[byte]bool {
+	set := map[byte]bool{}
+	for _, r := range ranges {
+		for cmd := int(r[0]); cmd <= int(r[1]); cmd++ {
+			set[byte(cmd)] = true
+		}
+	}
+	return set
+}
+
+// driverFamily is a family of stepper drivers sharing the axis parameters of their stallGuard
+// and coolStep features
+type driverFamily int
+
+const (
+	unknownDriver driverFamily = iota
+
+	// tmc249Driver has the stallGuard of the TMC249, used by the TMCM-351
+	tmc249Driver
+
+	// tmc26xDriver has stallGuard2 and coolStep of the TMC260 to TMC262
+	tmc26xDriver
+
+	// tmc2130Driver has stealthChop besides spreadCycle, like the TMC2130, TMC5130 and TMC5160.
+	// Its stallGuard2 and coolStep parameters take speeds in other units than the TMC26x.
+	tmc2130Driver
+)
+
+// moduleDrivers are the driver families of known module types
+var moduleDrivers = map[int]driverFamily{
+	351:  tmc249Driver,
+	1140: tmc26xDriver,
+	1141: tmc26xDriver,
+	1260: tmc26xDriver,
+	6110: tmc26xDriver,
+	1240: tmc2130Driver,
+	1276: tmc2130Driver,
+	1278: tmc2130Driver,
+}
+
+// driver returns the driver family of the module, detecting the module type if not done yet
+func (q *TMCL) driver() (driverFamily, int) {
+	t := q.ModuleType()
+	return moduleDrivers[t], t
+}
+
+// Capabilities identifies the module by its firmware version and returns its features
+func (q *TMCL) Capabilities() (Capabilities, error) {
+	v, err := q.GetVersion()
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	c := Capabilities{
+		Version:    v,
+		Motors:     moduleAxes[v.ModuleType],
+		ParamTable: ParamTables[v.ModuleType],
+	}
+	_, c.Known = moduleAxes[v.ModuleType]
+	c.StealthChop = moduleDrivers[v.ModuleType] == tmc2130Driver
+	if f, ok := knownFeatures[v.ModuleType]; ok {
+		c.Inputs = f.inputs
+		c.Outputs = f.outputs
+		c.AnalogInputs = f.analogInputs
+		c.Encoder = f.encoder
+		c.StallGuard = f.stallGuard
+		c.CoolStep = f.coolStep
+		c.commands = f.commands
+	} else if _, ok := c.ParamTable[ActualLoad]; ok {
+		// modules with TMC26x drivers report the stallGuard2 load value, which also drives coolStep
+		c.StallGuard = true
+		c.CoolStep = true
+	}
+	return c, nil
+}
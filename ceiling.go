@@ -0,0 +1,43 @@
+package tmcl
+
+import (
+	"github.com/pkg/errors"
+)
+
+// SetCurrentCeiling sets an absolute ceiling for the run and standby current (axis parameters
+// 6 and 7) of a motor. Higher values written via SAP are reduced to the ceiling, no matter
+// if they come from application code or a loaded configuration. 0 removes the ceiling.
+func (q *TMCL) SetCurrentCeiling(motor byte, ceiling int) {
+	q.do(globalKey, func() {
+		if q.currentCeiling == nil {
+			q.currentCeiling = make(map[byte]int)
+		}
+		if ceiling == 0 {
+			delete(q.currentCeiling, motor)
+		} else {
+			q.currentCeiling[motor] = ceiling
+		}
+	})
+}
+
+// applyCurrentCeiling returns the value to be sent for a command, reduced to the current
+// ceiling of the motor, must be called with the command lock held
+func (q *TMCL) applyCurrentCeiling(cmd byte, typeNo byte, motor byte, value int) (int, error) {
+	if typeNo != 6 && typeNo != 7 {
+		return value, nil
+	}
+	ceiling, ok := q.currentCeiling[motor]
+	if !ok {
+		return value, nil
+	}
+
+	switch cmd {
+	case 5: // SAP
+		if value > ceiling {
+			return ceiling, nil
+		}
+	case 34: // AAP, the accumulator value is unknown here
+		return 0, errors.Errorf("accumulator to current parameter %d of motor %d not permitted with current ceiling", typeNo, motor)
+	}
+	return value, nil
+}
@@ -0,0 +1,181 @@
+package tmcl
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// chopper parameters of the TMC26x and TMC2130 modules
+const (
+	chopperBlankTime       AxisParam = 162
+	chopperModeParam       AxisParam = 163
+	chopperHysteresisEnd   AxisParam = 165
+	chopperHysteresisStart AxisParam = 166
+	chopperOffTime         AxisParam = 167
+)
+
+// stealthChop parameters of the TMC2130 modules
+const (
+	pwmThresholdSpeed AxisParam = 186
+	pwmGradient       AxisParam = 187
+	pwmAmplitude      AxisParam = 188
+	pwmAutoscale      AxisParam = 192
+)
+
+// ChopperMode is the way the driver regulates the motor current
+type ChopperMode int
+
+const (
+	// SpreadCycle is the chopper with hysteresis, the default
+	SpreadCycle ChopperMode = 0
+
+	// ConstantOffTime is the classic chopper with constant off time
+	ConstantOffTime ChopperMode = 1
+
+	// StealthChop is the silent voltage mode up to a threshold speed, spreadCycle above
+	StealthChop ChopperMode = 2
+)
+
+// String returns the name of the chopper mode
+func (m ChopperMode) String() string {
+	switch m {
+	case SpreadCycle:
+		return "spreadCycle"
+	case ConstantOffTime:
+		return "constant off time"
+	case StealthChop:
+		return "stealthChop"
+	}
+	return "chopper mode " + strconv.Itoa(int(m))
+}
+
+// ChopperConfig is the chopper setup of a motor, values are in the units of the corresponding
+// axis parameters
+type ChopperConfig struct {
+	Mode ChopperMode `json:"mode"`
+
+	// BlankTime 0..3 is the comparator blank time, at least 2 if OffTime is 1
+	BlankTime int `json:"blankTime"`
+
+	// OffTime 1..15 is the slow decay time, 0 would disable the driver
+	OffTime int `json:"offTime"`
+
+	// HysteresisStart 0..7 and HysteresisEnd 0..15 set the hysteresis of spreadCycle, or the
+	// sine wave offset and fast decay time of the constant off time chopper
+	HysteresisStart int `json:"hysteresisStart"`
+	HysteresisEnd   int `json:"hysteresisEnd"`
+
+	// StealthChopSpeed is the speed above which the driver switches from stealthChop to
+	// spreadCycle, in the units the module uses for axis parameter 186
+	StealthChopSpeed int `json:"stealthChopSpeed"`
+
+	// Gradient 1..15 and Amplitude 0..255 set the PWM of stealthChop, Autoscale regulating
+	// the amplitude by the measured current
+	Gradient  int  `json:"gradient"`
+	Amplitude int  `json:"amplitude"`
+	Autoscale bool `json:"autoscale"`
+}
+
+// params returns the axis parameter values of the configuration for the driver family
+func (c ChopperConfig) params(family driverFamily) ([]AxisParam, []int, error) {
+	if family != tmc26xDriver && family != tmc2130Driver {
+		return nil, nil, errors.Wrap(ErrUnsupported, "chopper configuration")
+	}
+	if c.Mode == StealthChop && family != tmc2130Driver {
+		return nil, nil, errors.Wrap(ErrUnsupported, "stealthChop")
+	}
+	if c.Mode < SpreadCycle || c.Mode > StealthChop {
+		return nil, nil, errors.Wrapf(ErrParamRange, "unknown %v", c.Mode)
+	}
+	fields := []configField{
+		{"blank time", c.BlankTime, 0, 3},
+		{"off time", c.OffTime, 1, 15},
+		{"hysteresis start", c.HysteresisStart, 0, 7},
+		{"hysteresis end", c.HysteresisEnd, 0, 15},
+	}
+	if c.Mode == StealthChop {
+		fields = append(fields,
+			configField{"stealthChop speed", c.StealthChopSpeed, 0, math.MaxInt32},
+			configField{"PWM gradient", c.Gradient, 1, 15},
+			configField{"PWM amplitude", c.Amplitude, 0, 255},
+		)
+	}
+	if err := checkFields(fields); err != nil {
+		return nil, nil, err
+	}
+	if c.OffTime == 1 && c.BlankTime < 2 {
+		return nil, nil, errors.Wrap(ErrParamRange, "off time 1 needs a blank time of at least 2")
+	}
+
+	// stealthChop uses spreadCycle above the threshold speed
+	mode := c.Mode
+	if mode == StealthChop {
+		mode = SpreadCycle
+	}
+	params := []AxisParam{chopperModeParam, chopperBlankTime, chopperHysteresisStart, chopperHysteresisEnd, chopperOffTime}
+	values := []int{int(mode), c.BlankTime, c.HysteresisStart, c.HysteresisEnd, c.OffTime}
+	switch {
+	case c.Mode == StealthChop:
+		// the gradient last, as it enables stealthChop
+		params = append(params, pwmThresholdSpeed, pwmAmplitude, pwmAutoscale, pwmGradient)
+		values = append(values, c.StealthChopSpeed, c.Amplitude, boolValue(c.Autoscale), c.Gradient)
+	case family == tmc2130Driver:
+		params = append(params, pwmGradient)
+		values = append(values, 0)
+	}
+	return params, values, nil
+}
+
+// SetChopper validates the chopper configuration for the module type and writes it to the
+// motor. ErrUnsupported is returned for modules whose chopper is not configured by axis
+// parameters and for stealthChop on modules without it.
+func (q *TMCL) SetChopper(motor byte, c ChopperConfig) error {
+	family, t := q.driver()
+	params, values, err := c.params(family)
+	if err != nil {
+		return errors.Wrapf(err, "module type %d", t)
+	}
+	return q.setParams(motor, params, values)
+}
+
+// SetChopperMode switches the chopper mode of a motor, keeping the other settings
+func (q *TMCL) SetChopperMode(motor byte, mode ChopperMode) error {
+	c, err := q.ReadChopper(motor)
+	if err != nil {
+		return err
+	}
+	if mode == StealthChop && c.Mode != StealthChop && c.Gradient == 0 {
+		return errors.Wrap(ErrParamRange, "stealthChop needs a PWM gradient, use SetChopper")
+	}
+	c.Mode = mode
+	return q.SetChopper(motor, c)
+}
+
+// ReadChopper reads the chopper configuration of the motor. The stealthChop settings are only
+// read from modules that have stealthChop.
+func (q *TMCL) ReadChopper(motor byte) (ChopperConfig, error) {
+	var c ChopperConfig
+	family, t := q.driver()
+	if family != tmc26xDriver && family != tmc2130Driver {
+		return c, errors.Wrapf(ErrUnsupported, "module type %d: chopper configuration", t)
+	}
+	v, err := q.getParams(motor, chopperModeParam, chopperBlankTime, chopperHysteresisStart, chopperHysteresisEnd, chopperOffTime)
+	if err != nil {
+		return c, err
+	}
+	c.Mode, c.BlankTime, c.HysteresisStart, c.HysteresisEnd, c.OffTime = ChopperMode(v[0]), v[1], v[2], v[3], v[4]
+	if family != tmc2130Driver {
+		return c, nil
+	}
+
+	if v, err = q.getParams(motor, pwmThresholdSpeed, pwmGradient, pwmAmplitude, pwmAutoscale); err != nil {
+		return c, err
+	}
+	c.StealthChopSpeed, c.Gradient, c.Amplitude, c.Autoscale = v[0], v[1], v[2], v[3] != 0
+	if c.Gradient != 0 {
+		c.Mode = StealthChop
+	}
+	return c, nil
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/assembler"
+	"github.com/raceresult/go-tmcl/sim"
+)
+
+// command is a subcommand of tmclctl
+type command struct {
+	name string
+	args string
+	help string
+	run  func(e *env, fs *flag.FlagSet, args []string) error
+
+	// flags defines the flags of the command, if any
+	flags func(fs *flag.FlagSet)
+
+	// interactive commands read from the terminal and handle Ctrl-C themselves
+	interactive bool
+}
+
+// commands are all subcommands in the order of the usage text
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "version", help: "print module type and firmware version", run: runVersion},
+		{name: "move", args: "motor position", help: "move a motor to a position", run: runMove, flags: moveFlags},
+		{name: "stop", args: "[motor]", help: "stop one motor or all motors", run: runStop},
+		{name: "gap", args: "param motor", help: "get an axis parameter", run: runGAP},
+		{name: "sap", args: "param motor value", help: "set an axis parameter", run: runSAP},
+		{name: "ggp", args: "param bank", help: "get a global parameter", run: runGGP},
+		{name: "sgp", args: "param bank value", help: "set a global parameter", run: runSGP},
+		{name: "io", args: "in|out|analog port [0|1]", help: "read an input or read or set an output", run: runIO},
+		{name: "home", args: "motor", help: "run the reference search of a motor", run: runHome, flags: homeFlags},
+		{name: "dump-config", help: "print the configuration of the module as JSON", run: runDumpConfig},
+		{name: "flash-program", args: "file", help: "assemble a TMCL program and store it in the module", run: runFlashProgram, flags: flashFlags},
+		{name: "scan", help: "list serial ports with modules, or the modules on the bus of -port", run: runScan, flags: scanFlags},
+		{name: "trace", args: "motor position", help: "move a motor and write its motion profile as CSV or JSON", run: runTrace, flags: traceFlags},
+		{name: "dashboard", help: "live view of motors and IO with hotkeys to jog and stop", run: runDashboard, flags: dashboardFlags, interactive: true},
+		{name: "shell", help: "interactive shell keeping the connection open", run: runShell, interactive: true},
+	}
+}
+
+// findCommand returns the command with the given name
+func findCommand(name string) (*command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// exec parses the flags of the command and runs it
+func (c *command) exec(e *env, args []string) error {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: tmclctl %s %s\n\n%s\n", c.name, c.args, c.help)
+		fs.PrintDefaults()
+	}
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	return c.run(e, fs, fs.Args())
+}
+
+// wantArgs checks the number of positional arguments
+func wantArgs(fs *flag.FlagSet, args []string, min, max int) error {
+	if len(args) < min || len(args) > max {
+		fs.Usage()
+		return errors.New("wrong number of arguments")
+	}
+	return nil
+}
+
+func runVersion(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+	v, err := q.GetVersionContext(e.ctx)
+	if err != nil {
+		return err
+	}
+	e.printf("%s\n", v)
+	return nil
+}
+
+// move flags
+var (
+	moveRelative bool
+	moveWait     bool
+	moveTimeout  time.Duration
+)
+
+func moveFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&moveRelative, "rel", false, "move relative to the current target position")
+	fs.BoolVar(&moveWait, "wait", false, "wait until the position is reached, Ctrl-C stops the motor")
+	fs.DurationVar(&moveTimeout, "wait-timeout", 0, "maximum time to wait with -wait")
+}
+
+func runMove(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	motor, err := parseByte(args[0])
+	if err != nil {
+		return err
+	}
+	position, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+
+	mode := tmcl.ABS
+	if moveRelative {
+		mode = tmcl.REL
+	}
+	if !moveWait {
+		return q.MVPContext(e.ctx, mode, motor, position)
+	}
+	opts := tmcl.MoveOptions{Timeout: moveTimeout, StopOnCancel: true}
+	if moveRelative {
+		err = q.MoveRelWait(e.ctx, motor, position, opts)
+	} else {
+		err = q.MoveAbsWait(e.ctx, motor, position, opts)
+	}
+	if err != nil {
+		return err
+	}
+	v, err := q.GAPContext(e.ctx, byte(tmcl.ActualPosition), motor)
+	if err != nil {
+		return err
+	}
+	e.printf("%d\n", v)
+	return nil
+}
+
+func runStop(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 0, 1); err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return q.StopAll()
+	}
+	motor, err := parseByte(args[0])
+	if err != nil {
+		return err
+	}
+	return q.MST(motor)
+}
+
+func runGAP(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	index, err := parseAxisParam(args[0])
+	if err != nil {
+		return err
+	}
+	motor, err := parseByte(args[1])
+	if err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+	v, err := q.GAPContext(e.ctx, index, motor)
+	if err != nil {
+		return err
+	}
+	e.printf("%d\n", v)
+	return nil
+}
+
+func runSAP(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 3, 3); err != nil {
+		return err
+	}
+	index, err := parseAxisParam(args[0])
+	if err != nil {
+		return err
+	}
+	motor, err := parseByte(args[1])
+	if err != nil {
+		return err
+	}
+	value, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+	return q.SAPContext(e.ctx, index, motor, value)
+}
+
+func runGGP(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	index, err := parseGlobalParam(args[0])
+	if err != nil {
+		return err
+	}
+	bank, err := parseByte(args[1])
+	if err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+	v, err := q.GGPContext(e.ctx, index, bank)
+	if err != nil {
+		return err
+	}
+	e.printf("%d\n", v)
+	return nil
+}
+
+func runSGP(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 3, 3); err != nil {
+		return err
+	}
+	index, err := parseGlobalParam(args[0])
+	if err != nil {
+		return err
+	}
+	bank, err := parseByte(args[1])
+	if err != nil {
+		return err
+	}
+	value, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+	return q.SGPContext(e.ctx, index, bank, value)
+}
+
+func runIO(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 2, 3); err != nil {
+		return err
+	}
+	port, err := parseByte(args[1])
+	if err != nil {
+		return err
+	}
+	var bank byte
+	switch args[0] {
+	case "in":
+		bank = tmcl.DigitalInputBank
+	case "out":
+		bank = tmcl.DigitalOutputBank
+	case "analog":
+		bank = tmcl.AnalogInputBank
+	default:
+		fs.Usage()
+		return errors.Errorf("unknown io bank %q", args[0])
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 3 {
+		if bank != tmcl.DigitalOutputBank {
+			return errors.New("only outputs can be set")
+		}
+		value, err := parseInt(args[2])
+		if err != nil {
+			return err
+		}
+		return q.SIOContext(e.ctx, port, bank, value != 0)
+	}
+	v, err := q.GIOContext(e.ctx, port, bank)
+	if err != nil {
+		return err
+	}
+	e.printf("%d\n", v)
+	return nil
+}
+
+// trace flags
+var (
+	traceRelative bool
+	traceJSON     bool
+	traceOutput   string
+	traceConfig   tmcl.TraceConfig
+)
+
+func traceFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&traceRelative, "rel", false, "move relative to the current target position")
+	fs.BoolVar(&traceJSON, "json", false, "write JSON instead of CSV")
+	fs.StringVar(&traceOutput, "o", "", "output file instead of standard output")
+	fs.Float64Var(&traceConfig.Rate, "rate", 100, "samples per second")
+	fs.DurationVar(&traceConfig.Tail, "tail", 200*time.Millisecond, "recording time after the target position is reached")
+	fs.DurationVar(&traceConfig.Timeout, "timeout", time.Minute, "maximum duration of the move")
+}
+
+func runTrace(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	motor, err := parseByte(args[0])
+	if err != nil {
+		return err
+	}
+	position, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+
+	mode := tmcl.ABS
+	if traceRelative {
+		mode = tmcl.REL
+	}
+	t, err := q.TraceMove(e.ctx, mode, motor, position, traceConfig)
+	if t == nil {
+		return err
+	}
+	if err == nil && t.Missed > 0 {
+		fmt.Fprintf(os.Stderr, "%d samples missed\n", t.Missed)
+	}
+
+	w := e.out
+	if traceOutput != "" {
+		f, ferr := os.Create(traceOutput)
+		if ferr != nil {
+			return ferr
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+	if traceJSON {
+		if werr := t.WriteJSON(w); werr != nil {
+			return werr
+		}
+	} else if werr := t.WriteCSV(w); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// home flags
+var homeConfig tmcl.HomeConfig
+
+func homeFlags(fs *flag.FlagSet) {
+	fs.IntVar(&homeConfig.Offset, "offset", 0, "relative move in microsteps after the reference search")
+	fs.DurationVar(&homeConfig.Timeout, "timeout", time.Minute, "maximum duration of the reference search")
+}
+
+func runHome(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	motor, err := parseByte(args[0])
+	if err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+	return q.Home(e.ctx, motor, homeConfig)
+}
+
+func runDumpConfig(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+	c, err := q.DumpConfig()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(e.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// flash-program flags
+var flashRun bool
+
+func flashFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&flashRun, "run", false, "start the program afterwards")
+}
+
+func runFlashProgram(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	src, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	program, err := assembler.Assemble(string(src))
+	if err != nil {
+		return err
+	}
+	q, err := e.conn()
+	if err != nil {
+		return err
+	}
+
+	if err := q.StopApplication(); err != nil {
+		return err
+	}
+	err = q.DownloadProgram(program, func(done, total int) {
+		fmt.Fprintf(os.Stderr, "\r%d/%d instructions", done, total)
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+	if flashRun {
+		return q.RunApplicationAt(0)
+	}
+	return nil
+}
+
+// scan flags
+var scanFrom, scanTo int
+
+func scanFlags(fs *flag.FlagSet) {
+	fs.IntVar(&scanFrom, "from", 1, "first module address to probe on the bus")
+	fs.IntVar(&scanTo, "to", 255, "last module address to probe on the bus")
+}
+
+func runScan(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	// the bus opens the port itself
+	if !e.opts.sim {
+		e.close()
+	}
+
+	// without port all serial ports are probed
+	if e.opts.port == "" && !e.opts.sim {
+		found, err := tmcl.DiscoverPorts(e.ctx, nil)
+		for _, p := range found {
+			e.printf("%s\t%d baud\t%s\n", p.Port, p.BaudRate, p.Version)
+		}
+		return err
+	}
+
+	if scanFrom < 1 || scanTo > 255 || scanFrom > scanTo {
+		return errors.New("invalid address range")
+	}
+	addresses := make([]byte, 0, scanTo-scanFrom+1)
+	for addr := scanFrom; addr <= scanTo; addr++ {
+		addresses = append(addresses, byte(addr))
+	}
+	var opts []tmcl.Option
+	if e.opts.timeout > 0 {
+		opts = append(opts, tmcl.WithTimeout(e.opts.timeout))
+	}
+	var bus *tmcl.Bus
+	if e.opts.sim {
+		bus = tmcl.NewBusWithPort(sim.NewModule().Conn(), opts...)
+	} else {
+		bus = tmcl.NewBus(e.opts.port, e.opts.baud, opts...)
+	}
+	defer func() { _ = bus.Close() }()
+
+	found, err := bus.ScanBus(e.ctx, addresses)
+	for _, m := range found {
+		e.printf("%d\t%s\n", m.Address, m.Version)
+	}
+	return err
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+
+	tmcl "github.com/raceresult/go-tmcl"
+)
+
+// dashboardParams are the axis parameters polled per motor, in the order of the columns
+var dashboardParams = []tmcl.AxisParam{
+	tmcl.TargetPosition,
+	tmcl.ActualPosition,
+	tmcl.ActualSpeed,
+	tmcl.ActualLoad,
+	tmcl.RunCurrent,
+	208, // driver error flags
+	207, // extended error flags
+}
+
+// dashboard flags
+var (
+	dashboardMotors  string
+	dashboardInputs  string
+	dashboardOutputs string
+	dashboardRate    float64
+	dashboardJog     int
+)
+
+func dashboardFlags(fs *flag.FlagSet) {
+	fs.StringVar(&dashboardMotors, "motors", "0", "motors to show, e.g. 0,1 or 0-2")
+	fs.StringVar(&dashboardInputs, "in", "0-7", "digital inputs to show, empty for none")
+	fs.StringVar(&dashboardOutputs, "out", "0-7", "digital outputs to show, empty for none")
+	fs.Float64Var(&dashboardRate, "rate", 10, "maximum number of updates per second")
+	fs.IntVar(&dashboardJog, "jog", 200, "jog velocity in internal units")
+}
+
+// dashboard is the live view of motors and IO
+type dashboard struct {
+	q       *tmcl.TMCL
+	motors  []byte
+	inputs  []byte
+	outputs []byte
+
+	mutex    sync.Mutex
+	values   []int
+	err      error
+	selected int
+	jog      int
+	jogging  map[byte]int
+	message  string
+	monitor  *tmcl.Monitor
+}
+
+func runDashboard(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("the dashboard needs a terminal")
+	}
+	d := &dashboard{jog: dashboardJog, jogging: map[byte]int{}}
+	var err error
+	if d.motors, err = parseList(dashboardMotors); err != nil {
+		return err
+	}
+	if d.inputs, err = parseList(dashboardInputs); err != nil {
+		return err
+	}
+	if d.outputs, err = parseList(dashboardOutputs); err != nil {
+		return err
+	}
+	if len(d.motors) == 0 {
+		return errors.New("no motors given")
+	}
+	if d.q, err = e.conn(); err != nil {
+		return err
+	}
+
+	// the replies of the shell would scroll through the dashboard
+	if l, ok := e.logger.(*shellLogger); ok {
+		replies := l.replies
+		l.replies = false
+		defer func() { l.replies = replies }()
+	}
+
+	var params []tmcl.PollParam
+	for _, m := range d.motors {
+		for _, p := range dashboardParams {
+			params = append(params, tmcl.AxisPoll(p, m))
+		}
+	}
+	for _, port := range d.inputs {
+		params = append(params, tmcl.IOPoll(port, tmcl.DigitalInputBank))
+	}
+	for _, port := range d.outputs {
+		params = append(params, tmcl.IOPoll(port, tmcl.DigitalOutputBank))
+	}
+	var interval time.Duration
+	if dashboardRate > 0 {
+		interval = time.Duration(float64(time.Second) / dashboardRate)
+	}
+	d.monitor = d.q.NewMonitor(params, interval)
+	d.monitor.OnSample(d.update)
+
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		_ = term.Restore(int(os.Stdin.Fd()), state)
+	}()
+
+	ctx, cancel := context.WithCancel(e.ctx)
+	defer cancel()
+	go d.readKeys(ctx, cancel)
+	_ = d.monitor.Run(ctx)
+	d.stopJogging()
+	return nil
+}
+
+// update takes over a sample of the monitor and redraws
+func (q *dashboard) update(s tmcl.Sample) {
+	q.mutex.Lock()
+	if s.Err == nil {
+		q.values = s.Values
+	}
+	q.err = s.Err
+	q.mutex.Unlock()
+	q.render()
+}
+
+// readKeys handles the hotkeys until quit is pressed
+func (q *dashboard) readKeys(ctx context.Context, quit func()) {
+	buf := make([]byte, 16)
+	for ctx.Err() == nil {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			quit()
+			return
+		}
+		key := string(buf[:n])
+		switch key {
+		case "q", "Q", "\x03", "\x1b":
+			quit()
+			return
+		case "\x1b[C", "\x1b[D":
+			q.jogSelected(key == "\x1b[C")
+		case " ":
+			q.stopSelected()
+		case "s", "S":
+			q.stopJogging()
+			q.report(q.q.StopAll(), "all motors stopped")
+		case "+":
+			q.scaleJog(2)
+		case "-":
+			q.scaleJog(0.5)
+		case "\t":
+			q.mutex.Lock()
+			q.selected = (q.selected + 1) % len(q.motors)
+			q.mutex.Unlock()
+		default:
+			if len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+				q.selectMotor(key[0] - '0')
+			}
+		}
+		q.render()
+	}
+}
+
+// selectMotor selects a motor by number if it is shown
+func (q *dashboard) selectMotor(motor byte) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for i, m := range q.motors {
+		if m == motor {
+			q.selected = i
+		}
+	}
+}
+
+// selectedMotor returns the selected motor
+func (q *dashboard) selectedMotor() byte {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.motors[q.selected]
+}
+
+// scaleJog changes the jog velocity by a factor, within 1 and 2047
+func (q *dashboard) scaleJog(factor float64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	v := int(float64(q.jog) * factor)
+	if v < 1 {
+		v = 1
+	} else if v > 2047 {
+		v = 2047
+	}
+	q.jog = v
+	q.message = fmt.Sprintf("jog velocity %d", v)
+}
+
+// jogSelected rotates the selected motor right or left with the jog velocity
+func (q *dashboard) jogSelected(right bool) {
+	motor := q.selectedMotor()
+	q.mutex.Lock()
+	v := q.jog
+	q.mutex.Unlock()
+
+	var err error
+	if right {
+		err = q.q.ROR(motor, v)
+	} else {
+		err = q.q.ROL(motor, v)
+		v = -v
+	}
+	if err == nil {
+		q.mutex.Lock()
+		q.jogging[motor] = v
+		q.mutex.Unlock()
+	}
+	q.report(err, fmt.Sprintf("motor %d jogging with %d", motor, v))
+}
+
+// stopSelected stops the selected motor
+func (q *dashboard) stopSelected() {
+	motor := q.selectedMotor()
+	err := q.q.MST(motor)
+	if err == nil {
+		q.mutex.Lock()
+		delete(q.jogging, motor)
+		q.mutex.Unlock()
+	}
+	q.report(err, fmt.Sprintf("motor %d stopped", motor))
+}
+
+// stopJogging stops all motors started with the jog keys
+func (q *dashboard) stopJogging() {
+	q.mutex.Lock()
+	motors := q.jogging
+	q.jogging = map[byte]int{}
+	q.mutex.Unlock()
+	for motor := range motors {
+		_ = q.q.MST(motor)
+	}
+}
+
+// report sets the message line to the error or the given text
+func (q *dashboard) report(err error, text string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if err != nil {
+		text = "error: " + err.Error()
+	}
+	q.message = text
+}
+
+// render draws the dashboard
+func (q *dashboard) render() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	var b bytes.Buffer
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\x1b[K\r\n")
+	}
+	b.WriteString("\x1b[H")
+	line("tmclctl dashboard, %.1f updates/s", q.monitor.Rate())
+	line("")
+	line("  %-5s %11s %11s %7s %5s %7s  %s", "motor", "target", "actual", "speed", "load", "current", "errors")
+
+	n := len(dashboardParams)
+	complete := len(q.values) == len(q.motors)*n+len(q.inputs)+len(q.outputs)
+	for i, m := range q.motors {
+		cursor := " "
+		if i == q.selected {
+			cursor = ">"
+		}
+		if !complete {
+			line("%s %-5d", cursor, m)
+			continue
+		}
+		v := q.values[i*n : (i+1)*n]
+		errs := tmcl.DecodeDriverErrors(uint32(v[5]), uint32(v[6]))
+		jog := ""
+		if j, ok := q.jogging[m]; ok {
+			jog = fmt.Sprintf("  (jog %d)", j)
+		}
+		line("%s %-5d %11d %11d %7d %5d %7d  %s%s", cursor, m, v[0], v[1], v[2], v[3], v[4], errs, jog)
+	}
+
+	line("")
+	if complete {
+		ports := q.values[len(q.motors)*n:]
+		if len(q.inputs) > 0 {
+			line("  inputs   %s", ioStates(q.inputs, ports[:len(q.inputs)]))
+		}
+		if len(q.outputs) > 0 {
+			line("  outputs  %s", ioStates(q.outputs, ports[len(q.inputs):]))
+		}
+	}
+	line("")
+	if q.err != nil {
+		line("error: %v", q.err)
+	} else {
+		line("%s", q.message)
+	}
+	line("")
+	line("keys: 0-9/tab select motor, left/right jog, +/- jog velocity, space stop motor, s stop all, q quit")
+	b.WriteString("\x1b[J")
+	_, _ = os.Stdout.Write(b.Bytes())
+}
+
+// ioStates formats the states of IO ports, e.g. "0:ON  1:off"
+func ioStates(ports []byte, values []int) string {
+	s := make([]string, len(ports))
+	for i, port := range ports {
+		state := "off"
+		if values[i] != 0 {
+			state = "ON"
+		}
+		s[i] = fmt.Sprintf("%d:%-3s", port, state)
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/sim"
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// env is what the commands run with, the connection is opened with the first use
+type env struct {
+	ctx  context.Context
+	opts *options
+	out  io.Writer
+	q    *tmcl.TMCL
+
+	// logger is installed when the connection is opened, if set
+	logger tmcl.Logger
+}
+
+// run runs a command line without the global flags. Ctrl-C cancels the context of the
+// command, except for interactive commands handling it themselves.
+func (e *env) run(args []string) error {
+	c, ok := findCommand(args[0])
+	if !ok {
+		return errors.Errorf("unknown command %q", args[0])
+	}
+	if c.interactive {
+		return c.exec(e, args[1:])
+	}
+
+	parent := e.ctx
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt)
+	defer func() {
+		stop()
+		e.ctx = parent
+	}()
+	e.ctx = ctx
+	return c.exec(e, args[1:])
+}
+
+// conn returns the connection to the module
+func (e *env) conn() (*tmcl.TMCL, error) {
+	if e.q != nil {
+		return e.q, nil
+	}
+
+	var opts []tmcl.Option
+	if e.opts.address != 0 {
+		opts = append(opts, tmcl.WithModuleAddress(byte(e.opts.address)))
+	}
+	if e.opts.timeout > 0 {
+		opts = append(opts, tmcl.WithTimeout(e.opts.timeout))
+	}
+	if e.logger != nil {
+		opts = append(opts, tmcl.WithLogger(e.logger))
+	}
+
+	switch {
+	case e.opts.sim:
+		e.q = sim.NewModule().Connect(opts...)
+	case e.opts.tcp != "":
+		host, port, err := splitHostPort(e.opts.tcp)
+		if err != nil {
+			return nil, err
+		}
+		e.q = tmcl.NewTCP(host, port, opts...)
+	case e.opts.port != "":
+		e.q = tmcl.NewTMCL(e.opts.port, e.opts.baud, opts...)
+	default:
+		return nil, errors.New("no module given, use -port, -tcp or -sim (tmclctl scan lists the serial ports with modules)")
+	}
+	return e.q, nil
+}
+
+// close closes the connection if it was opened
+func (e *env) close() {
+	if e.q != nil {
+		_ = e.q.Close()
+		e.q = nil
+	}
+}
+
+// printf writes to the output
+func (e *env) printf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(e.out, format, args...)
+}
+
+// splitHostPort splits host[:port], the port defaulting to transport.DefaultTCPPort
+func splitHostPort(s string) (string, int, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return s, transport.DefaultTCPPort, nil
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return "", 0, errors.Errorf("invalid port %q", port)
+	}
+	return host, p, nil
+}
+
+// parseInt parses a 32 bit value, decimal or with 0x prefix
+func parseInt(s string) (int, error) {
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil || v < math.MinInt32 || v > math.MaxUint32 {
+		return 0, errors.Errorf("invalid value %q", s)
+	}
+	return int(int32(v)), nil
+}
+
+// parseByte parses a motor, bank, port or parameter number
+func parseByte(s string) (byte, error) {
+	v, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, errors.Errorf("invalid number %q", s)
+	}
+	return byte(v), nil
+}
+
+// parseList parses a list of motors or ports like "0,2,4-7"
+func parseList(s string) ([]byte, error) {
+	var res []byte
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parseByte(from)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseByte(to); err != nil {
+				return nil, err
+			}
+		}
+		for v := int(first); v <= int(last); v++ {
+			res = append(res, byte(v))
+		}
+	}
+	return res, nil
+}
+
+// parseAxisParam parses the number or name of an axis parameter
+func parseAxisParam(s string) (byte, error) {
+	if v, err := parseByte(s); err == nil {
+		return v, nil
+	}
+	if v, ok := tmcl.LookupAxisParam(s); ok {
+		return v, nil
+	}
+	return 0, errors.Errorf("unknown axis parameter %q", s)
+}
+
+// parseGlobalParam parses the number or name of a global parameter
+func parseGlobalParam(s string) (byte, error) {
+	if v, err := parseByte(s); err == nil {
+		return v, nil
+	}
+	if v, ok := tmcl.LookupGlobalParam(s); ok {
+		return v, nil
+	}
+	return 0, errors.Errorf("unknown global parameter %q", s)
+}
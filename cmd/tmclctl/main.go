@@ -0,0 +1,63 @@
+// Command tmclctl controls TMCL modules from the command line, e.g. during commissioning.
+//
+//	tmclctl -port /dev/ttyUSB0 version
+//	tmclctl -port /dev/ttyUSB0 move -wait 0 51200
+//	tmclctl -port /dev/ttyUSB0 gap "actual position" 0
+//
+// Run tmclctl without arguments for the list of commands. The shell command starts an
+// interactive shell that keeps the connection open between commands.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// options are the global flags selecting the module
+type options struct {
+	port    string
+	baud    int
+	tcp     string
+	address int
+	timeout time.Duration
+	sim     bool
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.port, "port", "", "serial port of the module")
+	flag.IntVar(&o.baud, "baud", 9600, "baud rate of the serial port")
+	flag.StringVar(&o.tcp, "tcp", "", "host[:port] of a module with Ethernet interface instead of a serial port")
+	flag.IntVar(&o.address, "addr", 0, "module address, 0 for a single module")
+	flag.DurationVar(&o.timeout, "timeout", 0, "reply timeout, 0 for the default")
+	flag.BoolVar(&o.sim, "sim", false, "use a simulated module instead of a real one")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	e := &env{ctx: context.Background(), opts: &o, out: os.Stdout}
+	err := e.run(flag.Args())
+	e.close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tmclctl:", err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the global flags and the commands
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: tmclctl [flags] command [arguments]\n\nflags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(out, "\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-14s %s\n", c.name, c.help)
+	}
+	fmt.Fprintf(out, "\nRun tmclctl command -h for the arguments of a command.\n")
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/assembler"
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// recording formats
+const (
+	recordGo   = "go"
+	recordTMCL = "tmcl"
+)
+
+// recording writes the requests of a shell session that change the state of the module as
+// Go function or TMCL program. Reads are left out, except that polling the position reached
+// flag after a move or the status of a reference search is written as wait.
+type recording struct {
+	format string
+	path   string
+	f      *os.File
+	err    error
+	count  int
+
+	// motors with a move or reference search not waited for yet
+	moving    map[byte]bool
+	searching map[byte]bool
+}
+
+// newRecording creates the file and writes the head of the recording
+func newRecording(format, path string) (*recording, error) {
+	if format != recordGo && format != recordTMCL {
+		return nil, errors.Errorf("unknown recording format %q, use go or tmcl", format)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	q := &recording{
+		format:    format,
+		path:      path,
+		f:         f,
+		moving:    map[byte]bool{},
+		searching: map[byte]bool{},
+	}
+	q.printf("// Session recorded by tmclctl on %s.\n", time.Now().Format("2006-01-02 15:04"))
+	if format == recordGo {
+		q.printf("func session(ctx context.Context, q *tmcl.TMCL) error {\n\tvar err error\n")
+	}
+	return q, nil
+}
+
+// close writes the end of the recording and closes the file
+func (q *recording) close() error {
+	if q.format == recordGo {
+		q.printf("\treturn err\n}\n")
+	} else {
+		q.printf("\tSTOP\n")
+	}
+	if err := q.f.Close(); err != nil && q.err == nil {
+		q.err = err
+	}
+	return q.err
+}
+
+// printf writes to the recording, errors are kept for close
+func (q *recording) printf(format string, args ...interface{}) {
+	if q.err != nil {
+		return
+	}
+	_, q.err = fmt.Fprintf(q.f, format, args...)
+}
+
+// request records a request that got a successful reply
+func (q *recording) request(req tmcl.Request, value int) {
+	switch {
+	case req.Cmd == 6 && req.Type == byte(tmcl.TargetPositionReached):
+		if value != 0 && q.moving[req.MotorBank] {
+			delete(q.moving, req.MotorBank)
+			q.waitPosition(req.MotorBank)
+		}
+		return
+	case req.Cmd == 13 && req.Type == 2:
+		if value == 0 && q.searching[req.MotorBank] {
+			delete(q.searching, req.MotorBank)
+			q.waitReference(req.MotorBank)
+		}
+		return
+	case isRead(req):
+		return
+	}
+
+	q.count++
+	if q.format == recordTMCL {
+		q.printf("\t%s\n", assembler.Format(protocol.Instruction{Cmd: req.Cmd, Type: req.Type, MotorBank: req.MotorBank, Value: req.Value}, nil))
+	} else {
+		q.printf("\t// %s\n", req)
+		q.goCheck(goCall(req))
+	}
+
+	switch {
+	case req.Cmd == 4, req.Cmd == 5 && req.Type == byte(tmcl.TargetPosition):
+		q.moving[req.MotorBank] = true
+	case req.Cmd == 13 && req.Type == 0:
+		q.searching[req.MotorBank] = true
+	}
+}
+
+// waitPosition records waiting for the target position of a motor
+func (q *recording) waitPosition(motor byte) {
+	if q.format == recordTMCL {
+		q.printf("\tWAIT POS, %d, 0\n", motor)
+		return
+	}
+	q.goCheck(fmt.Sprintf("err = q.WaitPositionReached(ctx, %d, 0)", motor))
+}
+
+// waitReference records waiting for the end of the reference search of a motor
+func (q *recording) waitReference(motor byte) {
+	if q.format == recordTMCL {
+		q.printf("\tWAIT RFS, %d, 0\n", motor)
+		return
+	}
+	q.printf("\tfor running := true; running; time.Sleep(10 * time.Millisecond) {\n")
+	q.printf("\t\tif running, err = q.RFSStatus(%d); err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n", motor)
+}
+
+// goCheck writes a Go statement assigning err followed by the error check
+func (q *recording) goCheck(stmt string) {
+	q.printf("\tif %s; err != nil {\n\t\treturn err\n\t}\n", stmt)
+}
+
+// isRead returns true for requests that do not change the state of the module
+func isRead(req tmcl.Request) bool {
+	switch req.Cmd {
+	case 6, 10, 15, 31, 135, 136:
+		return true
+	case 13:
+		return req.Type == 2
+	}
+	return false
+}
+
+// goCall returns the Go statement issuing a request, assigning the error to err
+func goCall(req tmcl.Request) string {
+	switch req.Cmd {
+	case 1:
+		return fmt.Sprintf("err = q.ROR(%d, %d)", req.MotorBank, req.Value)
+	case 2:
+		return fmt.Sprintf("err = q.ROL(%d, %d)", req.MotorBank, req.Value)
+	case 3:
+		return fmt.Sprintf("err = q.MST(%d)", req.MotorBank)
+	case 4:
+		if mode, ok := map[byte]string{tmcl.ABS: "tmcl.ABS", tmcl.REL: "tmcl.REL", tmcl.COORD: "tmcl.COORD"}[req.Type]; ok {
+			return fmt.Sprintf("err = q.MVP(%s, %d, %d)", mode, req.MotorBank, req.Value)
+		}
+	case 5:
+		return fmt.Sprintf("err = q.SAP(%d, %d, %d)", req.Type, req.MotorBank, req.Value)
+	case 7:
+		return fmt.Sprintf("err = q.STAP(%d, %d)", req.Type, req.MotorBank)
+	case 8:
+		return fmt.Sprintf("err = q.RSAP(%d, %d)", req.Type, req.MotorBank)
+	case 9:
+		return fmt.Sprintf("err = q.SGP(%d, %d, %d)", req.Type, req.MotorBank, req.Value)
+	case 11:
+		return fmt.Sprintf("_, err = q.STGP(%d, %d)", req.Type, req.MotorBank)
+	case 12:
+		return fmt.Sprintf("_, err = q.RSGP(%d, %d)", req.Type, req.MotorBank)
+	case 13:
+		switch req.Type {
+		case 0:
+			return fmt.Sprintf("err = q.RFSStart(%d)", req.MotorBank)
+		case 1:
+			return fmt.Sprintf("err = q.RFSStop(%d)", req.MotorBank)
+		}
+	case 14:
+		return fmt.Sprintf("err = q.SIO(%d, %d, %t)", req.Type, req.MotorBank, req.Value != 0)
+	}
+	return fmt.Sprintf("_, err = q.Exec(%d, %d, %d, %d)", req.Cmd, req.Type, req.MotorBank, req.Value)
+}
+
+// shellLogger shows the decoded replies in the shell and feeds the recording. Repetitions
+// of the same request and reply, e.g. while waiting for a motor, are shown once.
+type shellLogger struct {
+	out     io.Writer
+	replies bool
+	rec     *recording
+
+	last    string
+	repeats int
+}
+
+var _ tmcl.Logger = (*shellLogger)(nil)
+
+// Command shows a successful reply
+func (q *shellLogger) Command(req tmcl.Request, value int, d time.Duration) {
+	if q.rec != nil {
+		q.rec.request(req, value)
+	}
+	q.show(fmt.Sprintf("%s -> %d", req, value), d)
+}
+
+// Error shows a failed request
+func (q *shellLogger) Error(req tmcl.Request, err error, d time.Duration) {
+	q.show(fmt.Sprintf("%s -> %v", req, err), d)
+}
+
+// Timeout shows a request without reply
+func (q *shellLogger) Timeout(req tmcl.Request, d time.Duration) {
+	q.show(fmt.Sprintf("%s -> no reply", req), d)
+}
+
+// Retry shows a repeated request
+func (q *shellLogger) Retry(req tmcl.Request, attempt int, err error) {
+	q.show(fmt.Sprintf("%s -> retry %d after %v", req, attempt, err), 0)
+}
+
+// show prints a line unless it repeats the last one
+func (q *shellLogger) show(line string, d time.Duration) {
+	if !q.replies {
+		return
+	}
+	if line == q.last {
+		q.repeats++
+		return
+	}
+	q.flush()
+	q.last = line
+	if d = d.Round(100 * time.Microsecond); d > 0 {
+		line += " (" + d.String() + ")"
+	}
+	_, _ = fmt.Fprintln(q.out, "  "+line)
+}
+
+// flush prints the number of repetitions not shown, called after every command
+func (q *shellLogger) flush() {
+	if q.repeats > 0 {
+		_, _ = fmt.Fprintf(q.out, "  (%d times more)\n", q.repeats)
+	}
+	q.last = ""
+	q.repeats = 0
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/peterh/liner"
+	"github.com/pkg/errors"
+
+	tmcl "github.com/raceresult/go-tmcl"
+)
+
+// shellHelp describes the commands only available in the shell
+const shellHelp = `
+shell commands:
+  record go|tmcl file  record the commands as Go function or TMCL program
+  record stop          end the recording
+  replies on|off       show the decoded replies of all requests, on by default
+  help                 print this list
+  exit                 leave the shell, also Ctrl-D
+
+Parameter names can be given with underscores instead of spaces, e.g. gap actual_position 0.
+Tab completes commands and parameter names, Ctrl-C stops a running command.
+`
+
+// shell is an interactive session, the connection stays open between commands
+type shell struct {
+	e   *env
+	log *shellLogger
+}
+
+func runShell(e *env, fs *flag.FlagSet, args []string) error {
+	if err := wantArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if e.logger != nil {
+		return errors.New("already in the shell")
+	}
+	sh := &shell{e: e, log: &shellLogger{out: e.out, replies: true}}
+	e.logger = sh.log
+	defer func() {
+		e.logger = nil
+		e.close()
+	}()
+
+	line := liner.NewLiner()
+	defer func() { _ = line.Close() }()
+	line.SetCtrlCAborts(true)
+	line.SetWordCompleter(complete)
+	history := historyFile()
+	if f, err := os.Open(history); err == nil {
+		_, _ = line.ReadHistory(f)
+		_ = f.Close()
+	}
+
+	for {
+		s, err := line.Prompt("tmcl> ")
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err == io.EOF {
+			e.printf("\n")
+			break
+		}
+		if err != nil {
+			return err
+		}
+		words, err := splitWords(s)
+		if err != nil {
+			e.printf("error: %v\n", err)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		line.AppendHistory(s)
+		if words[0] == "exit" || words[0] == "quit" {
+			break
+		}
+		err = sh.exec(words)
+		sh.log.flush()
+		if err != nil {
+			e.printf("error: %v\n", err)
+		}
+	}
+
+	if f, err := os.Create(history); err == nil {
+		_, _ = line.WriteHistory(f)
+		_ = f.Close()
+	}
+	return sh.stopRecording()
+}
+
+// exec runs one line of the shell
+func (q *shell) exec(words []string) error {
+	switch words[0] {
+	case "help":
+		usage()
+		q.e.printf("%s", shellHelp)
+		return nil
+	case "record":
+		return q.record(words[1:])
+	case "replies":
+		if len(words) != 2 || (words[1] != "on" && words[1] != "off") {
+			return errors.New("usage: replies on|off")
+		}
+		q.log.replies = words[1] == "on"
+		return nil
+	}
+	return q.e.run(words)
+}
+
+// record starts or stops recording
+func (q *shell) record(args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "stop":
+		if q.log.rec == nil {
+			return errors.New("not recording")
+		}
+		return q.stopRecording()
+	case len(args) == 2:
+		if q.log.rec != nil {
+			return errors.Errorf("already recording to %s", q.log.rec.path)
+		}
+		rec, err := newRecording(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		q.log.rec = rec
+		q.e.printf("recording to %s\n", rec.path)
+		return nil
+	}
+	return errors.New("usage: record go|tmcl file, record stop")
+}
+
+// stopRecording ends the recording, if any
+func (q *shell) stopRecording() error {
+	rec := q.log.rec
+	if rec == nil {
+		return nil
+	}
+	q.log.rec = nil
+	if err := rec.close(); err != nil {
+		return errors.Wrapf(err, "recording to %s", rec.path)
+	}
+	q.e.printf("recorded %d requests to %s\n", rec.count, rec.path)
+	return nil
+}
+
+// historyFile returns the file the shell history is kept in
+func historyFile() string {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, ".tmclctl_history")
+}
+
+// complete completes the word at the cursor with the commands or the arguments known to it
+func complete(line string, pos int) (head string, completions []string, tail string) {
+	head, tail = line[:pos], line[pos:]
+	i := strings.LastIndexFunc(head, unicode.IsSpace) + 1
+	prefix := strings.ToLower(head[i:])
+	head = head[:i]
+
+	words := strings.Fields(head)
+	var candidates []string
+	if len(words) == 0 {
+		for _, c := range commands {
+			candidates = append(candidates, c.name)
+		}
+		candidates = append(candidates, "record", "replies", "help", "exit")
+	} else {
+		// only the first argument after the flags is completed
+		var n int
+		for _, w := range words[1:] {
+			if !strings.HasPrefix(w, "-") {
+				n++
+			}
+		}
+		if n == 0 {
+			candidates = argumentNames(words[0])
+		}
+	}
+
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(c), prefix) {
+			completions = append(completions, c)
+		}
+	}
+	sort.Strings(completions)
+	return head, completions, tail
+}
+
+// argumentNames returns the possible first arguments of a command
+func argumentNames(command string) []string {
+	switch command {
+	case "gap", "sap":
+		return paramWords(tmcl.AxisParamNames())
+	case "ggp", "sgp":
+		return paramWords(tmcl.GlobalParamNames())
+	case "io":
+		return []string{"in", "out", "analog"}
+	case "record":
+		return []string{recordGo, recordTMCL, "stop"}
+	case "replies":
+		return []string{"on", "off"}
+	}
+	return nil
+}
+
+// paramWords returns parameter names as single words
+func paramWords(names map[byte]string) []string {
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		res = append(res, strings.ReplaceAll(name, " ", "_"))
+	}
+	return res
+}
+
+// splitWords splits a line of the shell at white space, single or double quotes keeping
+// words with spaces together
+func splitWords(s string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+		quote  rune
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
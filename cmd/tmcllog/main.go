@@ -0,0 +1,46 @@
+// Command tmcllog prints session logs written with tmcl.WithSessionLog
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/raceresult/go-tmcl/sessionlog"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, name := range flag.Args() {
+		if err := printFile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// printFile prints one session log
+func printFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if flag.NArg() > 1 {
+		fmt.Printf("== %s\n", name)
+	}
+	return sessionlog.Print(os.Stdout, f)
+}
@@ -1,9 +1,16 @@
 package tmcl
 
+import "fmt"
+
 const ABS byte = 0
 const REL byte = 1
 const COORD byte = 2
 
+// IO banks used with SIO and GIO
+const DigitalInputBank byte = 0
+const AnalogInputBank byte = 1
+const DigitalOutputBank byte = 2
+
 // ROR is Rotate right
 func (q *TMCL) ROR(motor byte, velocity int) error {
 	_, err := q.Exec(1, 0, motor, velocity)
@@ -39,6 +46,11 @@ func (q *TMCL) GAP(index byte, motor byte) (int, error) {
 	return q.Exec(6, index, motor, 0)
 }
 
+// GAPUnsigned is get axis parameter for parameters that are bit fields or unsigned counters
+func (q *TMCL) GAPUnsigned(index byte, motor byte) (uint32, error) {
+	return q.ExecUnsigned(6, index, motor, 0)
+}
+
 // STAP is store axis parameter
 func (q *TMCL) STAP(index byte, motor byte) error {
 	_, err := q.Exec(7, index, motor, 0)
@@ -62,6 +74,11 @@ func (q *TMCL) GGP(index byte, bank byte) (int, error) {
 	return q.Exec(10, index, bank, 0)
 }
 
+// GGPUnsigned is get global parameter for parameters that are bit fields or unsigned counters
+func (q *TMCL) GGPUnsigned(index byte, bank byte) (uint32, error) {
+	return q.ExecUnsigned(10, index, bank, 0)
+}
+
 // STGP is store global parameter
 func (q *TMCL) STGP(index byte, bank byte) (int, error) {
 	return q.Exec(11, index, bank, 0)
@@ -72,6 +89,24 @@ func (q *TMCL) RSGP(index byte, bank byte) (int, error) {
 	return q.Exec(12, index, bank, 0)
 }
 
+// RFSStart starts the reference search of a motor
+func (q *TMCL) RFSStart(motor byte) error {
+	_, err := q.Exec(13, 0, motor, 0)
+	return err
+}
+
+// RFSStop aborts the reference search of a motor
+func (q *TMCL) RFSStop(motor byte) error {
+	_, err := q.Exec(13, 1, motor, 0)
+	return err
+}
+
+// RFSStatus returns true while the reference search of a motor is still running
+func (q *TMCL) RFSStatus(motor byte) (bool, error) {
+	v, err := q.Exec(13, 2, motor, 0)
+	return v != 0, err
+}
+
 // SIO is set io
 func (q *TMCL) SIO(port byte, bank byte, value bool) error {
 	var b int
@@ -86,3 +121,61 @@ func (q *TMCL) SIO(port byte, bank byte, value bool) error {
 func (q *TMCL) GIO(port byte, bank byte) (int, error) {
 	return q.Exec(15, port, bank, 0)
 }
+
+// AAP is accumulator to axis parameter, it copies the accumulator of the TMCL interpreter to
+// an axis parameter
+func (q *TMCL) AAP(index byte, motor byte) error {
+	_, err := q.Exec(34, index, motor, 0)
+	return err
+}
+
+// AGP is accumulator to global parameter, it copies the accumulator of the TMCL interpreter
+// to a global parameter
+func (q *TMCL) AGP(index byte, bank byte) error {
+	_, err := q.Exec(35, index, bank, 0)
+	return err
+}
+
+// GetFirmwareVersion returns module type and firmware revision in binary format as hex string
+func (q *TMCL) GetFirmwareVersion() (string, error) {
+	v, err := q.Exec(136, 1, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08X", uint32(v)), nil
+}
+
+// StopApplication stops a running TMCL standalone application
+func (q *TMCL) StopApplication() error {
+	_, err := q.Exec(128, 0, 0, 0)
+	return err
+}
+
+// RunApplication starts or continues the TMCL standalone application at the current address
+func (q *TMCL) RunApplication() error {
+	_, err := q.Exec(129, 0, 0, 0)
+	return err
+}
+
+// RunApplicationAt starts the TMCL standalone application at the given address
+func (q *TMCL) RunApplicationAt(address int) error {
+	_, err := q.Exec(129, 1, 0, address)
+	return err
+}
+
+// StepApplication executes only the next command of the TMCL standalone application
+func (q *TMCL) StepApplication() error {
+	_, err := q.Exec(130, 0, 0, 0)
+	return err
+}
+
+// ResetApplication sets the program counter to zero and stops the standalone application
+func (q *TMCL) ResetApplication() error {
+	_, err := q.Exec(131, 0, 0, 0)
+	return err
+}
+
+// GetApplicationStatus returns the state of the standalone application: 0 stop, 1 run, 2 step, 3 reset
+func (q *TMCL) GetApplicationStatus() (int, error) {
+	return q.Exec(135, 0, 0, 0)
+}
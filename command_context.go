@@ -0,0 +1,151 @@
+package tmcl
+
+import (
+	"context"
+	"fmt"
+)
+
+// RORContext is Rotate right with a context
+func (q *TMCL) RORContext(ctx context.Context, motor byte, velocity int) error {
+	_, err := q.ExecContext(ctx, 1, 0, motor, velocity)
+	return err
+}
+
+// ROLContext is rotate left with a context
+func (q *TMCL) ROLContext(ctx context.Context, motor byte, velocity int) error {
+	_, err := q.ExecContext(ctx, 2, 0, motor, velocity)
+	return err
+}
+
+// MSTContext is motor stop with a context
+func (q *TMCL) MSTContext(ctx context.Context, motor byte) error {
+	_, err := q.ExecContext(ctx, 3, 0, motor, 0)
+	return err
+}
+
+// MVPContext is moving an axis with a context
+func (q *TMCL) MVPContext(ctx context.Context, mode byte, motor byte, value int) error {
+	_, err := q.ExecContext(ctx, 4, mode, motor, value)
+	return err
+}
+
+// SAPContext is set axis parameter with a context
+func (q *TMCL) SAPContext(ctx context.Context, index byte, motor byte, value int) error {
+	_, err := q.ExecContext(ctx, 5, index, motor, value)
+	return err
+}
+
+// GAPContext is get axis parameter with a context
+func (q *TMCL) GAPContext(ctx context.Context, index byte, motor byte) (int, error) {
+	return q.ExecContext(ctx, 6, index, motor, 0)
+}
+
+// STAPContext is store axis parameter with a context
+func (q *TMCL) STAPContext(ctx context.Context, index byte, motor byte) error {
+	_, err := q.ExecContext(ctx, 7, index, motor, 0)
+	return err
+}
+
+// RSAPContext is restore axis parameter with a context
+func (q *TMCL) RSAPContext(ctx context.Context, index byte, motor byte) error {
+	_, err := q.ExecContext(ctx, 8, index, motor, 0)
+	return err
+}
+
+// SGPContext is set global parameter with a context
+func (q *TMCL) SGPContext(ctx context.Context, index byte, bank byte, value int) error {
+	_, err := q.ExecContext(ctx, 9, index, bank, value)
+	return err
+}
+
+// GGPContext is get global parameter with a context
+func (q *TMCL) GGPContext(ctx context.Context, index byte, bank byte) (int, error) {
+	return q.ExecContext(ctx, 10, index, bank, 0)
+}
+
+// STGPContext is store global parameter with a context
+func (q *TMCL) STGPContext(ctx context.Context, index byte, bank byte) (int, error) {
+	return q.ExecContext(ctx, 11, index, bank, 0)
+}
+
+// RSGPContext is restore global parameter with a context
+func (q *TMCL) RSGPContext(ctx context.Context, index byte, bank byte) (int, error) {
+	return q.ExecContext(ctx, 12, index, bank, 0)
+}
+
+// RFSStartContext starts the reference search of a motor
+func (q *TMCL) RFSStartContext(ctx context.Context, motor byte) error {
+	_, err := q.ExecContext(ctx, 13, 0, motor, 0)
+	return err
+}
+
+// RFSStopContext aborts the reference search of a motor
+func (q *TMCL) RFSStopContext(ctx context.Context, motor byte) error {
+	_, err := q.ExecContext(ctx, 13, 1, motor, 0)
+	return err
+}
+
+// RFSStatusContext returns true while the reference search of a motor is still running
+func (q *TMCL) RFSStatusContext(ctx context.Context, motor byte) (bool, error) {
+	v, err := q.ExecContext(ctx, 13, 2, motor, 0)
+	return v != 0, err
+}
+
+// SIOContext is set io with a context
+func (q *TMCL) SIOContext(ctx context.Context, port byte, bank byte, value bool) error {
+	var b int
+	if value {
+		b = 1
+	}
+	_, err := q.ExecContext(ctx, 14, port, bank, b)
+	return err
+}
+
+// GIOContext is get io with a context
+func (q *TMCL) GIOContext(ctx context.Context, port byte, bank byte) (int, error) {
+	return q.ExecContext(ctx, 15, port, bank, 0)
+}
+
+// GetFirmwareVersionContext returns module type and firmware revision as hex string
+func (q *TMCL) GetFirmwareVersionContext(ctx context.Context) (string, error) {
+	v, err := q.ExecContext(ctx, 136, 1, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08X", uint32(v)), nil
+}
+
+// StopApplicationContext stops a running TMCL standalone application
+func (q *TMCL) StopApplicationContext(ctx context.Context) error {
+	_, err := q.ExecContext(ctx, 128, 0, 0, 0)
+	return err
+}
+
+// RunApplicationContext starts or continues the TMCL standalone application
+func (q *TMCL) RunApplicationContext(ctx context.Context) error {
+	_, err := q.ExecContext(ctx, 129, 0, 0, 0)
+	return err
+}
+
+// RunApplicationAtContext starts the TMCL standalone application at the given address
+func (q *TMCL) RunApplicationAtContext(ctx context.Context, address int) error {
+	_, err := q.ExecContext(ctx, 129, 1, 0, address)
+	return err
+}
+
+// StepApplicationContext executes only the next command of the TMCL standalone application
+func (q *TMCL) StepApplicationContext(ctx context.Context) error {
+	_, err := q.ExecContext(ctx, 130, 0, 0, 0)
+	return err
+}
+
+// ResetApplicationContext sets the program counter to zero and stops the standalone application
+func (q *TMCL) ResetApplicationContext(ctx context.Context) error {
+	_, err := q.ExecContext(ctx, 131, 0, 0, 0)
+	return err
+}
+
+// GetApplicationStatusContext returns the state of the standalone application
+func (q *TMCL) GetApplicationStatusContext(ctx context.Context) (int, error) {
+	return q.ExecContext(ctx, 135, 0, 0, 0)
+}
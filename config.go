@@ -0,0 +1,131 @@
+package tmcl
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// userVariables is the number of user variables of global parameter bank 2
+const userVariables = 56
+
+// stateAxisParams are writable axis parameters which are motion state, not configuration
+var stateAxisParams = map[AxisParam]bool{TargetPosition: true, ActualPosition: true, TargetSpeed: true, EncoderPosition: true}
+
+// configGlobalParams are the bank 0 global parameters making up the module configuration
+var configGlobalParams = []byte{65, 66, 67, 68, 69, 70, 71, 75, 76, 77, 80, 81, 82, 83, 84, 85, 87}
+
+// communicationParams are the bank 0 global parameters changing how the module is reached,
+// which ApplyConfig does not write. ASCII mode and TMCL code protection are among them, as
+// they can lock the host out as well.
+var communicationParams = map[byte]bool{65: true, 66: true, 67: true, 69: true, 70: true, 71: true, 76: true, 81: true, 83: true, 87: true}
+
+// configAxisParams returns the writable axis parameters of the module type making up the
+// configuration of a motor, leaving out positions and velocities. Unknown module types use
+// the parameters common to most modules.
+func (q *TMCL) configAxisParams() []byte {
+	table, ok := ParamTables[q.ModuleType()]
+	if !ok {
+		table = tmclBaseParams
+	}
+	params := make([]byte, 0, len(table))
+	for p, r := range table {
+		if !r.ReadOnly && !stateAxisParams[p] {
+			params = append(params, byte(p))
+		}
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i] < params[j] })
+	return params
+}
+
+// Config is a snapshot of the configuration of a module: axis parameters by motor and
+// parameter number, global parameters by bank and parameter number. Parameters the module
+// does not support are left out.
+type Config struct {
+	ModuleType int                   `json:"moduleType"`
+	Motors     map[byte]map[byte]int `json:"motors"`
+	Globals    map[byte]map[byte]int `json:"globals"`
+}
+
+// DumpConfig reads the writable axis parameters of the module type except positions and
+// velocities for all motors, the bank 0 global parameters and the user variables of bank 2
+func (q *TMCL) DumpConfig() (*Config, error) {
+	axes := q.AxisCount()
+	if axes == 0 {
+		axes = 1
+	}
+	c := &Config{
+		ModuleType: q.ModuleType(),
+		Motors:     make(map[byte]map[byte]int, axes),
+		Globals:    make(map[byte]map[byte]int, 2),
+	}
+
+	params := q.configAxisParams()
+	for motor := 0; motor < axes; motor++ {
+		m, err := q.DumpAxisParams(byte(motor), params, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "motor %d", motor)
+		}
+		c.Motors[byte(motor)] = m
+	}
+
+	globals, err := q.DumpGlobalParams(0, configGlobalParams, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "bank 0")
+	}
+	c.Globals[0] = globals
+
+	vars := make([]byte, userVariables)
+	for i := range vars {
+		vars[i] = byte(i)
+	}
+	user, err := q.DumpGlobalParams(2, vars, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "bank 2")
+	}
+	c.Globals[2] = user
+	return c, nil
+}
+
+// ApplyConfig writes a configuration snapshot to the module in one transaction. Global
+// parameters changing the communication (baud rate, addresses, CAN settings, ASCII mode) and
+// the code protection are not written, as the module would not be reachable anymore; use the
+// dedicated setters for them. Bank 0 global parameters are stored in the EEPROM by the
+// module, axis parameters and user variables only have to be stored if they shall survive a
+// reset.
+func (q *TMCL) ApplyConfig(c *Config) error {
+	return q.WithTransaction(func(tx *TMCL) error {
+		for _, motor := range sortedKeys(c.Motors) {
+			values := make(map[AxisParam]int, len(c.Motors[motor]))
+			for index, v := range c.Motors[motor] {
+				values[AxisParam(index)] = v
+			}
+			if err := tx.SetAxisParams(motor, values); err != nil {
+				return errors.Wrapf(err, "motor %d", motor)
+			}
+		}
+
+		for _, bank := range sortedKeys(c.Globals) {
+			params := c.Globals[bank]
+			for _, index := range sortedKeys(params) {
+				if bank == 0 && communicationParams[index] {
+					continue
+				}
+				if err := tx.SGP(index, bank, params[index]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// sortedKeys returns the keys of a map in ascending order
+func sortedKeys[V any](m map[byte]V) []byte {
+	keys := make([]byte, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
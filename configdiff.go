@@ -0,0 +1,95 @@
+package tmcl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ConfigChange is a parameter differing between two configuration snapshots
+type ConfigChange struct {
+	// Global is true for global parameters, MotorBank is the bank then, otherwise the motor
+	Global    bool
+	MotorBank byte
+	Index     byte
+	Name      string
+
+	// Old and New are the values in the first and second snapshot, valid if HasOld/HasNew
+	// are set, a parameter missing in one snapshot is reported with the flag cleared
+	Old, New       int
+	HasOld, HasNew bool
+}
+
+// String formats the change, e.g. `motor 0 "maximum acceleration": 100 -> 200`
+func (c ConfigChange) String() string {
+	where := "motor " + strconv.Itoa(int(c.MotorBank))
+	if c.Global {
+		where = "bank " + strconv.Itoa(int(c.MotorBank))
+	}
+	from, to := "missing", "missing"
+	if c.HasOld {
+		from = strconv.Itoa(c.Old)
+	}
+	if c.HasNew {
+		to = strconv.Itoa(c.New)
+	}
+	return fmt.Sprintf("%s %q: %s -> %s", where, c.Name, from, to)
+}
+
+// DiffConfig returns the parameters differing between two snapshots, ordered by axis
+// parameters before global parameters, then by motor or bank and parameter number
+func DiffConfig(a, b *Config) []ConfigChange {
+	var changes []ConfigChange
+	changes = diffParams(changes, false, a.Motors, b.Motors)
+	changes = diffParams(changes, true, a.Globals, b.Globals)
+	return changes
+}
+
+// diffParams appends the differences of two sets of parameter tables
+func diffParams(changes []ConfigChange, global bool, a, b map[byte]map[byte]int) []ConfigChange {
+	for _, mb := range sortedKeys(unionKeys(a, b)) {
+		for _, index := range sortedKeys(unionKeys(a[mb], b[mb])) {
+			oldValue, hasOld := a[mb][index]
+			newValue, hasNew := b[mb][index]
+			if hasOld == hasNew && oldValue == newValue {
+				continue
+			}
+			name := AxisParamName(index)
+			if global {
+				name = GlobalParamName(index, mb)
+			}
+			changes = append(changes, ConfigChange{
+				Global:    global,
+				MotorBank: mb,
+				Index:     index,
+				Name:      name,
+				Old:       oldValue,
+				New:       newValue,
+				HasOld:    hasOld,
+				HasNew:    hasNew,
+			})
+		}
+	}
+	return changes
+}
+
+// unionKeys returns a set of the keys of both maps
+func unionKeys[V any](a, b map[byte]V) map[byte]struct{} {
+	keys := make(map[byte]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
+
+// ConfigDrift reads the configuration of the module and returns how it differs from the
+// snapshot, Old being the snapshot values and New the current ones
+func (q *TMCL) ConfigDrift(c *Config) ([]ConfigChange, error) {
+	current, err := q.DumpConfig()
+	if err != nil {
+		return nil, err
+	}
+	return DiffConfig(c, current), nil
+}
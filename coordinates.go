@@ -0,0 +1,86 @@
+package tmcl
+
+// MaxCoordinate is the highest coordinate number, coordinate 0 is always kept in RAM only
+const MaxCoordinate = 20
+
+// coordinateEEPROM is the motor number of SCO and GCO copying coordinates to or from the EEPROM
+const coordinateEEPROM = 255
+
+// SetCoordinate is SCO, it sets a coordinate of a motor for use with MVP COORD
+func (q *TMCL) SetCoordinate(coordinate byte, motor byte, position int) error {
+	_, err := q.Exec(30, coordinate, motor, position)
+	return err
+}
+
+// GetCoordinate is GCO, it returns a coordinate of a motor
+func (q *TMCL) GetCoordinate(coordinate byte, motor byte) (int, error) {
+	return q.Exec(31, coordinate, motor, 0)
+}
+
+// CaptureCoordinate is CCO, it copies the actual position of a motor to a coordinate
+func (q *TMCL) CaptureCoordinate(coordinate byte, motor byte) error {
+	_, err := q.Exec(32, coordinate, motor, 0)
+	return err
+}
+
+// MoveToCoordinate moves a motor to a coordinate set before with SetCoordinate or
+// CaptureCoordinate
+func (q *TMCL) MoveToCoordinate(motor byte, coordinate byte) error {
+	return q.MVP(COORD, motor, int(coordinate))
+}
+
+// StoreCoordinates copies a coordinate (1 to MaxCoordinate) from RAM to the EEPROM, 0 copies
+// all of them
+func (q *TMCL) StoreCoordinates(coordinate byte) error {
+	_, err := q.Exec(30, coordinate, coordinateEEPROM, 0)
+	return err
+}
+
+// LoadCoordinates copies a coordinate (1 to MaxCoordinate) from the EEPROM to RAM, 0 copies
+// all of them
+func (q *TMCL) LoadCoordinates(coordinate byte) error {
+	_, err := q.Exec(31, coordinate, coordinateEEPROM, 0)
+	return err
+}
+
+// DumpCoordinates reads the complete coordinate table of a motor in one bulk operation, the
+// index of the result is the coordinate number
+func (q *TMCL) DumpCoordinates(motor byte, progress ProgressFunc) ([]int, error) {
+	n := MaxCoordinate + 1
+	frames := make([]byte, n*frameSize)
+	for i := 0; i < n; i++ {
+		q.encodeFrame(frames[i*frameSize:(i+1)*frameSize], 31, byte(i), motor, 0)
+	}
+
+	values := make([]int, n)
+	statuses := make([]byte, n)
+	if err := q.execBulk(globalKey, frames, values, statuses, progress); err != nil {
+		return nil, err
+	}
+
+	var err error
+	q.do(globalKey, func() {
+		for i, status := range statuses {
+			if err = q.statusError(status, Request{Cmd: 31, Type: byte(i), MotorBank: motor}); err != nil {
+				return
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// RestoreCoordinates writes a coordinate table read by DumpCoordinates back to a motor
+func (q *TMCL) RestoreCoordinates(motor byte, coordinates []int) error {
+	for i, position := range coordinates {
+		if i > MaxCoordinate {
+			break
+		}
+		if err := q.SetCoordinate(byte(i), motor, position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
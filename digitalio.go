@@ -0,0 +1,179 @@
+package tmcl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// allPorts is the port number of GIO and SIO addressing all digital ports of a bank at once
+const allPorts = 255
+
+// defaultDigitalPorts is the number of digital ports assumed for unknown module types
+const defaultDigitalPorts = 8
+
+// InputPort is a digital input read from DigitalInputBank
+type InputPort byte
+
+// OutputPort is a digital output of DigitalOutputBank
+type OutputPort byte
+
+// AnalogPort is an analog input read from AnalogInputBank
+type AnalogPort byte
+
+// Digital inputs of the TMCM-351
+const (
+	IN0   InputPort = 0
+	IN1   InputPort = 1
+	IN2   InputPort = 2
+	IN3   InputPort = 3
+	IN4   InputPort = 4
+	IN5   InputPort = 5
+	ADIN6 InputPort = 6
+	ADIN7 InputPort = 7
+)
+
+// Digital outputs of the TMCM-351
+const (
+	OUT0 OutputPort = 0
+	OUT1 OutputPort = 1
+	OUT2 OutputPort = 2
+	OUT3 OutputPort = 3
+	OUT4 OutputPort = 4
+	OUT5 OutputPort = 5
+	OUT6 OutputPort = 6
+	OUT7 OutputPort = 7
+)
+
+// Analog inputs of the TMCM-351
+const (
+	AIN0          AnalogPort = 0
+	AIN1          AnalogPort = 1
+	AIN2          AnalogPort = 2
+	AIN3          AnalogPort = 3
+	SupplyVoltage AnalogPort = 4
+	Temperature   AnalogPort = 5
+	AIN6          AnalogPort = 6
+	AIN7          AnalogPort = 7
+)
+
+// GetDigitalInput returns the state of a digital input
+func (q *TMCL) GetDigitalInput(port InputPort) (bool, error) {
+	v, err := q.GIO(byte(port), DigitalInputBank)
+	return v != 0, err
+}
+
+// GetDigitalOutput returns the state of a digital output
+func (q *TMCL) GetDigitalOutput(port OutputPort) (bool, error) {
+	v, err := q.GIO(byte(port), DigitalOutputBank)
+	return v != 0, err
+}
+
+// SetDigitalOutput sets a digital output
+func (q *TMCL) SetDigitalOutput(port OutputPort, value bool) error {
+	return q.SIO(byte(port), DigitalOutputBank, value)
+}
+
+// GetAnalogInput returns the raw ADC value of an analog input
+func (q *TMCL) GetAnalogInput(port AnalogPort) (int, error) {
+	return q.GIO(byte(port), AnalogInputBank)
+}
+
+// InputState are the digital inputs read at once, bit n of Bits being input n
+type InputState struct {
+	Bits uint32
+
+	// Count is the number of inputs of the module
+	Count int
+}
+
+// Get returns the state of an input
+func (s InputState) Get(port InputPort) bool {
+	return s.Bits&(1<<port) != 0
+}
+
+// String lists the inputs as 0 and 1, input 0 first
+func (s InputState) String() string {
+	return formatPortBits("IN", s.Bits, s.Count)
+}
+
+// OutputLevels are the digital outputs read at once, bit n of Bits being output n
+type OutputLevels struct {
+	Bits uint32
+
+	// Count is the number of outputs of the module
+	Count int
+}
+
+// Get returns the state of an output
+func (s OutputLevels) Get(port OutputPort) bool {
+	return s.Bits&(1<<port) != 0
+}
+
+// String lists the outputs as 0 and 1, output 0 first
+func (s OutputLevels) String() string {
+	return formatPortBits("OUT", s.Bits, s.Count)
+}
+
+// formatPortBits formats port states like "IN0=1 IN1=0"
+func formatPortBits(prefix string, bits uint32, count int) string {
+	s := make([]string, count)
+	for i := range s {
+		s[i] = fmt.Sprintf("%s%d=%d", prefix, i, bits>>i&1)
+	}
+	return strings.Join(s, " ")
+}
+
+// ReadInputs reads all digital inputs with one GIO of port 255. If the firmware does not
+// support it, the inputs are read one by one, holding the command lock only once.
+func (q *TMCL) ReadInputs() (InputState, error) {
+	bits, count, err := q.readPorts(DigitalInputBank)
+	return InputState{Bits: bits, Count: count}, err
+}
+
+// ReadOutputs reads all digital outputs like ReadInputs
+func (q *TMCL) ReadOutputs() (OutputLevels, error) {
+	bits, count, err := q.readPorts(DigitalOutputBank)
+	return OutputLevels{Bits: bits, Count: count}, err
+}
+
+// readPorts reads all digital ports of a bank as bit field
+func (q *TMCL) readPorts(bank byte) (uint32, int, error) {
+	count := defaultDigitalPorts
+	if f, ok := knownFeatures[q.ModuleType()]; ok {
+		count = f.inputs
+		if bank == DigitalOutputBank {
+			count = f.outputs
+		}
+	}
+	mask := uint32(1)<<count - 1
+
+	if !q.allPortsUnsupported[bank].Load() {
+		v, err := q.GIO(allPorts, bank)
+		if err == nil {
+			return uint32(v) & mask, count, nil
+		}
+		if !errors.Is(err, ErrWrongType) && !errors.Is(err, ErrInvalidValue) {
+			return 0, count, err
+		}
+		q.allPortsUnsupported[bank].Store(true)
+	}
+
+	params := make([]PollParam, count)
+	for i := range params {
+		params[i] = IOPoll(byte(i), bank)
+	}
+	key, frames := q.encodePollParams(params)
+	values := make([]int, count)
+	if err := q.pollBulk(key, frames, values, make([]byte, count)); err != nil {
+		return 0, count, err
+	}
+	var bits uint32
+	for i, v := range values {
+		if v != 0 {
+			bits |= 1 << i
+		}
+	}
+	return bits, count, nil
+}
@@ -0,0 +1,61 @@
+package tmcl
+
+import (
+	"context"
+	"time"
+
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// discoverTimeout is the reply timeout used when probing serial ports
+const discoverTimeout = 200 * time.Millisecond
+
+// defaultBaudCandidates are the baud rates DiscoverPorts tries if none are given, the
+// factory default first
+var defaultBaudCandidates = []int{9600, 115200, 57600, 38400, 19200}
+
+// DiscoveredPort is a serial port with a TMCL module answering at the baud rate
+type DiscoveredPort struct {
+	Port     string
+	BaudRate int
+	Version  Version
+}
+
+// DiscoverPorts probes all serial ports of the system at the given baud rates (nil for the
+// common ones) by reading the firmware version and returns the ports with a module answering.
+// Ports which cannot be opened, e.g. because they are in use, are skipped.
+func DiscoverPorts(ctx context.Context, baudCandidates []int) ([]DiscoveredPort, error) {
+	if baudCandidates == nil {
+		baudCandidates = defaultBaudCandidates
+	}
+	ports, err := transport.ListSerialPorts()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []DiscoveredPort
+	for _, port := range ports {
+		for _, baud := range baudCandidates {
+			if err := ctx.Err(); err != nil {
+				return found, err
+			}
+			v, err := probePort(ctx, port, baud)
+			if err == nil {
+				found = append(found, DiscoveredPort{Port: port, BaudRate: baud, Version: v})
+				break
+			}
+			if !isLineError(err) {
+				// port not usable at all
+				break
+			}
+		}
+	}
+	return found, ctx.Err()
+}
+
+// probePort reads the firmware version of a module on a serial port
+func probePort(ctx context.Context, port string, baud int) (Version, error) {
+	q := NewTMCL(port, baud, WithTimeout(discoverTimeout))
+	defer func() { _ = q.Close() }()
+	return q.GetVersionContext(ctx)
+}
@@ -0,0 +1,22 @@
+// Package tmcl implements the Trinamic Motion Control Language to control TMCL modules over
+// serial connections.
+//
+// The package is split into subpackages which can also be used on their own:
+//
+//	protocol   telegram encoding, checksums, command and status names
+//	transport  connections the telegrams are exchanged over
+//	params     typed axis parameter definitions
+//	motion     axes with positions and velocities in physical units
+//	program    builder of standalone programs
+//	assembler  TMCL assembly for standalone programs
+//	sim        simulated modules answering TMCL telegrams
+//	tmcltest   simulator and mock boards for tests without hardware
+//	zerologger Logger writing to zerolog
+//	tmclprom   command metrics as Prometheus collector
+//	tmclotel   OpenTelemetry spans per request
+//	sessionlog binary log of all data exchanged, printed by cmd/tmcllog
+//
+// The TMCL type in this package is the core combining them and remains the main entry point.
+// Types moved to a subpackage are still available here under their old names. The command
+// cmd/tmclctl controls modules from the command line.
+package tmcl
@@ -0,0 +1,126 @@
+package tmcl
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/raceresult/go-tmcl/program"
+)
+
+// Instruction is one TMCL command of a standalone program
+type Instruction = program.Instruction
+
+// EnterDownloadMode stops the standalone application and stores all following commands in the
+// program memory, starting at the given address, until ExitDownloadMode is called
+func (q *TMCL) EnterDownloadMode(address int) error {
+	_, err := q.Exec(132, 0, 0, address)
+	return err
+}
+
+// ExitDownloadMode ends the download mode, commands are executed again
+func (q *TMCL) ExitDownloadMode() error {
+	_, err := q.Exec(133, 0, 0, 0)
+	return err
+}
+
+// ReadProgramMemory returns the instruction stored at an address of the program memory
+func (q *TMCL) ReadProgramMemory(address int) (Instruction, error) {
+	if err := q.checkUsable(); err != nil {
+		return Instruction{}, err
+	}
+	var in Instruction
+	var err error
+	q.do(globalKey, func() {
+		if err = q.checkCommandFilter(134, 0); err != nil {
+			return
+		}
+		if err = q.OpenPort(); err != nil {
+			return
+		}
+		in, err = q.readProgramMemory(address)
+	})
+	return in, err
+}
+
+// readProgramMemory reads an instruction from the program memory, must be called with the
+// command lock held. Like the version string, the reply is no regular telegram: host address,
+// command, type, motor/bank, the value and an unused byte, without checksum.
+func (q *TMCL) readProgramMemory(address int) (Instruction, error) {
+	if err := q.transactSpecial(Request{Cmd: 134, Value: address}); err != nil {
+		return Instruction{}, err
+	}
+	return Instruction{
+		Cmd:       q.rx[1],
+		Type:      q.rx[2],
+		MotorBank: q.rx[3],
+		Value:     int(int32(binary.BigEndian.Uint32(q.rx[4:8]))),
+	}, nil
+}
+
+// DownloadProgram stores a standalone program in the program memory, starting at address 0.
+// No other command is sent in between, and every instruction must be acknowledged as loaded.
+// Afterwards the instructions are read back and compared. Limits, interlocks and the current
+// ceiling are not applied to the instructions, as they are not executed now.
+func (q *TMCL) DownloadProgram(program []Instruction, progress ProgressFunc) error {
+	if err := q.checkUsable(); err != nil {
+		return err
+	}
+	var err error
+	q.do(globalKey, func() {
+		err = q.downloadProgram(program, progress)
+	})
+	return err
+}
+
+// downloadProgram implements DownloadProgram, must be called with the command lock held
+func (q *TMCL) downloadProgram(program []Instruction, progress ProgressFunc) error {
+	if err := q.checkCommandFilter(132, 0); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if _, err := q.transact(ctx, Request{Cmd: 132}); err != nil {
+		return errors.Wrap(err, "enter download mode")
+	}
+
+	frames := make([]byte, len(program)*frameSize)
+	for i, in := range program {
+		in.Encode(frames[i*frameSize:(i+1)*frameSize], q.address)
+	}
+	values := make([]int, len(program))
+	statuses := make([]byte, len(program))
+	err := q.bulk(frames, values, statuses, progress)
+	if _, exitErr := q.transact(ctx, Request{Cmd: 133}); err == nil && exitErr != nil {
+		err = errors.Wrap(exitErr, "exit download mode")
+	}
+	if err != nil {
+		return err
+	}
+	for i, status := range statuses {
+		if status == StatusLoaded {
+			continue
+		}
+		req := instructionRequest(program[i])
+		if err := q.statusError(status, req); err != nil {
+			return errors.Wrapf(err, "instruction %d", i)
+		}
+		return errors.Errorf("instruction %d: %s was executed instead of loaded", i, req)
+	}
+
+	// verify
+	for i, in := range program {
+		stored, err := q.readProgramMemory(i)
+		if err != nil {
+			return errors.Wrapf(err, "verify instruction %d", i)
+		}
+		if stored != in {
+			return errors.Errorf("verify instruction %d: %s stored instead of %s", i, instructionRequest(stored), instructionRequest(in))
+		}
+	}
+	return nil
+}
+
+// instructionRequest returns the request executing an instruction
+func instructionRequest(in Instruction) Request {
+	return Request{Cmd: in.Cmd, Type: in.Type, MotorBank: in.MotorBank, Value: in.Value}
+}
@@ -0,0 +1,128 @@
+package tmcl
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProgressFunc is called during bulk operations with the number of finished and total requests
+type ProgressFunc func(done, total int)
+
+// SetPipelineDepth sets the maximum number of requests that are sent to the board before
+// the reply of the first one was received. The default 1 waits for every reply before
+// sending the next request, which is what the firmware manual demands. Higher values
+// only work with modules and interfaces that buffer incoming telegrams.
+func (q *TMCL) SetPipelineDepth(depth int) {
+	if depth < 1 {
+		depth = 1
+	}
+	q.do(globalKey, func() {
+		q.pipelineDepth = depth
+	})
+}
+
+// DumpAxisParams reads the given axis parameters of one motor in one bulk operation.
+// Parameters not supported by the board are left out of the result.
+func (q *TMCL) DumpAxisParams(motor byte, indices []byte, progress ProgressFunc) (map[byte]int, error) {
+	return q.dumpParams(6, motor, indices, progress)
+}
+
+// DumpGlobalParams reads the given global parameters of one bank in one bulk operation.
+// Parameters not supported by the board are left out of the result.
+func (q *TMCL) DumpGlobalParams(bank byte, indices []byte, progress ProgressFunc) (map[byte]int, error) {
+	return q.dumpParams(10, bank, indices, progress)
+}
+
+// dumpParams reads parameters using GAP or GGP
+func (q *TMCL) dumpParams(cmd byte, motorOrBank byte, indices []byte, progress ProgressFunc) (map[byte]int, error) {
+	// precompute all request frames
+	frames := make([]byte, len(indices)*frameSize)
+	for i, index := range indices {
+		q.encodeFrame(frames[i*frameSize:(i+1)*frameSize], cmd, index, motorOrBank, 0)
+	}
+
+	key := globalKey
+	if cmd == 6 {
+		key = int(motorOrBank)
+	}
+	values := make([]int, len(indices))
+	statuses := make([]byte, len(indices))
+	if err := q.execBulk(key, frames, values, statuses, progress); err != nil {
+		return nil, err
+	}
+
+	m := make(map[byte]int, len(indices))
+	for i, index := range indices {
+		if statusSuccess(statuses[i]) {
+			m[index] = values[i]
+		}
+	}
+	return m, nil
+}
+
+// execBulk sends the precomputed frames and collects the replies into values and statuses,
+// keeping up to pipelineDepth requests in flight
+func (q *TMCL) execBulk(key int, frames []byte, values []int, statuses []byte, progress ProgressFunc) error {
+	if err := q.checkUsable(); err != nil {
+		return err
+	}
+	var err error
+	if runErr := q.run(context.Background(), key, func() { err = q.bulk(frames, values, statuses, progress) }); runErr != nil {
+		return runErr
+	}
+	return err
+}
+
+// bulk is execBulk with the command lock already held
+func (q *TMCL) bulk(frames []byte, values []int, statuses []byte, progress ProgressFunc) error {
+	// open port if not done yet
+	if err := q.OpenPort(); err != nil {
+		return err
+	}
+
+	total := len(frames) / frameSize
+	for i := 0; i < total; i++ {
+		if err := q.checkCommandFilter(frames[i*frameSize+1], frames[i*frameSize+2]); err != nil {
+			return err
+		}
+	}
+
+	if err := q.flushInput(); err != nil {
+		return err
+	}
+	sentAt := make([]time.Time, q.pipelineDepth)
+	var sent, received int
+	for received < total {
+		// fill pipeline
+		for sent < total && sent-received < q.pipelineDepth {
+			sentAt[sent%q.pipelineDepth] = time.Now()
+			if err := q.writeFrame(frames[sent*frameSize : (sent+1)*frameSize]); err != nil {
+				return err
+			}
+			sent++
+		}
+
+		// read next reply
+		frame := frames[received*frameSize : (received+1)*frameSize]
+		sentTime := sentAt[received%q.pipelineDepth]
+		value, status, err := q.readReply(context.Background(), frame[1], sentTime, q.currentTimeout())
+		q.logFrame(frame, value, status, err, sentTime)
+		if err != nil {
+			return errors.Wrapf(err, "request %d of %d", received+1, total)
+		}
+		if !statusSuccess(status) {
+			atomic.AddUint64(&q.stats.boardErrors, 1)
+		}
+		values[received] = value
+		statuses[received] = status
+		received++
+
+		if progress != nil {
+			q.lockedCallback(func() { progress(received, total) })
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package tmcl_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/protocol"
+	"github.com/raceresult/go-tmcl/tmcltest"
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// pipeConn is a connection to a simulated module keeping track of the requests sent before
+// their replies were read
+type pipeConn struct {
+	transport.Transport
+
+	mutex         sync.Mutex
+	written, read int
+	maxOut        int
+}
+
+// Write counts the requests
+func (c *pipeConn) Write(b []byte) (int, error) {
+	c.mutex.Lock()
+	c.written += len(b)
+	out := c.written/protocol.FrameSize - c.read/protocol.FrameSize
+	if out > c.maxOut {
+		c.maxOut = out
+	}
+	c.mutex.Unlock()
+	return c.Transport.Write(b)
+}
+
+// Read counts the replies
+func (c *pipeConn) Read(b []byte) (int, error) {
+	n, err := c.Transport.Read(b)
+	c.mutex.Lock()
+	c.read += n
+	c.mutex.Unlock()
+	return n, err
+}
+
+func TestPipelining(t *testing.T) {
+	params := []tmcl.AxisParam{
+		tmcl.TargetPosition, tmcl.ActualPosition, tmcl.MaxSpeed, tmcl.MaxAcceleration,
+		tmcl.RunCurrent, tmcl.StandbyCurrent, tmcl.RampMode, tmcl.MinSpeed,
+		tmcl.MicrostepResolution, tmcl.RampDivisor, tmcl.PulseDivisor, tmcl.FreewheelingDelay,
+	}
+	tests := []struct {
+		depth   int
+		maxSent int
+	}{
+		{depth: 0, maxSent: 1},
+		{depth: 1, maxSent: 1},
+		{depth: 2, maxSent: 2},
+		{depth: 5, maxSent: 5},
+		{depth: 64, maxSent: len(params)},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("depth %d", tt.depth), func(t *testing.T) {
+			m := tmcltest.NewModule()
+			for i, p := range params {
+				m.SetAxisParam(1, byte(p), i+1)
+			}
+			conn := &pipeConn{Transport: m.Conn()}
+			q := tmcl.NewWithPort(conn)
+			if _, err := q.GetFirmwareVersion(); err != nil {
+				t.Fatal(err)
+			}
+			q.SetPipelineDepth(tt.depth)
+
+			values, err := q.GetAxisParams(1, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, p := range params {
+				if values[p] != i+1 {
+					t.Errorf("%s is %d instead of %d", p, values[p], i+1)
+				}
+			}
+			if conn.maxOut != tt.maxSent {
+				t.Errorf("depth %d: %d requests sent ahead, want %d", tt.depth, conn.maxOut, tt.maxSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package tmcl
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// default retry settings for commands writing to the EEPROM
+const (
+	defaultStoreRetries    = 3
+	defaultStoreRetryDelay = 20 * time.Millisecond
+)
+
+// SetStoreRetry sets how often and after which delay commands writing to the EEPROM (STAP,
+// STGP and SGP of bank 0 parameters 64 and above) are repeated after a transient error.
+// Such errors occur when many store commands are issued in quick succession. 0 retries
+// disables repeating.
+func (q *TMCL) SetStoreRetry(retries int, delay time.Duration) {
+	q.do(globalKey, func() {
+		q.storeRetries = retries
+		q.storeRetryDelay = delay
+	})
+}
+
+// isStoreCommand returns true for commands which write to the EEPROM
+func isStoreCommand(cmd byte, typeNo byte, motorOrBank byte) bool {
+	switch cmd {
+	case 7, 11:
+		return true
+	case 9, 35:
+		return motorOrBank == 0 && typeNo >= 64 && typeNo < 128
+	}
+	return false
+}
+
+// isTransient returns true for errors that may disappear when the command is repeated
+func isTransient(err error) bool {
+	switch cause := errors.Cause(err).(type) {
+	case *Error:
+		// the documented codes are permanent
+		_, documented := statusTexts(cause.Code)
+		return !documented
+	default:
+		return cause == ErrTimeout || cause == ErrChecksum
+	}
+}
+
+// transactStore sends a command writing to the EEPROM, repeating it after transient errors,
+// must be called with the command lock held
+func (q *TMCL) transactStore(ctx context.Context, req Request) (int, error) {
+	for i := 0; ; i++ {
+		v, err := q.transact(ctx, req)
+		if err == nil || i >= q.storeRetries || !isTransient(err) {
+			return v, err
+		}
+		q.logRetry(req, i+1, err)
+		time.Sleep(q.storeRetryDelay)
+	}
+}
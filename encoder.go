@@ -0,0 +1,189 @@
+package tmcl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrEncoderDeviation is wrapped by DeviationError
+var ErrEncoderDeviation = errors.New("encoder deviation exceeded")
+
+// ReadEncoder reads the encoder position of a motor, scaled to microsteps by the encoder
+// prescaler
+func (q *TMCL) ReadEncoder(motor byte) (int, error) {
+	return q.GAP(byte(EncoderPosition), motor)
+}
+
+// SetEncoderPosition sets the encoder position of a motor, e.g. after homing
+func (q *TMCL) SetEncoderPosition(motor byte, position int) error {
+	return q.SAP(byte(EncoderPosition), motor, position)
+}
+
+// ClearEncoder sets the encoder position of a motor to 0
+func (q *TMCL) ClearEncoder(motor byte) error {
+	return q.SetEncoderPosition(motor, 0)
+}
+
+// SyncEncoder sets the encoder position of a motor to its actual position, so that the
+// deviation starts from 0
+func (q *TMCL) SyncEncoder(motor byte) error {
+	p, err := q.GAP(byte(ActualPosition), motor)
+	if err != nil {
+		return err
+	}
+	return q.SetEncoderPosition(motor, p)
+}
+
+// SetMaxEncoderDeviation sets the deviation in microsteps between actual and encoder position
+// at which the module stops the motor and sets the encoder deviation error flag, 0 disabling
+// the check
+func (q *TMCL) SetMaxEncoderDeviation(motor byte, steps int) error {
+	if steps < 0 {
+		return errors.Wrapf(ErrParamRange, "maximum encoder deviation %d is negative", steps)
+	}
+	return q.SAP(byte(MaxEncoderDeviation), motor, steps)
+}
+
+// ReadMaxEncoderDeviation reads the deviation at which the module stops the motor
+func (q *TMCL) ReadMaxEncoderDeviation(motor byte) (int, error) {
+	return q.GAP(byte(MaxEncoderDeviation), motor)
+}
+
+// EncoderDeviation reads actual and encoder position of a motor in one cycle and returns
+// their difference
+func (q *TMCL) EncoderDeviation(motor byte) (int, error) {
+	key, frames := q.encodePollParams(deviationParams(motor))
+	values := make([]int, 2)
+	if err := q.pollBulk(key, frames, values, make([]byte, 2)); err != nil {
+		return 0, err
+	}
+	return values[0] - values[1], nil
+}
+
+// deviationParams are the parameters polled for the deviation of a motor
+func deviationParams(motor byte) []PollParam {
+	return []PollParam{AxisPoll(ActualPosition, motor), AxisPoll(EncoderPosition, motor)}
+}
+
+// DeviationEvent reports that actual and encoder position of a motor differ more than the
+// threshold of a DeviationMonitor, usually because the motor lost steps
+type DeviationEvent struct {
+	Motor     byte
+	Time      time.Time
+	Actual    int
+	Encoder   int
+	Deviation int
+}
+
+// DeviationError is returned by DeviationMonitor.Run without callbacks
+type DeviationError struct {
+	DeviationEvent
+}
+
+// Error implements the error interface
+func (e *DeviationError) Error() string {
+	return fmt.Sprintf("motor %d: %v by %d microsteps", e.Motor, ErrEncoderDeviation, e.Deviation)
+}
+
+// Unwrap returns ErrEncoderDeviation
+func (e *DeviationError) Unwrap() error {
+	return ErrEncoderDeviation
+}
+
+// DeviationMonitor polls actual and encoder position of motors and reports deviations above
+// a threshold. Unlike the maximum encoder deviation of the module it leaves the motors
+// running, e.g. to log lost steps or to correct the position.
+type DeviationMonitor struct {
+	monitor   *Monitor
+	motors    []byte
+	threshold int
+
+	mutex     sync.Mutex
+	callbacks []func(DeviationEvent)
+	exceeded  map[byte]bool
+}
+
+// NewDeviationMonitor creates a monitor polling the motors every interval
+func (q *TMCL) NewDeviationMonitor(motors []byte, threshold int, interval time.Duration) *DeviationMonitor {
+	var params []PollParam
+	for _, motor := range motors {
+		params = append(params, deviationParams(motor)...)
+	}
+	return &DeviationMonitor{
+		monitor:   q.NewMonitor(params, interval),
+		motors:    append([]byte(nil), motors...),
+		threshold: threshold,
+		exceeded:  map[byte]bool{},
+	}
+}
+
+// OnDeviation registers a callback called from Run when the deviation of a motor exceeds the
+// threshold. It is called again only after the deviation was back within the threshold.
+func (m *DeviationMonitor) OnDeviation(fn func(DeviationEvent)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.callbacks = append(m.callbacks, fn)
+}
+
+// Run polls until the context is done. Without callbacks it returns a *DeviationError as soon
+// as a deviation exceeds the threshold. Communication errors are skipped.
+func (m *DeviationMonitor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m.mutex.Lock()
+	m.exceeded = map[byte]bool{}
+	m.mutex.Unlock()
+
+	var res error
+	remove := m.monitor.OnSample(func(s Sample) {
+		if s.Err != nil {
+			return
+		}
+		for i, motor := range m.motors {
+			ev := DeviationEvent{Motor: motor, Time: s.Time, Actual: s.Values[2*i], Encoder: s.Values[2*i+1]}
+			ev.Deviation = ev.Actual - ev.Encoder
+			if callbacks, ok := m.check(ev); ok {
+				if len(callbacks) == 0 {
+					res = &DeviationError{ev}
+					cancel()
+					return
+				}
+				for _, fn := range callbacks {
+					fn(ev)
+				}
+			}
+		}
+	})
+	defer remove()
+
+	err := m.monitor.Run(ctx)
+	if res != nil {
+		return res
+	}
+	return err
+}
+
+// check returns true with the callbacks if the event starts a deviation above the threshold
+func (m *DeviationMonitor) check(ev DeviationEvent) ([]func(DeviationEvent), bool) {
+	d := ev.Deviation
+	if d < 0 {
+		d = -d
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if d <= m.threshold {
+		delete(m.exceeded, ev.Motor)
+		return nil, false
+	}
+	if m.exceeded[ev.Motor] {
+		return nil, false
+	}
+	m.exceeded[ev.Motor] = true
+	callbacks := make([]func(DeviationEvent), len(m.callbacks))
+	copy(callbacks, m.callbacks)
+	return callbacks, true
+}
@@ -0,0 +1,107 @@
+package tmcl
+
+import (
+	"strings"
+
+	"github.com/raceresult/go-tmcl/program"
+)
+
+// ErrorFlag selects the error flags cleared by CLE
+type ErrorFlag = program.ErrorFlag
+
+// error flags of CLE
+const (
+	ErrorFlagAll       = program.ErrorFlagAll
+	ErrorFlagTimeout   = program.ErrorFlagTimeout
+	ErrorFlagAlarm     = program.ErrorFlagAlarm
+	ErrorFlagDeviation = program.ErrorFlagDeviation
+	ErrorFlagPosition  = program.ErrorFlagPosition
+	ErrorFlagShutdown  = program.ErrorFlagShutdown
+)
+
+// CLE is clear error flags. The manual restricts it to standalone applications, it is only
+// provided for completeness and may be rejected in direct mode.
+func (q *TMCL) CLE(flag ErrorFlag) error {
+	_, err := q.Exec(36, byte(flag), 0, 0)
+	return err
+}
+
+// DriverErrors are the decoded error flags of a motor driver (axis parameters 207 and 208)
+type DriverErrors struct {
+	OvercurrentLowSideA bool
+	OvercurrentLowSideB bool
+	OpenLoadA           bool
+	OpenLoadB           bool
+	OvercurrentHighSide bool
+	Undervoltage        bool
+	TemperatureWarning  bool
+	Overtemperature     bool
+
+	// EncoderDeviation and Stalled report why the motor was stopped, they are cleared by the
+	// next motion command
+	EncoderDeviation bool
+	Stalled          bool
+}
+
+// DecodeDriverErrors decodes axis parameters 208 (driver error flags) and 207 (extended error
+// flags), e.g. when they are polled together with other parameters
+func DecodeDriverErrors(driver uint32, extended uint32) DriverErrors {
+	return DriverErrors{
+		OvercurrentLowSideA: driver&(1<<0) != 0,
+		OvercurrentLowSideB: driver&(1<<1) != 0,
+		OpenLoadA:           driver&(1<<2) != 0,
+		OpenLoadB:           driver&(1<<3) != 0,
+		OvercurrentHighSide: driver&(1<<4) != 0,
+		Undervoltage:        driver&(1<<5) != 0,
+		TemperatureWarning:  driver&(1<<6) != 0,
+		Overtemperature:     driver&(1<<7) != 0,
+		EncoderDeviation:    extended&(1<<0) != 0,
+		Stalled:             extended&(1<<1) != 0,
+	}
+}
+
+// Any returns true if at least one flag is set
+func (e DriverErrors) Any() bool {
+	return e != DriverErrors{}
+}
+
+// String returns the names of the flags set, separated by commas
+func (e DriverErrors) String() string {
+	var s []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{e.OvercurrentLowSideA, "overcurrent low side A"},
+		{e.OvercurrentLowSideB, "overcurrent low side B"},
+		{e.OpenLoadA, "open load A"},
+		{e.OpenLoadB, "open load B"},
+		{e.OvercurrentHighSide, "overcurrent high side"},
+		{e.Undervoltage, "undervoltage"},
+		{e.TemperatureWarning, "temperature warning"},
+		{e.Overtemperature, "overtemperature"},
+		{e.EncoderDeviation, "encoder deviation"},
+		{e.Stalled, "stalled"},
+	} {
+		if f.set {
+			s = append(s, f.name)
+		}
+	}
+	if len(s) == 0 {
+		return "none"
+	}
+	return strings.Join(s, ", ")
+}
+
+// DriverErrorFlags reads and decodes the error flags of a motor driver
+func (q *TMCL) DriverErrorFlags(motor byte) (DriverErrors, error) {
+	driver, err := q.GAPUnsigned(208, motor)
+	if err != nil {
+		return DriverErrors{}, err
+	}
+	extended, err := q.GAPUnsigned(207, motor)
+	if err != nil {
+		return DriverErrors{}, err
+	}
+	return DecodeDriverErrors(driver, extended), nil
+}
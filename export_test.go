@@ -0,0 +1,7 @@
+package tmcl
+
+// QueuedCommands returns the number of commands pushed onto the channel of the writer and
+// not taken by it yet
+func QueuedCommands(q *TMCL) int {
+	return len(q.cmdLock.jobs)
+}
@@ -0,0 +1,75 @@
+package tmcl
+
+import (
+	"context"
+)
+
+// FailSafe defines the safe state applied when the connection is closed, a watched context
+// is cancelled or the application panics
+type FailSafe struct {
+	// Motors are stopped
+	Motors []byte
+
+	// Outputs are set after the motors were stopped
+	Outputs []OutputState
+}
+
+// SetFailSafe sets the safe state applied by ClosePort, WatchContext and RecoverFailSafe
+func (q *TMCL) SetFailSafe(fs FailSafe) {
+	q.do(globalKey, func() {
+		q.failSafe = &fs
+	})
+}
+
+// ApplyFailSafe stops the motors and sets the outputs of the configured safe state
+func (q *TMCL) ApplyFailSafe() error {
+	var fs *FailSafe
+	q.do(globalKey, func() {
+		fs = q.failSafe
+	})
+
+	if fs == nil {
+		return nil
+	}
+	return q.applySafeState(fs.Motors, fs.Outputs)
+}
+
+// WatchContext applies the safe state as soon as the context is cancelled
+func (q *TMCL) WatchContext(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		_ = q.ApplyFailSafe()
+	}()
+}
+
+// WithShutdownContext makes the TMCL object stop a running standalone application and all
+// motors and then apply the fail-safe state as soon as the context is cancelled, e.g. by
+// signal.NotifyContext on SIGTERM
+func WithShutdownContext(ctx context.Context) Option {
+	return func(q *TMCL) {
+		q.shutdownCtx = ctx
+	}
+}
+
+// watchShutdown starts watching the context given by WithShutdownContext, called by the
+// constructors once all options were applied
+func (q *TMCL) watchShutdown() {
+	ctx := q.shutdownCtx
+	if ctx == nil {
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		_ = q.shutdown()
+		_ = q.ApplyFailSafe()
+	}()
+}
+
+// RecoverFailSafe applies the safe state if the application panics and then continues
+// panicking. It must be deferred directly: defer q.RecoverFailSafe()
+func (q *TMCL) RecoverFailSafe() {
+	if r := recover(); r != nil {
+		_ = q.ApplyFailSafe()
+		panic(r)
+	}
+}
@@ -0,0 +1,59 @@
+package tmcl
+
+import (
+	"github.com/pkg/errors"
+)
+
+// SetReadOnly restricts the connection to commands which only read from the board
+// (GAP, GGP, GIO, GCO, RFS status, application status, program memory and firmware version),
+// so that monitoring services cannot command any motion
+func (q *TMCL) SetReadOnly(readOnly bool) {
+	if readOnly {
+		q.setCommandFilter(isReadCommand)
+	} else {
+		q.setCommandFilter(nil)
+	}
+}
+
+// SetAllowedCommands restricts the connection to the given command numbers, no commands
+// allowing everything again
+func (q *TMCL) SetAllowedCommands(cmds ...byte) {
+	if len(cmds) == 0 {
+		q.setCommandFilter(nil)
+		return
+	}
+	var allowed [256]bool
+	for _, cmd := range cmds {
+		allowed[cmd] = true
+	}
+	q.setCommandFilter(func(cmd byte, typeNo byte) bool {
+		return allowed[cmd]
+	})
+}
+
+// setCommandFilter sets the function deciding which commands may be sent
+func (q *TMCL) setCommandFilter(f func(cmd byte, typeNo byte) bool) {
+	q.do(globalKey, func() {
+		q.commandFilter = f
+	})
+}
+
+// checkCommandFilter returns an error if the command is not permitted, must be called with
+// the command lock held
+func (q *TMCL) checkCommandFilter(cmd byte, typeNo byte) error {
+	if q.commandFilter == nil || q.commandFilter(cmd, typeNo) {
+		return nil
+	}
+	return errors.Errorf("command %s not permitted on this connection", Opcode(cmd))
+}
+
+// isReadCommand returns true for commands which do not change the state of the board
+func isReadCommand(cmd byte, typeNo byte) bool {
+	switch cmd {
+	case 6, 10, 15, 31, 134, 135, 136:
+		return true
+	case 13:
+		return typeNo == 2
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package tmcl
+
+import "sync/atomic"
+
+// FlushPolicy defines when data received but not read yet is discarded before a request is sent
+type FlushPolicy int
+
+const (
+	// FlushAfterError flushes before the first request after a read failed, e.g. timed out,
+	// so that a late reply is not taken for the reply to the next request
+	FlushAfterError FlushPolicy = iota
+
+	// FlushNever never flushes, stale replies are still dropped by their command echo
+	FlushNever
+
+	// FlushAlways flushes before every request, which adds latency on network links
+	FlushAlways
+)
+
+// flusher is implemented by transports that can discard received data, e.g. transport.Transport
+type flusher interface {
+	Flush() error
+}
+
+// SetFlushPolicy sets when the input buffer of the port is flushed before sending a request
+// (default FlushAfterError). Ports that cannot be flushed are never flushed.
+func (q *TMCL) SetFlushPolicy(p FlushPolicy) {
+	q.do(globalKey, func() {
+		q.flushPolicy = p
+	})
+}
+
+// flushInput discards stale data of the port according to the flush policy, must be called
+// with the command lock held and no request in flight
+func (q *TMCL) flushInput() error {
+	switch q.flushPolicy {
+	case FlushNever:
+		return nil
+	case FlushAfterError:
+		if !q.desynced {
+			return nil
+		}
+	}
+	f, ok := q.port.(flusher)
+	if !ok {
+		return nil
+	}
+	if err := f.Flush(); err != nil {
+		return err
+	}
+	q.desynced = false
+	atomic.AddUint64(&q.stats.flushes, 1)
+	return nil
+}
@@ -0,0 +1,214 @@
+package tmcl
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// global parameters of bank 0 (module settings), all of them are stored in the EEPROM
+const (
+	globalBaudRate          = 65
+	globalSerialAddress     = 66
+	globalSerialHeartbeat   = 68
+	globalCANBitRate        = 69
+	globalCANReplyID        = 70
+	globalCANID             = 71
+	globalEEPROMLock        = 73
+	globalTelegramPause     = 75
+	globalHostAddress       = 76
+	globalAutoStart         = 77
+	globalEndSwitchPolarity = 79
+	globalCoordinateStorage = 84
+)
+
+// values of global parameter 73 locking and unlocking the configuration EEPROM
+const (
+	eepromLock   = 1234
+	eepromUnlock = 4321
+)
+
+// baudRates are the RS232/RS485 baud rates by their code in global parameter 65
+var baudRates = []int{9600, 14400, 19200, 28800, 38400, 57600, 76800, 115200, 230400, 250000, 500000, 1000000}
+
+// canBitRates are the CAN bit rates in kBit/s by their code in global parameter 69
+var canBitRates = map[int]int{2: 20, 3: 50, 4: 100, 5: 125, 6: 250, 7: 500, 8: 1000}
+
+// baudRateCode returns the code of global parameter 65 for a baud rate
+func baudRateCode(baud int) (int, error) {
+	for code, b := range baudRates {
+		if b == baud {
+			return code, nil
+		}
+	}
+	return 0, errors.Errorf("baud rate %d not supported", baud)
+}
+
+// SerialBaudRate returns the RS232/RS485 baud rate
+func (q *TMCL) SerialBaudRate() (int, error) {
+	code, err := q.GGP(globalBaudRate, 0)
+	if err != nil {
+		return 0, err
+	}
+	if code < 0 || code >= len(baudRates) {
+		return 0, errors.Errorf("unknown baud rate code %d", code)
+	}
+	return baudRates[code], nil
+}
+
+// SetSerialBaudRate sets the RS232/RS485 baud rate, which takes effect after the next power up
+func (q *TMCL) SetSerialBaudRate(baud int) error {
+	code, err := baudRateCode(baud)
+	if err != nil {
+		return err
+	}
+	return q.SGP(globalBaudRate, 0, code)
+}
+
+// SerialAddress returns the RS232/RS485 address of the module
+func (q *TMCL) SerialAddress() (byte, error) {
+	v, err := q.GGP(globalSerialAddress, 0)
+	return byte(v), err
+}
+
+// SetSerialAddress sets the RS232/RS485 address of the module. The module replies from the
+// new address right away, so all further commands are sent to it; ChangeModuleAddress also
+// verifies that the module answers there.
+func (q *TMCL) SetSerialAddress(addr byte) error {
+	return q.setModuleAddress(addr)
+}
+
+// HostAddress returns the address the module sends its replies to
+func (q *TMCL) HostAddress() (byte, error) {
+	v, err := q.GGP(globalHostAddress, 0)
+	return byte(v), err
+}
+
+// SetHostAddress sets the address the module sends its replies to
+func (q *TMCL) SetHostAddress(addr byte) error {
+	return q.SGP(globalHostAddress, 0, int(addr))
+}
+
+// TelegramPauseTime returns the pause before a reply is sent
+func (q *TMCL) TelegramPauseTime() (int, error) {
+	return q.GGP(globalTelegramPause, 0)
+}
+
+// SetTelegramPauseTime sets the pause before a reply is sent (0 to 255). RS485 adapters
+// switching direction by the RTS pin often need 15.
+func (q *TMCL) SetTelegramPauseTime(pause int) error {
+	return q.SGP(globalTelegramPause, 0, pause)
+}
+
+// SerialHeartbeat returns the time after which the motors are stopped if no command was
+// received via RS232/RS485, 0 if disabled
+func (q *TMCL) SerialHeartbeat() (time.Duration, error) {
+	v, err := q.GGP(globalSerialHeartbeat, 0)
+	return time.Duration(v) * time.Millisecond, err
+}
+
+// SetSerialHeartbeat sets the time after which the motors are stopped if no command was
+// received via RS232/RS485, 0 disables it
+func (q *TMCL) SetSerialHeartbeat(d time.Duration) error {
+	return q.SGP(globalSerialHeartbeat, 0, int(d/time.Millisecond))
+}
+
+// AutoStart returns true if the TMCL application is started after power up
+func (q *TMCL) AutoStart() (bool, error) {
+	v, err := q.GGP(globalAutoStart, 0)
+	return v != 0, err
+}
+
+// SetAutoStart sets whether the TMCL application is started after power up
+func (q *TMCL) SetAutoStart(on bool) error {
+	return q.SGP(globalAutoStart, 0, boolValue(on))
+}
+
+// EEPROMLocked returns true if the configuration EEPROM is locked
+func (q *TMCL) EEPROMLocked() (bool, error) {
+	v, err := q.GGP(globalEEPROMLock, 0)
+	return v != 0, err
+}
+
+// LockEEPROM locks the configuration EEPROM, afterwards parameters cannot be stored anymore
+func (q *TMCL) LockEEPROM() error {
+	return q.SGP(globalEEPROMLock, 0, eepromLock)
+}
+
+// UnlockEEPROM unlocks the configuration EEPROM
+func (q *TMCL) UnlockEEPROM() error {
+	return q.SGP(globalEEPROMLock, 0, eepromUnlock)
+}
+
+// CANBitRate returns the CAN bit rate in kBit/s
+func (q *TMCL) CANBitRate() (int, error) {
+	code, err := q.GGP(globalCANBitRate, 0)
+	if err != nil {
+		return 0, err
+	}
+	rate, ok := canBitRates[code]
+	if !ok {
+		return 0, errors.Errorf("unknown CAN bit rate code %d", code)
+	}
+	return rate, nil
+}
+
+// SetCANBitRate sets the CAN bit rate in kBit/s
+func (q *TMCL) SetCANBitRate(kbit int) error {
+	for code, rate := range canBitRates {
+		if rate == kbit {
+			return q.SGP(globalCANBitRate, 0, code)
+		}
+	}
+	return errors.Errorf("CAN bit rate %d kBit/s not supported", kbit)
+}
+
+// CANID returns the CAN identifier of the module and the one of its replies
+func (q *TMCL) CANID() (id int, replyID int, err error) {
+	if id, err = q.GGP(globalCANID, 0); err != nil {
+		return 0, 0, err
+	}
+	replyID, err = q.GGP(globalCANReplyID, 0)
+	return id, replyID, err
+}
+
+// SetCANID sets the CAN identifier of the module and the one of its replies (0 to 0x7FF)
+func (q *TMCL) SetCANID(id int, replyID int) error {
+	if err := q.SGP(globalCANID, 0, id); err != nil {
+		return err
+	}
+	return q.SGP(globalCANReplyID, 0, replyID)
+}
+
+// EndSwitchPolarity returns true if the end switches are active low. Not all modules have
+// this setting.
+func (q *TMCL) EndSwitchPolarity() (bool, error) {
+	v, err := q.GGP(globalEndSwitchPolarity, 0)
+	return v != 0, err
+}
+
+// SetEndSwitchPolarity inverts the end switches, making them active low. Not all modules
+// have this setting.
+func (q *TMCL) SetEndSwitchPolarity(inverted bool) error {
+	return q.SGP(globalEndSwitchPolarity, 0, boolValue(inverted))
+}
+
+// CoordinatesInEEPROM returns true if coordinates are always stored in the EEPROM
+func (q *TMCL) CoordinatesInEEPROM() (bool, error) {
+	v, err := q.GGP(globalCoordinateStorage, 0)
+	return v != 0, err
+}
+
+// SetCoordinatesInEEPROM sets whether coordinates are always stored in the EEPROM or only in
+// RAM, where they can be copied with StoreCoordinates and LoadCoordinates
+func (q *TMCL) SetCoordinatesInEEPROM(on bool) error {
+	return q.SGP(globalCoordinateStorage, 0, boolValue(on))
+}
+
+// boolValue converts a flag to a parameter value
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
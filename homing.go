@@ -0,0 +1,267 @@
+package tmcl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrHomingTravel is returned if a homing routine moved farther than allowed without finding home
+	ErrHomingTravel = errors.New("maximum homing travel exceeded")
+
+	// ErrHomingTimeout is returned if the reference search did not finish in time
+	ErrHomingTimeout = errors.New("reference search timed out")
+
+	// ErrSwitchFailure is returned if the limit switches report an implausible state
+	ErrSwitchFailure = errors.New("both limit switches active")
+)
+
+// HomingError is returned by Home, Err being one of the ErrHoming/ErrSwitch errors or the
+// error of the failed command
+type HomingError struct {
+	Motor byte
+	Err   error
+}
+
+// Error implements the error interface
+func (e *HomingError) Error() string {
+	return fmt.Sprintf("homing motor %d: %v", e.Motor, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *HomingError) Unwrap() error {
+	return e.Err
+}
+
+// StallHomeConfig configures StallHome
+type StallHomeConfig struct {
+	// Velocity of the homing move, the sign gives the direction towards the end stop
+	Velocity int
+
+	// Current is the run current during the homing move, 0 keeps the configured current
+	Current int
+
+	// Threshold is the stallGuard threshold used during the homing move, axis parameter 174
+	// with stallGuard2 or 205 on the TMCM-351
+	Threshold int
+
+	// StallLoad is the load value (axis parameter 206) at or below which the motor is stalled
+	StallLoad int
+
+	// SettleTime is the time after starting the move during which the load value is ignored,
+	// as the load value is not meaningful while accelerating
+	SettleTime time.Duration
+
+	// MaxTravel is the maximum distance in microsteps to drive before giving up, 0 meaning unlimited
+	MaxTravel int
+
+	// Timeout limits the duration of the homing move, 0 only waits for the context
+	Timeout time.Duration
+
+	// PollInterval is the pause between two polls of the load value, 0 meaning 10ms
+	PollInterval time.Duration
+}
+
+// StallHome drives a motor towards the end stop until stallGuard detects a stall, stops the
+// motor and sets its position to 0. The motor is stopped and the run current restored
+// in any case.
+func (q *TMCL) StallHome(ctx context.Context, motor byte, c StallHomeConfig) (err error) {
+	if c.Velocity == 0 {
+		return errors.New("homing velocity is 0")
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	pollInterval := c.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultWaitPollInterval
+	}
+
+	// reduce current, restored when done
+	if c.Current > 0 {
+		runCurrent, err := q.GAPContext(ctx, byte(RunCurrent), motor)
+		if err != nil {
+			return err
+		}
+		if err := q.SAPContext(ctx, byte(RunCurrent), motor, c.Current); err != nil {
+			return err
+		}
+		defer func() {
+			if err2 := q.SAP(byte(RunCurrent), motor, runCurrent); err == nil {
+				err = err2
+			}
+		}()
+	}
+	threshold, err := q.stallThresholdParam(c.Threshold)
+	if err != nil {
+		return err
+	}
+	if err := q.SAPContext(ctx, byte(threshold), motor, c.Threshold); err != nil {
+		return err
+	}
+
+	start, err := q.GAPContext(ctx, byte(ActualPosition), motor)
+	if err != nil {
+		return err
+	}
+
+	// start the move, the motor is stopped on every way out
+	if c.Velocity > 0 {
+		err = q.RORContext(ctx, motor, c.Velocity)
+	} else {
+		err = q.ROLContext(ctx, motor, -c.Velocity)
+	}
+	if err != nil {
+		_ = q.MST(motor)
+		return err
+	}
+	startedAt := time.Now()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = q.MST(motor)
+			return errors.Wrap(ctx.Err(), "homing")
+		case <-ticker.C:
+		}
+
+		if c.MaxTravel > 0 {
+			pos, err := q.GAPContext(ctx, byte(ActualPosition), motor)
+			if err != nil {
+				_ = q.MST(motor)
+				return err
+			}
+			if pos-start > c.MaxTravel || start-pos > c.MaxTravel {
+				_ = q.MST(motor)
+				return ErrHomingTravel
+			}
+		}
+		if time.Since(startedAt) < c.SettleTime {
+			continue
+		}
+		load, err := q.GAPContext(ctx, byte(ActualLoad), motor)
+		if err != nil {
+			_ = q.MST(motor)
+			return err
+		}
+		if load <= c.StallLoad {
+			break
+		}
+	}
+
+	// stop and zero the position
+	if err := q.MSTContext(ctx, motor); err != nil {
+		_ = q.MST(motor)
+		return err
+	}
+	return q.zeroPosition(ctx, motor)
+}
+
+// zeroPosition sets the actual and target position of a stopped motor to 0
+func (q *TMCL) zeroPosition(ctx context.Context, motor byte) error {
+	if err := q.SAPContext(ctx, byte(TargetPosition), motor, 0); err != nil {
+		return err
+	}
+	return q.SAPContext(ctx, byte(ActualPosition), motor, 0)
+}
+
+// HomeConfig configures Home
+type HomeConfig struct {
+	// Offset is a relative move in microsteps after the reference search, e.g. to clear the switch
+	Offset int
+
+	// Timeout limits the duration of the reference search and offset move, 0 only waits for the context
+	Timeout time.Duration
+
+	// PollInterval is the pause between two polls of the reference search status, 0 meaning 10ms
+	PollInterval time.Duration
+}
+
+// Home runs the reference search of a motor as configured in its axis parameters 193 to 196,
+// waits until it finished, moves by the offset and sets the position to 0. Errors are returned
+// as *HomingError.
+func (q *TMCL) Home(ctx context.Context, motor byte, c HomeConfig) error {
+	if err := q.home(ctx, motor, c); err != nil {
+		return &HomingError{Motor: motor, Err: err}
+	}
+	return nil
+}
+
+// home implements Home
+func (q *TMCL) home(ctx context.Context, motor byte, c HomeConfig) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	pollInterval := c.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultWaitPollInterval
+	}
+
+	// a broken or mis-wired switch often shows as both switches being active
+	right, err := q.GAPContext(ctx, byte(RightLimitSwitch), motor)
+	if err != nil {
+		return err
+	}
+	left, err := q.GAPContext(ctx, byte(LeftLimitSwitch), motor)
+	if err != nil {
+		return err
+	}
+	if right != 0 && left != 0 {
+		return ErrSwitchFailure
+	}
+
+	if err := q.RFSStartContext(ctx, motor); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = q.RFSStop(motor)
+			if ctx.Err() == context.DeadlineExceeded {
+				return ErrHomingTimeout
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		running, err := q.RFSStatusContext(ctx, motor)
+		if err != nil {
+			_ = q.RFSStop(motor)
+			return err
+		}
+		if !running {
+			break
+		}
+	}
+
+	if c.Offset != 0 {
+		if err := q.MVPContext(ctx, REL, motor, c.Offset); err != nil {
+			return err
+		}
+		if err := q.WaitPositionReached(ctx, motor, pollInterval); err != nil {
+			_ = q.MST(motor)
+			if ctx.Err() == context.DeadlineExceeded {
+				return ErrHomingTimeout
+			}
+			return err
+		}
+	}
+
+	// stop and zero the position
+	if err := q.MSTContext(ctx, motor); err != nil {
+		_ = q.MST(motor)
+		return err
+	}
+	return q.zeroPosition(ctx, motor)
+}
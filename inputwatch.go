@@ -0,0 +1,205 @@
+package tmcl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Edge is the direction of an input change
+type Edge int
+
+const (
+	RisingEdge  Edge = 1
+	FallingEdge Edge = 2
+
+	// AnyEdge selects both edges in OnEdge
+	AnyEdge = RisingEdge | FallingEdge
+)
+
+// String returns the name of the edge
+func (e Edge) String() string {
+	switch e {
+	case RisingEdge:
+		return "rising"
+	case FallingEdge:
+		return "falling"
+	case AnyEdge:
+		return "any"
+	}
+	return "unknown"
+}
+
+// InputEvent is delivered when a watched input changed its state
+type InputEvent struct {
+	Port byte
+	Bank byte
+	Edge Edge
+	Time time.Time
+}
+
+// inputEventBuffer is the capacity of the subscription channels
+const inputEventBuffer = 16
+
+// InputWatcher polls digital inputs and delivers debounced edges to subscription channels
+// and callbacks, since TMCL modules do not report input changes by themselves
+type InputWatcher struct {
+	q        *TMCL
+	interval time.Duration
+	debounce time.Duration
+
+	mutex  sync.Mutex
+	inputs []*watchedInput
+}
+
+// watchedInput is the state of one watched input
+type watchedInput struct {
+	port, bank  byte
+	initialized bool
+	state       bool
+	candidate   bool
+	since       time.Time
+	subscribers []chan InputEvent
+	callbacks   []edgeCallback
+}
+
+// edgeCallback is a callback registered with OnEdge
+type edgeCallback struct {
+	edge Edge
+	fn   func(InputEvent)
+}
+
+// NewInputWatcher creates a watcher polling every interval. A change is only reported after
+// the input kept its new state for the debounce duration.
+func (q *TMCL) NewInputWatcher(interval time.Duration, debounce time.Duration) *InputWatcher {
+	return &InputWatcher{
+		q:        q,
+		interval: interval,
+		debounce: debounce,
+	}
+}
+
+// Watch subscribes to edges of an input. Events are dropped if the receiver does not keep up.
+func (w *InputWatcher) Watch(port byte, bank byte) <-chan InputEvent {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	ch := make(chan InputEvent, inputEventBuffer)
+	in := w.input(port, bank)
+	in.subscribers = append(in.subscribers, ch)
+	return ch
+}
+
+// OnEdge registers a callback for the given edges of an input, RisingEdge, FallingEdge or
+// AnyEdge. Callbacks are called from the goroutine running Run and delay polling until they
+// return.
+func (w *InputWatcher) OnEdge(port byte, bank byte, edge Edge, fn func(InputEvent)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	in := w.input(port, bank)
+	in.callbacks = append(in.callbacks, edgeCallback{edge: edge, fn: fn})
+}
+
+// input returns the state of a watched input, adding it if necessary. Must be called with the
+// mutex held.
+func (w *InputWatcher) input(port byte, bank byte) *watchedInput {
+	for _, in := range w.inputs {
+		if in.port == port && in.bank == bank {
+			return in
+		}
+	}
+	in := &watchedInput{port: port, bank: bank}
+	w.inputs = append(w.inputs, in)
+	return in
+}
+
+// Run polls the inputs until the context is done or reading an input fails. All subscription
+// channels are closed when Run returns.
+func (w *InputWatcher) Run(ctx context.Context) error {
+	defer w.closeSubscribers()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		w.mutex.Lock()
+		inputs := w.inputs
+		w.mutex.Unlock()
+		if len(inputs) == 0 {
+			continue
+		}
+
+		// read all inputs holding the command lock only once
+		params := make([]PollParam, len(inputs))
+		for i, in := range inputs {
+			params[i] = IOPoll(in.port, in.bank)
+		}
+		key, frames := w.q.encodePollParams(params)
+		values := make([]int, len(inputs))
+		if err := w.q.pollBulk(key, frames, values, make([]byte, len(inputs))); err != nil {
+			return err
+		}
+		now := time.Now()
+		for i, in := range inputs {
+			w.update(in, values[i] != 0, now)
+		}
+	}
+}
+
+// update feeds a new sample into the debouncing of an input and delivers the edge, if any
+func (w *InputWatcher) update(in *watchedInput, value bool, now time.Time) {
+	w.mutex.Lock()
+	if !in.initialized {
+		in.initialized = true
+		in.state, in.candidate = value, value
+		w.mutex.Unlock()
+		return
+	}
+	if value != in.candidate {
+		in.candidate = value
+		in.since = now
+	}
+	if in.candidate == in.state || now.Sub(in.since) < w.debounce {
+		w.mutex.Unlock()
+		return
+	}
+
+	in.state = in.candidate
+	ev := InputEvent{Port: in.port, Bank: in.bank, Edge: FallingEdge, Time: now}
+	if in.state {
+		ev.Edge = RisingEdge
+	}
+	for _, ch := range in.subscribers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+	callbacks := append([]edgeCallback(nil), in.callbacks...)
+	w.mutex.Unlock()
+
+	// callbacks may use the watcher themselves
+	for _, cb := range callbacks {
+		if cb.edge&ev.Edge != 0 {
+			cb.fn(ev)
+		}
+	}
+}
+
+// closeSubscribers closes all subscription channels
+func (w *InputWatcher) closeSubscribers() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, in := range w.inputs {
+		for _, ch := range in.subscribers {
+			close(ch)
+		}
+		in.subscribers = nil
+	}
+}
@@ -0,0 +1,171 @@
+package tmcl
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Interlock is an input which must be closed for the gated motors to move, e.g. a door switch
+type Interlock struct {
+	Name string
+
+	// Port and Bank of the input, usually on DigitalInputBank
+	Port byte
+	Bank byte
+
+	// ClosedValue is the value GIO returns while the interlock is closed (safe)
+	ClosedValue int
+
+	// Motors are the gated motors, none meaning all motors
+	Motors []byte
+}
+
+// InterlockEvent is reported when motion was refused or stopped because of an open interlock
+type InterlockEvent struct {
+	Interlock Interlock
+
+	// Motor is the motor which was refused to move or was stopped
+	Motor byte
+
+	// Stopped is true if a running motor was stopped by MonitorInterlocks, false if a
+	// motion command was refused
+	Stopped bool
+
+	// Err is set if the interlock could not be read or the motor could not be stopped
+	Err error
+}
+
+// AddInterlock adds an input which is checked before every motion command of the gated motors
+func (q *TMCL) AddInterlock(il Interlock) {
+	q.do(globalKey, func() {
+		q.interlocks = append(q.interlocks, il)
+	})
+}
+
+// SetInterlockHandler sets the function called when an interlock refused or stopped motion
+func (q *TMCL) SetInterlockHandler(fn func(InterlockEvent)) {
+	q.do(globalKey, func() {
+		q.onInterlock = fn
+	})
+}
+
+// MonitorInterlocks polls the interlocks until the context is done and stops the gated motors
+// while an interlock is open or cannot be read. Motors gated by an interlock without explicit
+// motor list are those which received a motion command before. The handler is called when an
+// interlock opens or fails to be read, not again while it stays open.
+func (q *TMCL) MonitorInterlocks(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	open := map[int]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		var interlocks []Interlock
+		var handler func(InterlockEvent)
+		q.do(globalKey, func() {
+			interlocks = q.interlocks
+			handler = q.onInterlock
+		})
+
+		for i, il := range interlocks {
+			v, err := q.exec(15, il.Port, il.Bank, 0)
+			if err == nil && v == il.ClosedValue {
+				delete(open, i)
+				continue
+			}
+			if err != nil {
+				err = errors.Wrapf(err, "reading interlock %s", il.Name)
+			}
+			opened := !open[i]
+			open[i] = true
+
+			// stop every gated motor, whether the interlock is open or unreadable
+			for _, motor := range q.gatedMotors(il) {
+				_, stopErr := q.exec(3, 0, motor, 0)
+				if handler == nil || !opened {
+					continue
+				}
+				ev := InterlockEvent{Interlock: il, Motor: motor, Stopped: true, Err: err}
+				if stopErr != nil {
+					ev.Err = errors.Wrapf(stopErr, "stopping motor %d", motor)
+				}
+				handler(ev)
+			}
+		}
+	}
+}
+
+// gatedMotors returns the motors gated by the interlock
+func (q *TMCL) gatedMotors(il Interlock) []byte {
+	if len(il.Motors) != 0 {
+		return il.Motors
+	}
+	var motors []byte
+	q.do(globalKey, func() {
+		for m, moved := range q.moved {
+			if moved {
+				motors = append(motors, byte(m))
+			}
+		}
+	})
+	return motors
+}
+
+// checkInterlocks reads all interlocks gating the motor of a motion command and returns an
+// error if one of them is open, must be called with the command lock held
+func (q *TMCL) checkInterlocks(cmd byte, typeNo byte, motor byte) error {
+	if !isMotionCommand(cmd, typeNo) {
+		return nil
+	}
+	for _, il := range q.interlocks {
+		if !il.gates(motor) {
+			continue
+		}
+		v, err := q.transact(context.Background(), Request{Cmd: 15, Type: il.Port, MotorBank: il.Bank})
+		if err == nil && v == il.ClosedValue {
+			continue
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "reading interlock %s", il.Name)
+		} else {
+			err = errors.Errorf("interlock %s open", il.Name)
+		}
+		if q.onInterlock != nil {
+			// report asynchronously, the handler may issue commands itself
+			go q.onInterlock(InterlockEvent{Interlock: il, Motor: motor, Err: err})
+		}
+		return err
+	}
+	q.moved[motor] = true
+	return nil
+}
+
+// gates returns true if the interlock gates the motor
+func (il Interlock) gates(motor byte) bool {
+	if len(il.Motors) == 0 {
+		return true
+	}
+	for _, m := range il.Motors {
+		if m == motor {
+			return true
+		}
+	}
+	return false
+}
+
+// isMotionCommand returns true for ROR, ROL, MVP and RFS start
+func isMotionCommand(cmd byte, typeNo byte) bool {
+	switch cmd {
+	case 1, 2, 4:
+		return true
+	case 13:
+		return typeNo == 0
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package tmcl
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Limits are safety limits of one motor, checked before a command is sent to the board.
+// Zero values mean no limit.
+type Limits struct {
+	// MaxVelocity limits the velocity of ROR/ROL and the speed parameters 2 and 4 set via SAP
+	MaxVelocity int
+
+	// MaxCurrent limits the current parameters 6 and 7 set via SAP
+	MaxCurrent int
+
+	// MinPosition and MaxPosition limit the target of MVP moves and parameter 0 set via SAP,
+	// not checked if both are zero. Relative moves are checked against the current target
+	// position, moves to a coordinate against the stored coordinate.
+	MinPosition int
+	MaxPosition int
+}
+
+// SetLimits sets the safety limits of a motor
+func (q *TMCL) SetLimits(motor byte, limits Limits) {
+	q.do(globalKey, func() {
+		if q.limits == nil {
+			q.limits = make(map[byte]Limits)
+		}
+		q.limits[motor] = limits
+	})
+}
+
+// checkLimits returns an error if a command violates the limits of its motor, must be called
+// with the command lock held
+func (q *TMCL) checkLimits(cmd byte, typeNo byte, motor byte, value int) error {
+	l, ok := q.limits[motor]
+	if !ok {
+		return nil
+	}
+
+	switch cmd {
+	case 1, 2: // ROR, ROL
+		return checkMax("velocity", value, l.MaxVelocity, motor)
+	case 4: // MVP
+		if l.MinPosition == 0 && l.MaxPosition == 0 {
+			return nil
+		}
+		target, err := q.moveTarget(typeNo, motor, value)
+		if err != nil {
+			return errors.Wrapf(err, "checking limits of motor %d", motor)
+		}
+		return checkRange("target position", target, l.MinPosition, l.MaxPosition, motor)
+	case 5: // SAP
+		switch typeNo {
+		case 0:
+			return checkRange("target position", value, l.MinPosition, l.MaxPosition, motor)
+		case 2, 4:
+			return checkMax("velocity", value, l.MaxVelocity, motor)
+		case 6, 7:
+			return checkMax("current", value, l.MaxCurrent, motor)
+		}
+	case 34: // AAP, the value of the accumulator is not known
+		limited := false
+		switch typeNo {
+		case 0:
+			limited = l.MinPosition != 0 || l.MaxPosition != 0
+		case 2, 4:
+			limited = l.MaxVelocity != 0
+		case 6, 7:
+			limited = l.MaxCurrent != 0
+		}
+		if limited {
+			return errors.Errorf("AAP to parameter %d refused, motor %d has limits set", typeNo, motor)
+		}
+	}
+	return nil
+}
+
+// moveTarget returns the target position of an MVP, reading the current target position or
+// the coordinate from the module, must be called with the command lock held
+func (q *TMCL) moveTarget(mode byte, motor byte, value int) (int, error) {
+	switch mode {
+	case ABS:
+		return value, nil
+	case REL:
+		target, err := q.transact(context.Background(), Request{Cmd: 6, Type: byte(TargetPosition), MotorBank: motor})
+		if err != nil {
+			return 0, err
+		}
+		return target + value, nil
+	case COORD:
+		if motor&0x80 != 0 {
+			return 0, errors.New("coordinated moves of several motors cannot be checked")
+		}
+		return q.transact(context.Background(), Request{Cmd: 31, Type: byte(value), MotorBank: motor})
+	}
+	return 0, errors.Errorf("unknown MVP mode %d", mode)
+}
+
+// checkMax checks the absolute value against max, 0 meaning no limit
+func checkMax(name string, value int, max int, motor byte) error {
+	if max == 0 {
+		return nil
+	}
+	if value > max || value < -max {
+		return errors.Errorf("%s %d exceeds limit %d of motor %d", name, value, max, motor)
+	}
+	return nil
+}
+
+// checkRange checks value against min and max, not checked if both are 0
+func checkRange(name string, value int, min, max int, motor byte) error {
+	if min == 0 && max == 0 {
+		return nil
+	}
+	if value < min || value > max {
+		return errors.Errorf("%s %d outside of range %d..%d of motor %d", name, value, min, max, motor)
+	}
+	return nil
+}
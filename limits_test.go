@@ -0,0 +1,71 @@
+package tmcl_test
+
+import (
+	"testing"
+
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/tmcltest"
+)
+
+func TestLimits(t *testing.T) {
+	limits := tmcl.Limits{MaxVelocity: 500, MaxCurrent: 100, MinPosition: -1000, MaxPosition: 1000}
+	tests := []struct {
+		name    string
+		req     tmcl.Request
+		refused bool
+	}{
+		{name: "ROR", req: tmcl.Request{Cmd: 1, Value: 500}},
+		{name: "ROR too fast", req: tmcl.Request{Cmd: 1, Value: 501}, refused: true},
+		{name: "ROL too fast", req: tmcl.Request{Cmd: 2, Value: 600}, refused: true},
+		{name: "ROR negative", req: tmcl.Request{Cmd: 1, Value: -501}, refused: true},
+		{name: "other motor", req: tmcl.Request{Cmd: 1, MotorBank: 1, Value: 2000}},
+		{name: "MVP ABS", req: tmcl.Request{Cmd: 4, Type: tmcl.ABS, Value: -1000}},
+		{name: "MVP ABS beyond", req: tmcl.Request{Cmd: 4, Type: tmcl.ABS, Value: 1001}, refused: true},
+		{name: "MVP REL", req: tmcl.Request{Cmd: 4, Type: tmcl.REL, Value: 200}},
+		{name: "MVP REL beyond", req: tmcl.Request{Cmd: 4, Type: tmcl.REL, Value: 300}, refused: true},
+		{name: "MVP COORD", req: tmcl.Request{Cmd: 4, Type: tmcl.COORD, Value: 1}},
+		{name: "MVP COORD beyond", req: tmcl.Request{Cmd: 4, Type: tmcl.COORD, Value: 2}, refused: true},
+		{name: "SAP target", req: tmcl.Request{Cmd: 5, Type: 0, Value: 1000}},
+		{name: "SAP target beyond", req: tmcl.Request{Cmd: 5, Type: 0, Value: -1001}, refused: true},
+		{name: "SAP max speed", req: tmcl.Request{Cmd: 5, Type: 4, Value: 501}, refused: true},
+		{name: "SAP target speed", req: tmcl.Request{Cmd: 5, Type: 2, Value: 501}, refused: true},
+		{name: "SAP run current", req: tmcl.Request{Cmd: 5, Type: 6, Value: 100}},
+		{name: "SAP run current too high", req: tmcl.Request{Cmd: 5, Type: 6, Value: 101}, refused: true},
+		{name: "SAP standby current too high", req: tmcl.Request{Cmd: 5, Type: 7, Value: 200}, refused: true},
+		{name: "SAP other parameter", req: tmcl.Request{Cmd: 5, Type: 5, Value: 1500}},
+		{name: "AAP", req: tmcl.Request{Cmd: 34, Type: 4}, refused: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tmcltest.NewModule()
+			m.SetAxisParam(0, byte(tmcl.TargetPosition), 750)
+			q := m.Connect()
+			for coord, pos := range []int{0, 900, 1100} {
+				if _, err := q.Exec(30, byte(coord), 0, pos); err != nil {
+					t.Fatal(err)
+				}
+			}
+			q.SetLimits(0, limits)
+
+			sent := q.Stats().Commands
+			_, err := q.ExecRequest(tt.req)
+			if tt.refused {
+				if err == nil {
+					t.Fatal("command not refused")
+				}
+				// relative moves and moves to a coordinate read the target first
+				var reads uint64
+				if tt.req.Cmd == 4 && tt.req.Type != tmcl.ABS {
+					reads = 1
+				}
+				if n := q.Stats().Commands - sent; n != reads {
+					t.Errorf("%d commands sent, want %d", n, reads)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
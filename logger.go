@@ -0,0 +1,113 @@
+package tmcl
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// Logger receives the events of the communication with the board. The methods are called
+// with the command lock held, so they must not call methods of the connection.
+type Logger interface {
+	// Command is called for every request that got a successful reply, d being the time
+	// from sending the request until the reply arrived
+	Command(req Request, value int, d time.Duration)
+
+	// Error is called for every request that failed for other reasons than a timeout, e.g.
+	// a reply with an error status code or an invalid checksum
+	Error(req Request, err error, d time.Duration)
+
+	// Timeout is called for every request without reply within the reply timeout d
+	Timeout(req Request, d time.Duration)
+
+	// Retry is called before a request is repeated for the given attempt, 1 being the first
+	// repetition, because of err
+	Retry(req Request, attempt int, err error)
+}
+
+// WithLogger sets the logger receiving the events of the communication
+func WithLogger(l Logger) Option {
+	return func(q *TMCL) {
+		q.logger = l
+	}
+}
+
+// logResult reports the outcome of a request sent at the given time to the logger, must be
+// called with the command lock held
+func (q *TMCL) logResult(req Request, value int, err error, sent time.Time) {
+	if q.logger == nil {
+		return
+	}
+	d := time.Since(sent)
+	q.lockedCallback(func() {
+		switch {
+		case err == nil:
+			q.logger.Command(req, value, d)
+		case errors.Cause(err) == ErrTimeout:
+			q.logger.Timeout(req, d)
+		default:
+			q.logger.Error(req, err, d)
+		}
+	})
+}
+
+// logFrame reports the outcome of a precomputed request frame of a bulk operation to the
+// logger, must be called with the command lock held
+func (q *TMCL) logFrame(frame []byte, value int, status byte, err error, sent time.Time) {
+	if q.logger == nil {
+		return
+	}
+	in := protocol.DecodeInstruction(frame)
+	req := Request{Cmd: in.Cmd, Type: in.Type, MotorBank: in.MotorBank, Value: in.Value}
+	if err == nil {
+		err = q.statusError(status, req)
+	}
+	q.logResult(req, value, err, sent)
+}
+
+// logRetry reports the repetition of a request to the logger, must be called with the command
+// lock held
+func (q *TMCL) logRetry(req Request, attempt int, err error) {
+	if q.logger == nil {
+		return
+	}
+	q.lockedCallback(func() { q.logger.Retry(req, attempt, err) })
+}
+
+// slogLogger writes the events to a slog.Logger
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to l: successful commands at debug level, timeouts
+// and retries as warnings and other failures as errors
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+// Command implements Logger
+func (q slogLogger) Command(req Request, value int, d time.Duration) {
+	q.l.LogAttrs(context.Background(), slog.LevelDebug, "tmcl command",
+		slog.String("request", req.String()), slog.Int("reply", value), slog.Duration("latency", d))
+}
+
+// Error implements Logger
+func (q slogLogger) Error(req Request, err error, d time.Duration) {
+	q.l.LogAttrs(context.Background(), slog.LevelError, "tmcl command failed",
+		slog.String("request", req.String()), slog.String("error", err.Error()), slog.Duration("latency", d))
+}
+
+// Timeout implements Logger
+func (q slogLogger) Timeout(req Request, d time.Duration) {
+	q.l.LogAttrs(context.Background(), slog.LevelWarn, "tmcl command timed out",
+		slog.String("request", req.String()), slog.Duration("timeout", d))
+}
+
+// Retry implements Logger
+func (q slogLogger) Retry(req Request, attempt int, err error) {
+	q.l.LogAttrs(context.Background(), slog.LevelWarn, "tmcl command repeated",
+		slog.String("request", req.String()), slog.Int("attempt", attempt), slog.String("error", err.Error()))
+}
@@ -0,0 +1,159 @@
+package tmcl
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the latency histogram of NewMetrics if no
+// buckets are given
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond,
+	20 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond,
+	500 * time.Millisecond, time.Second,
+}
+
+// CommandMetrics are the metrics of one command
+type CommandMetrics struct {
+	// Count is the number of requests sent, Errors and Timeouts the failed ones of them and
+	// Retries the repetitions among them
+	Count    uint64
+	Errors   uint64
+	Timeouts uint64
+	Retries  uint64
+
+	// Buckets are the cumulative counts of requests with a latency up to the bucket bounds
+	// of Metrics, LatencySum the sum of the latencies of all requests
+	Buckets    []uint64
+	LatencySum time.Duration
+}
+
+// Metrics is a Logger collecting per command counts, error counts and latency histograms.
+// MultiLogger combines it with a logger writing the events.
+type Metrics struct {
+	mutex    sync.Mutex
+	buckets  []time.Duration
+	commands map[Opcode]*CommandMetrics
+}
+
+// NewMetrics returns empty metrics with the given latency histogram bucket bounds,
+// DefaultLatencyBuckets if none are given
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &Metrics{buckets: buckets, commands: map[Opcode]*CommandMetrics{}}
+}
+
+// Buckets returns the upper bounds of the latency histogram buckets
+func (q *Metrics) Buckets() []time.Duration {
+	return append([]time.Duration(nil), q.buckets...)
+}
+
+// Snapshot returns a copy of the metrics of all commands sent so far
+func (q *Metrics) Snapshot() map[Opcode]CommandMetrics {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	res := make(map[Opcode]CommandMetrics, len(q.commands))
+	for op, m := range q.commands {
+		c := *m
+		c.Buckets = append([]uint64(nil), m.Buckets...)
+		res[op] = c
+	}
+	return res
+}
+
+// Reset sets all metrics to zero
+func (q *Metrics) Reset() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.commands = map[Opcode]*CommandMetrics{}
+}
+
+// command returns the metrics of a command, must be called with the mutex held
+func (q *Metrics) command(cmd byte) *CommandMetrics {
+	m, ok := q.commands[Opcode(cmd)]
+	if !ok {
+		m = &CommandMetrics{Buckets: make([]uint64, len(q.buckets))}
+		q.commands[Opcode(cmd)] = m
+	}
+	return m
+}
+
+// observe counts a request with the given latency
+func (q *Metrics) observe(cmd byte, d time.Duration, update func(m *CommandMetrics)) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	m := q.command(cmd)
+	m.Count++
+	m.LatencySum += d
+	for i, b := range q.buckets {
+		if d <= b {
+			m.Buckets[i]++
+		}
+	}
+	if update != nil {
+		update(m)
+	}
+}
+
+// Command implements Logger
+func (q *Metrics) Command(req Request, value int, d time.Duration) {
+	q.observe(req.Cmd, d, nil)
+}
+
+// Error implements Logger
+func (q *Metrics) Error(req Request, err error, d time.Duration) {
+	q.observe(req.Cmd, d, func(m *CommandMetrics) { m.Errors++ })
+}
+
+// Timeout implements Logger
+func (q *Metrics) Timeout(req Request, d time.Duration) {
+	q.observe(req.Cmd, d, func(m *CommandMetrics) { m.Timeouts++ })
+}
+
+// Retry implements Logger
+func (q *Metrics) Retry(req Request, attempt int, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.command(req.Cmd).Retries++
+}
+
+// multiLogger passes the events to several loggers
+type multiLogger []Logger
+
+// MultiLogger returns a Logger passing all events to the given loggers in order
+func MultiLogger(loggers ...Logger) Logger {
+	return multiLogger(loggers)
+}
+
+// Command implements Logger
+func (q multiLogger) Command(req Request, value int, d time.Duration) {
+	for _, l := range q {
+		l.Command(req, value, d)
+	}
+}
+
+// Error implements Logger
+func (q multiLogger) Error(req Request, err error, d time.Duration) {
+	for _, l := range q {
+		l.Error(req, err, d)
+	}
+}
+
+// Timeout implements Logger
+func (q multiLogger) Timeout(req Request, d time.Duration) {
+	for _, l := range q {
+		l.Timeout(req, d)
+	}
+}
+
+// Retry implements Logger
+func (q multiLogger) Retry(req Request, attempt int, err error) {
+	for _, l := range q {
+		l.Retry(req, attempt, err)
+	}
+}
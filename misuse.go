@@ -0,0 +1,84 @@
+package tmcl
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrClosed is returned for commands issued after Close
+	ErrClosed = errors.New("connection closed")
+
+	// ErrReentrant is returned for commands issued from a callback running with the command
+	// lock held, which would deadlock
+	ErrReentrant = errors.New("command issued from a callback running with the command lock held")
+)
+
+// states of a connection, closing is the time Close stops the motors and applies the
+// fail-safe state
+const (
+	stateOpen    = 0
+	stateClosed  = 1
+	stateClosing = 2
+)
+
+// Close closes the serial port for good, first stopping all motors if WithStopOnClose was
+// given and applying the fail-safe state, if configured. Unlike ClosePort, later commands
+// return an error instead of reopening the port.
+func (q *TMCL) Close() error {
+	if !atomic.CompareAndSwapInt32(&q.closed, stateOpen, stateClosing) {
+		return ErrClosed
+	}
+	var err error
+	if q.stopOnClose {
+		err = q.StopAll()
+	}
+	q.ClosePort()
+	atomic.StoreInt32(&q.closed, stateClosed)
+	return err
+}
+
+var _ io.Closer = (*TMCL)(nil)
+
+// checkUsable returns an error if the connection was closed or the caller is a callback
+// invoked with the command lock held, which would deadlock
+func (q *TMCL) checkUsable() error {
+	if atomic.LoadInt32(&q.closed) == stateClosed {
+		return ErrClosed
+	}
+	if id := atomic.LoadInt64(&q.callbackGoroutine); id != 0 && id == goroutineID() {
+		return ErrReentrant
+	}
+	return nil
+}
+
+// lockedCallback calls fn, which is invoked with the command lock held, and marks the calling
+// goroutine so that commands issued by fn are detected instead of deadlocking
+func (q *TMCL) lockedCallback(fn func()) {
+	atomic.StoreInt64(&q.callbackGoroutine, goroutineID())
+	defer atomic.StoreInt64(&q.callbackGoroutine, 0)
+	fn()
+}
+
+// goroutineID returns the id of the current goroutine, used for misuse detection
+func goroutineID() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
+
+// echoError returns the error for a timeout after only replies echoing a different command than
+// the one sent arrived, which usually means somebody else is using the port at the same time
+func echoError(expected, received byte) error {
+	return errors.Errorf("received reply to %s while waiting for %s, is the port used outside of this package?", Opcode(received), Opcode(expected))
+}
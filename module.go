@@ -0,0 +1,150 @@
+package tmcl
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidMotor is returned for commands addressing a motor the module does not have
+var ErrInvalidMotor = errors.New("invalid motor")
+
+// moduleAxes is the number of motors of known module types
+var moduleAxes = map[int]int{
+	110:  1,
+	111:  1,
+	140:  1,
+	141:  1,
+	142:  1,
+	160:  1,
+	161:  1,
+	170:  1,
+	171:  1,
+	310:  3,
+	351:  3,
+	610:  6,
+	1021: 1,
+	1090: 1,
+	1110: 1,
+	1140: 1,
+	1141: 1,
+	1160: 1,
+	1161: 1,
+	1180: 1,
+	1240: 1,
+	1241: 1,
+	1260: 1,
+	1270: 1,
+	1276: 1,
+	1278: 1,
+	1310: 3,
+	1311: 1,
+	1633: 1,
+	1670: 1,
+	1671: 1,
+	2110: 2,
+	2160: 2,
+	3110: 3,
+	3230: 3,
+	6110: 6,
+	6212: 6,
+	6214: 6,
+}
+
+// SetAxisCount sets the number of motors of the module, disabling the automatic detection.
+// Commands for motors beyond that number fail with ErrInvalidMotor. 0 disables the check.
+func (q *TMCL) SetAxisCount(n int) {
+	q.do(globalKey, func() {
+		q.axisCount = n
+		q.moduleDetected = true
+	})
+}
+
+// AxisCount returns the number of motors of the module, detecting the module type if not done
+// yet. 0 means unknown.
+func (q *TMCL) AxisCount() int {
+	var n int
+	q.do(globalKey, func() {
+		q.detectModule()
+		n = q.axisCount
+	})
+	return n
+}
+
+// detectRetryInterval is the time after a failed module detection before it is tried again
+const detectRetryInterval = time.Second
+
+// detectModule reads the module type once and looks up its number of motors, must be called
+// with the command lock held. The detection is done once the module replied or the command
+// filter does not permit the request, after line errors it is tried again with the next
+// command, but not before detectRetryInterval.
+func (q *TMCL) detectModule() {
+	if q.moduleDetected || time.Now().Before(q.detectRetry) {
+		return
+	}
+	req := Request{Cmd: 136, Type: 1}
+	if err := q.checkCommandFilter(req.Cmd, req.Type); err != nil {
+		q.moduleDetected = true
+		return
+	}
+
+	var end func(int, error)
+	if q.tracer != nil {
+		end = q.tracer.Start(context.Background(), req)
+	}
+	v, err := q.transact(context.Background(), req)
+	if end != nil {
+		end(v, err)
+	}
+	var boardErr *Error
+	switch {
+	case err == nil:
+		q.moduleType = (v >> 16) & 0xFFFF
+		q.axisCount = moduleAxes[q.moduleType]
+	case errors.As(err, &boardErr):
+		// the firmware does not report its module type
+	default:
+		q.detectRetry = time.Now().Add(detectRetryInterval)
+		return
+	}
+	q.moduleDetected = true
+}
+
+// checkMotor returns ErrInvalidMotor if a command addresses a motor the module does not have,
+// must be called with the command lock held
+func (q *TMCL) checkMotor(cmd byte, motor byte) error {
+	switch cmd {
+	case 1, 2, 3, 4, 5, 6, 7, 8, 13, 32, 34:
+	case 30, 31:
+		// motor 255 copies coordinates between RAM and EEPROM
+		if motor == coordinateEEPROM {
+			return nil
+		}
+	default:
+		return nil
+	}
+	q.detectModule()
+	if q.axisCount != 0 && int(motor) >= q.axisCount {
+		return errors.Wrapf(ErrInvalidMotor, "%s: motor %d, module has %d", Opcode(cmd), motor, q.axisCount)
+	}
+	return nil
+}
+
+// SetModuleAddress sets the serial address of the module the requests are sent to. If it is
+// not 0, replies from modules with other addresses are ignored, so that several modules can
+// share an RS485 bus.
+func (q *TMCL) SetModuleAddress(addr byte) {
+	q.do(globalKey, func() {
+		q.address = addr
+	})
+}
+
+// ModuleAddress returns the serial address of the module
+func (q *TMCL) ModuleAddress() byte {
+	var addr byte
+	q.do(globalKey, func() {
+		addr = q.address
+	})
+	return addr
+}
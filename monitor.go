@@ -0,0 +1,227 @@
+package tmcl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// AxisPoll returns the PollParam reading an axis parameter of a motor
+func AxisPoll(p AxisParam, motor byte) PollParam {
+	return PollParam{Cmd: 6, TypeNo: byte(p), MotorOrBank: motor}
+}
+
+// GlobalPoll returns the PollParam reading a global parameter
+func GlobalPoll(index byte, bank byte) PollParam {
+	return PollParam{Cmd: 10, TypeNo: index, MotorOrBank: bank}
+}
+
+// IOPoll returns the PollParam reading an input or output
+func IOPoll(port byte, bank byte) PollParam {
+	return PollParam{Cmd: 15, TypeNo: port, MotorOrBank: bank}
+}
+
+// Sample is the result of one poll cycle of a Monitor
+type Sample struct {
+	Time time.Time
+
+	// Params are the polled parameters of the monitor, shared by all samples
+	Params []PollParam
+
+	// Values are in the order of Params, nil if the cycle failed with Err
+	Values []int
+	Err    error
+}
+
+// Value returns the value of a parameter, false if it is not polled or the cycle failed
+func (s Sample) Value(p PollParam) (int, bool) {
+	if s.Values == nil {
+		return 0, false
+	}
+	for i, param := range s.Params {
+		if param == p {
+			return s.Values[i], true
+		}
+	}
+	return 0, false
+}
+
+const (
+	// sampleBuffer is the capacity of the subscription channels
+	sampleBuffer = 16
+
+	// monitorErrorPause is the pause after a failed cycle of a monitor without interval
+	monitorErrorPause = 100 * time.Millisecond
+)
+
+// Monitor polls a set of parameters at a fixed rate and delivers the samples to subscribers.
+// All parameters of a cycle are read holding the command lock only once, so other commands
+// get their turn between two cycles.
+type Monitor struct {
+	q        *TMCL
+	params   []PollParam
+	key      int
+	frames   []byte
+	interval time.Duration
+	rate     rateMeter
+
+	mutex       sync.Mutex
+	latest      Sample
+	subscribers []chan Sample
+	callbacks   []*sampleCallback
+}
+
+// sampleCallback wraps a callback so that it can be removed again
+type sampleCallback struct {
+	fn func(Sample)
+}
+
+// NewMonitor creates a monitor polling the parameters every interval, 0 polling without pause
+func (q *TMCL) NewMonitor(params []PollParam, interval time.Duration) *Monitor {
+	params = append([]PollParam(nil), params...)
+	key, frames := q.encodePollParams(params)
+	return &Monitor{
+		q:        q,
+		params:   params,
+		key:      key,
+		frames:   frames,
+		interval: interval,
+	}
+}
+
+// Params returns the polled parameters
+func (m *Monitor) Params() []PollParam {
+	return append([]PollParam(nil), m.params...)
+}
+
+// Subscribe returns a channel receiving all samples. Samples are dropped if the receiver does
+// not keep up. The channel is closed by Unsubscribe or when Run returns.
+func (m *Monitor) Subscribe() <-chan Sample {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ch := make(chan Sample, sampleBuffer)
+	m.subscribers = append(m.subscribers, ch)
+	return ch
+}
+
+// Unsubscribe removes and closes a channel returned by Subscribe
+func (m *Monitor) Unsubscribe(ch <-chan Sample) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i, sub := range m.subscribers {
+		if sub == ch {
+			close(sub)
+			m.subscribers = append(m.subscribers[:i], m.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// OnSample registers a callback called with every sample from the goroutine running Run,
+// delaying the next cycle until it returns. The returned function removes the callback.
+func (m *Monitor) OnSample(fn func(Sample)) (remove func()) {
+	cb := &sampleCallback{fn: fn}
+	m.mutex.Lock()
+	m.callbacks = append(m.callbacks, cb)
+	m.mutex.Unlock()
+
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		for i, c := range m.callbacks {
+			if c == cb {
+				m.callbacks = append(m.callbacks[:i], m.callbacks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Latest returns the last sample, the zero Sample before the first cycle
+func (m *Monitor) Latest() Sample {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.latest
+}
+
+// Rate returns the measured number of poll cycles per second
+func (m *Monitor) Rate() float64 {
+	return m.rate.get()
+}
+
+// Run polls until the context is done. Failed cycles are delivered as samples with Err set and
+// polling continues. All subscription channels are closed when Run returns.
+func (m *Monitor) Run(ctx context.Context) error {
+	defer m.closeSubscribers()
+
+	var tick <-chan time.Time
+	if m.interval > 0 {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	n := len(m.params)
+	statuses := make([]byte, n)
+	var last time.Time
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := time.Now()
+		if !last.IsZero() {
+			m.rate.update(start.Sub(last))
+		}
+		last = start
+
+		values := make([]int, n)
+		s := Sample{Params: m.params}
+		if err := m.q.pollBulk(m.key, m.frames, values, statuses); err != nil {
+			s.Err = err
+		} else {
+			s.Values = values
+		}
+		s.Time = time.Now()
+		m.deliver(s)
+
+		// wait for next cycle, without interval only after errors
+		wait := tick
+		if wait == nil && s.Err != nil {
+			wait = time.After(monitorErrorPause)
+		}
+		if wait != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-wait:
+			}
+		}
+	}
+}
+
+// deliver passes a sample to all subscribers and callbacks
+func (m *Monitor) deliver(s Sample) {
+	m.mutex.Lock()
+	m.latest = s
+	for _, ch := range m.subscribers {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+	callbacks := append([]*sampleCallback(nil), m.callbacks...)
+	m.mutex.Unlock()
+
+	for _, cb := range callbacks {
+		cb.fn(s)
+	}
+}
+
+// closeSubscribers closes all subscription channels
+func (m *Monitor) closeSubscribers() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, ch := range m.subscribers {
+		close(ch)
+	}
+	m.subscribers = nil
+}
@@ -0,0 +1,275 @@
+// Package motion gives positions and velocities of TMCL motors in physical units. It works on
+// any Board, e.g. a tmcl.TMCL connection or a simulator.
+package motion
+
+import (
+	"math"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// defaultClockHz is the clock of the TMC428/429 motion controller of most modules
+const defaultClockHz = 16e6
+
+// modes of MVP
+const (
+	abs byte = 0
+	rel byte = 1
+)
+
+// Board are the commands of a module an axis needs, implemented by tmcl.TMCL and tmcl.Board
+type Board interface {
+	ROR(motor byte, velocity int) error
+	ROL(motor byte, velocity int) error
+	MST(motor byte) error
+	MVP(mode byte, motor byte, value int) error
+	SAP(index byte, motor byte, value int) error
+	GAP(index byte, motor byte) (int, error)
+	STAP(index byte, motor byte) error
+	RSAP(index byte, motor byte) error
+}
+
+// Mechanics describes the drive train of a motor, used to convert physical units
+type Mechanics struct {
+	// FullStepsPerRev are the full steps of one motor revolution, 0 meaning 200
+	FullStepsPerRev int `json:"fullStepsPerRev"`
+
+	// Microsteps per full step, 0 reads the microstep resolution (axis parameter 140)
+	Microsteps int `json:"microsteps"`
+
+	// GearRatio are the motor revolutions per revolution of the output, 0 meaning 1
+	GearRatio float64 `json:"gearRatio"`
+
+	// MMPerRev is the travel per revolution of the output, e.g. the lead of a spindle
+	MMPerRev float64 `json:"mmPerRev"`
+
+	// ClockHz is the clock of the motion controller, 0 meaning 16 MHz
+	ClockHz float64 `json:"clockHz"`
+}
+
+// Axis is a motor whose positions and velocities are given in physical units
+type Axis struct {
+	Board Board
+	Motor byte
+	Mech  Mechanics
+
+	mutex        sync.Mutex
+	loaded       bool
+	microsteps   int
+	pulseDivisor int
+}
+
+// NewAxis creates an axis for a motor of a board
+func NewAxis(b Board, motor byte, m Mechanics) *Axis {
+	return &Axis{Board: b, Motor: motor, Mech: m}
+}
+
+// Reload reads the microstep resolution and pulse divisor from the module again, needed after
+// they were changed
+func (a *Axis) Reload() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.loaded = false
+	return a.load()
+}
+
+// load reads the settings needed for the conversions once, must be called with the mutex held
+func (a *Axis) load() error {
+	if a.loaded {
+		return nil
+	}
+	a.microsteps = a.Mech.Microsteps
+	if a.microsteps == 0 {
+		res, err := a.Board.GAP(140, a.Motor)
+		if err != nil {
+			return errors.Wrap(err, "microstep resolution")
+		}
+		a.microsteps = 1 << uint(res)
+	}
+	div, err := a.Board.GAP(154, a.Motor)
+	if err != nil {
+		return errors.Wrap(err, "pulse divisor")
+	}
+	a.pulseDivisor = div
+	a.loaded = true
+	return nil
+}
+
+// stepsPerRev returns the microsteps of one revolution of the output
+func (a *Axis) stepsPerRev() (float64, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if err := a.load(); err != nil {
+		return 0, err
+	}
+	full := a.Mech.FullStepsPerRev
+	if full == 0 {
+		full = 200
+	}
+	gear := a.Mech.GearRatio
+	if gear == 0 {
+		gear = 1
+	}
+	return float64(full*a.microsteps) * gear, nil
+}
+
+// stepsPerMM returns the microsteps per mm of travel
+func (a *Axis) stepsPerMM() (float64, error) {
+	if a.Mech.MMPerRev == 0 {
+		return 0, errors.New("MMPerRev not set")
+	}
+	spr, err := a.stepsPerRev()
+	return spr / a.Mech.MMPerRev, err
+}
+
+// stepsPerSecond returns the microsteps per second of one unit of internal velocity
+func (a *Axis) stepsPerSecond() (float64, error) {
+	if _, err := a.stepsPerRev(); err != nil {
+		return 0, err
+	}
+	clock := a.Mech.ClockHz
+	if clock == 0 {
+		clock = defaultClockHz
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return clock / (math.Exp2(float64(a.pulseDivisor)) * 2048 * 32), nil
+}
+
+// MMToSteps converts a travel in mm to microsteps
+func (a *Axis) MMToSteps(mm float64) (int, error) {
+	f, err := a.stepsPerMM()
+	return int(math.Round(mm * f)), err
+}
+
+// StepsToMM converts microsteps to a travel in mm
+func (a *Axis) StepsToMM(steps int) (float64, error) {
+	f, err := a.stepsPerMM()
+	if err != nil {
+		return 0, err
+	}
+	return float64(steps) / f, nil
+}
+
+// DegreesToSteps converts an angle of the output to microsteps
+func (a *Axis) DegreesToSteps(deg float64) (int, error) {
+	spr, err := a.stepsPerRev()
+	return int(math.Round(deg * spr / 360)), err
+}
+
+// StepsToDegrees converts microsteps to an angle of the output
+func (a *Axis) StepsToDegrees(steps int) (float64, error) {
+	spr, err := a.stepsPerRev()
+	if err != nil {
+		return 0, err
+	}
+	return float64(steps) * 360 / spr, nil
+}
+
+// RPMToVelocity converts revolutions per minute of the output to internal velocity units
+func (a *Axis) RPMToVelocity(rpm float64) (int, error) {
+	spr, err := a.stepsPerRev()
+	if err != nil {
+		return 0, err
+	}
+	sps, err := a.stepsPerSecond()
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Round(rpm / 60 * spr / sps)), nil
+}
+
+// VelocityToRPM converts internal velocity units to revolutions per minute of the output
+func (a *Axis) VelocityToRPM(v int) (float64, error) {
+	spr, err := a.stepsPerRev()
+	if err != nil {
+		return 0, err
+	}
+	sps, err := a.stepsPerSecond()
+	if err != nil {
+		return 0, err
+	}
+	return float64(v) * sps / spr * 60, nil
+}
+
+// MoveToMM moves to an absolute position in mm
+func (a *Axis) MoveToMM(mm float64) error {
+	steps, err := a.MMToSteps(mm)
+	if err != nil {
+		return err
+	}
+	return a.Board.MVP(abs, a.Motor, steps)
+}
+
+// MoveByMM moves by a distance in mm
+func (a *Axis) MoveByMM(mm float64) error {
+	steps, err := a.MMToSteps(mm)
+	if err != nil {
+		return err
+	}
+	return a.Board.MVP(rel, a.Motor, steps)
+}
+
+// PositionMM returns the actual position in mm
+func (a *Axis) PositionMM() (float64, error) {
+	v, err := a.Board.GAP(1, a.Motor)
+	if err != nil {
+		return 0, err
+	}
+	return a.StepsToMM(v)
+}
+
+// MoveToDegrees moves to an absolute angle of the output
+func (a *Axis) MoveToDegrees(deg float64) error {
+	steps, err := a.DegreesToSteps(deg)
+	if err != nil {
+		return err
+	}
+	return a.Board.MVP(abs, a.Motor, steps)
+}
+
+// PositionDegrees returns the actual angle of the output
+func (a *Axis) PositionDegrees() (float64, error) {
+	v, err := a.Board.GAP(1, a.Motor)
+	if err != nil {
+		return 0, err
+	}
+	return a.StepsToDegrees(v)
+}
+
+// VelocityRPM returns the actual velocity in revolutions per minute of the output
+func (a *Axis) VelocityRPM() (float64, error) {
+	v, err := a.Board.GAP(3, a.Motor)
+	if err != nil {
+		return 0, err
+	}
+	return a.VelocityToRPM(v)
+}
+
+// SetMaxVelocityRPM sets the maximum positioning speed in revolutions per minute of the output
+func (a *Axis) SetMaxVelocityRPM(rpm float64) error {
+	v, err := a.RPMToVelocity(rpm)
+	if err != nil {
+		return err
+	}
+	return a.Board.SAP(4, a.Motor, v)
+}
+
+// RotateRPM rotates with the given revolutions per minute of the output, negative values
+// rotate left
+func (a *Axis) RotateRPM(rpm float64) error {
+	v, err := a.RPMToVelocity(math.Abs(rpm))
+	if err != nil {
+		return err
+	}
+	if rpm < 0 {
+		return a.Board.ROL(a.Motor, v)
+	}
+	return a.Board.ROR(a.Motor, v)
+}
+
+// Stop stops the motor
+func (a *Axis) Stop() error {
+	return a.Board.MST(a.Motor)
+}
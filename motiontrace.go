@@ -0,0 +1,165 @@
+package tmcl
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// defaultTraceRate is the sample rate of traces if none is configured
+const defaultTraceRate = 100
+
+// TraceConfig configures RecordTrace and TraceMove
+type TraceConfig struct {
+	// Rate is the number of samples per second, 0 meaning 100. Every sample reads 3 or 4
+	// parameters, so the rate reached depends on the baud rate.
+	Rate float64
+
+	// Tail is how long TraceMove keeps recording after the target position was reached
+	Tail time.Duration
+
+	// Timeout limits TraceMove, 0 recording until the target is reached or the context is done
+	Timeout time.Duration
+}
+
+// TracePoint is one sample of a motion trace
+type TracePoint struct {
+	// Time since the start of the trace
+	Time time.Duration
+
+	Position int
+	Speed    int
+	Load     int
+}
+
+// MotionTrace is the recorded motion of a motor, for tuning acceleration and current by
+// inspecting real motion profiles
+type MotionTrace struct {
+	Motor  byte
+	Start  time.Time
+	Points []TracePoint
+
+	// Missed is the number of samples lost because of communication errors
+	Missed int
+}
+
+// WriteCSV writes the trace as CSV with a header line, the time in seconds
+func (t *MotionTrace) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"time", "position", "speed", "load"})
+	for _, p := range t.Points {
+		_ = cw.Write([]string{
+			strconv.FormatFloat(p.Time.Seconds(), 'f', 3, 64),
+			strconv.Itoa(p.Position),
+			strconv.Itoa(p.Speed),
+			strconv.Itoa(p.Load),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the trace as JSON object, the times in seconds
+func (t *MotionTrace) WriteJSON(w io.Writer) error {
+	type point struct {
+		Time     float64 `json:"time"`
+		Position int     `json:"position"`
+		Speed    int     `json:"speed"`
+		Load     int     `json:"load"`
+	}
+	res := struct {
+		Motor  byte      `json:"motor"`
+		Start  time.Time `json:"start"`
+		Missed int       `json:"missed"`
+		Points []point   `json:"points"`
+	}{Motor: t.Motor, Start: t.Start, Missed: t.Missed, Points: make([]point, len(t.Points))}
+	for i, p := range t.Points {
+		res.Points[i] = point{Time: p.Time.Seconds(), Position: p.Position, Speed: p.Speed, Load: p.Load}
+	}
+	return json.NewEncoder(w).Encode(res)
+}
+
+// RecordTrace records actual position, actual speed and load value of a motor until the
+// context is done, e.g. while the motor is moved by other code
+func (q *TMCL) RecordTrace(ctx context.Context, motor byte, c TraceConfig) *MotionTrace {
+	t, _ := q.recordTrace(ctx, motor, c, time.Now(), nil)
+	return t
+}
+
+// TraceMove moves a motor like MVP and records its motion until the target position is
+// reached and the tail of the configuration has passed. If the timeout expires or the
+// context is done before, the motor is stopped and the partial trace is returned with the
+// error.
+func (q *TMCL) TraceMove(ctx context.Context, mode byte, motor byte, value int, c TraceConfig) (*MotionTrace, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	if err := q.MVPContext(ctx, mode, motor, value); err != nil {
+		return nil, err
+	}
+
+	var reached time.Time
+	t, finished := q.recordTrace(ctx, motor, c, start, func(s Sample) bool {
+		if reached.IsZero() {
+			if s.Values[3] == 0 {
+				return false
+			}
+			reached = s.Time
+		}
+		return s.Time.Sub(reached) >= c.Tail
+	})
+	if !finished {
+		_ = q.MST(motor)
+		return t, ctx.Err()
+	}
+	return t, nil
+}
+
+// recordTrace records a trace from start until the context is done or done returns true for
+// a sample, which then also has the target position reached flag as fourth value. With done
+// the result tells whether it ended the recording.
+func (q *TMCL) recordTrace(ctx context.Context, motor byte, c TraceConfig, start time.Time, done func(Sample) bool) (*MotionTrace, bool) {
+	params := []PollParam{
+		AxisPoll(ActualPosition, motor),
+		AxisPoll(ActualSpeed, motor),
+		AxisPoll(ActualLoad, motor),
+	}
+	if done != nil {
+		params = append(params, AxisPoll(TargetPositionReached, motor))
+	}
+	rate := c.Rate
+	if rate <= 0 {
+		rate = defaultTraceRate
+	}
+	m := q.NewMonitor(params, time.Duration(float64(time.Second)/rate))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	t := &MotionTrace{Motor: motor, Start: start}
+	var finished bool
+	m.OnSample(func(s Sample) {
+		if s.Err != nil {
+			t.Missed++
+			return
+		}
+		t.Points = append(t.Points, TracePoint{
+			Time:     s.Time.Sub(t.Start),
+			Position: s.Values[0],
+			Speed:    s.Values[1],
+			Load:     s.Values[2],
+		})
+		if done != nil && done(s) {
+			finished = true
+			cancel()
+		}
+	})
+	_ = m.Run(ctx)
+	return t, finished
+}
@@ -0,0 +1,124 @@
+package tmcl
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MotorErrors collects the errors of a group operation by motor
+type MotorErrors map[byte]error
+
+// Error lists the failed motors
+func (e MotorErrors) Error() string {
+	motors := make([]int, 0, len(e))
+	for m := range e {
+		motors = append(motors, int(m))
+	}
+	sort.Ints(motors)
+	msgs := make([]string, len(motors))
+	for i, m := range motors {
+		msgs[i] = "motor " + strconv.Itoa(m) + ": " + e[byte(m)].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// err returns nil if no errors were collected
+func (e MotorErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// MotorSet applies operations to a group of axes of one module
+type MotorSet struct {
+	q      *TMCL
+	axes   []*Axis
+	motors []byte
+}
+
+// MotorSet returns a group of the given motors, as axes without mechanics
+func (q *TMCL) MotorSet(motors ...byte) *MotorSet {
+	axes := make([]*Axis, len(motors))
+	for i, m := range motors {
+		axes[i] = q.Axis(m, Mechanics{})
+	}
+	return q.AxisSet(axes...)
+}
+
+// AxisSet returns a group of axes, which must be axes of this module
+func (q *TMCL) AxisSet(axes ...*Axis) *MotorSet {
+	motors := make([]byte, len(axes))
+	for i, a := range axes {
+		motors[i] = a.Motor
+	}
+	return &MotorSet{q: q, axes: axes, motors: motors}
+}
+
+// Axes returns the axes of the group
+func (s *MotorSet) Axes() []*Axis {
+	return s.axes
+}
+
+// Motors returns the motors of the group
+func (s *MotorSet) Motors() []byte {
+	return s.motors
+}
+
+// each runs fn for every axis, also if it fails for some, and returns MotorErrors if any failed
+func (s *MotorSet) each(fn func(a *Axis) error) error {
+	errs := make(MotorErrors)
+	for _, a := range s.axes {
+		if err := fn(a); err != nil {
+			errs[a.Motor] = err
+		}
+	}
+	return errs.err()
+}
+
+// SAP sets an axis parameter on all motors
+func (s *MotorSet) SAP(index byte, value int) error {
+	return s.each(func(a *Axis) error { return s.q.SAP(index, a.Motor, value) })
+}
+
+// STAP stores an axis parameter of all motors in the EEPROM
+func (s *MotorSet) STAP(index byte) error {
+	return s.each(func(a *Axis) error { return s.q.STAP(index, a.Motor) })
+}
+
+// Stop stops all motors, trying every motor even if stopping another one failed
+func (s *MotorSet) Stop() error {
+	return s.each((*Axis).Stop)
+}
+
+// GAP reads an axis parameter of all motors in one batch
+func (s *MotorSet) GAP(index byte) (map[byte]int, error) {
+	frames := make([]byte, len(s.motors)*frameSize)
+	for i, m := range s.motors {
+		s.q.encodeFrame(frames[i*frameSize:(i+1)*frameSize], 6, index, m, 0)
+	}
+	values := make([]int, len(s.motors))
+	statuses := make([]byte, len(s.motors))
+	if err := s.q.execBulk(globalKey, frames, values, statuses, nil); err != nil {
+		return nil, err
+	}
+
+	res := make(map[byte]int, len(s.motors))
+	errs := make(MotorErrors)
+	s.q.do(globalKey, func() {
+		for i, m := range s.motors {
+			if err := s.q.statusError(statuses[i], Request{Cmd: 6, Type: index, MotorBank: m}); err != nil {
+				errs[m] = err
+				continue
+			}
+			res[m] = values[i]
+		}
+	})
+	return res, errs.err()
+}
+
+// Positions reads the actual positions of all motors in one batch
+func (s *MotorSet) Positions() (map[byte]int, error) {
+	return s.GAP(1)
+}
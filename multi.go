@@ -0,0 +1,82 @@
+package tmcl
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BoardResult is the outcome of an operation on one board
+type BoardResult struct {
+	Board *TMCL
+	Err   error
+}
+
+// BoardResults are the outcomes of an operation run on several boards
+type BoardResults []BoardResult
+
+// Err returns an error listing all failed boards, or nil if the operation succeeded everywhere.
+// It unwraps to the error of each board, so that errors.Is and errors.As work on it.
+func (r BoardResults) Err() error {
+	var errs boardErrors
+	for i, res := range r {
+		if res.Err == nil {
+			continue
+		}
+		name := "board " + strconv.Itoa(i)
+		if res.Board != nil && res.Board.ComPort != "" {
+			name = res.Board.ComPort
+		}
+		errs = append(errs, errors.WithMessage(res.Err, name))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// boardErrors are the errors of several boards
+type boardErrors []error
+
+// Error implements the error interface
+func (e boardErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of the boards
+func (e boardErrors) Unwrap() []error {
+	return e
+}
+
+// ForEachBoard runs fn for all boards concurrently using at most workers goroutines
+// (0 meaning one per board) and returns the results in the order of boards
+func ForEachBoard(boards []*TMCL, workers int, fn func(i int, q *TMCL) error) BoardResults {
+	if workers <= 0 || workers > len(boards) {
+		workers = len(boards)
+	}
+
+	results := make(BoardResults, len(boards))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = BoardResult{Board: boards[i], Err: fn(i, boards[i])}
+			}
+		}()
+	}
+	for i := range boards {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
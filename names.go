@@ -0,0 +1,226 @@
+package tmcl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// Opcode is a TMCL command number
+type Opcode byte
+
+// String returns the mnemonic of the command
+func (o Opcode) String() string {
+	if s, ok := protocol.Mnemonic(byte(o)); ok {
+		return s
+	}
+	return "CMD" + strconv.Itoa(int(o))
+}
+
+// Status is the status code of a reply
+type Status byte
+
+// String returns the description of the status code
+func (s Status) String() string {
+	if t, ok := protocol.StatusText(byte(s)); ok {
+		return t
+	}
+	return "status " + strconv.Itoa(int(s))
+}
+
+// ApplicationState is the state of the standalone application returned by GetApplicationStatus
+type ApplicationState int
+
+const (
+	ApplicationStopped  ApplicationState = 0
+	ApplicationRunning  ApplicationState = 1
+	ApplicationStepping ApplicationState = 2
+	ApplicationReset    ApplicationState = 3
+)
+
+// String returns the name of the application state
+func (s ApplicationState) String() string {
+	switch s {
+	case ApplicationStopped:
+		return "stopped"
+	case ApplicationRunning:
+		return "running"
+	case ApplicationStepping:
+		return "stepping"
+	case ApplicationReset:
+		return "reset"
+	}
+	return "state " + strconv.Itoa(int(s))
+}
+
+// axisParamNames is the catalog of axis parameters of the TMCM-351 firmware
+var axisParamNames = map[byte]string{
+	0:   "target position",
+	1:   "actual position",
+	2:   "target speed",
+	3:   "actual speed",
+	4:   "maximum positioning speed",
+	5:   "maximum acceleration",
+	6:   "absolute max. current",
+	7:   "standby current",
+	8:   "target position reached",
+	9:   "reference switch status",
+	10:  "right limit switch status",
+	11:  "left limit switch status",
+	12:  "right limit switch disable",
+	13:  "left limit switch disable",
+	130: "minimum speed",
+	135: "actual acceleration",
+	138: "ramp mode",
+	140: "microstep resolution",
+	141: "reference switch tolerance",
+	149: "soft stop flag",
+	153: "ramp divisor",
+	154: "pulse divisor",
+	193: "reference search mode",
+	194: "reference search speed",
+	195: "reference switch speed",
+	196: "distance end switches",
+	203: "mixed decay threshold",
+	204: "freewheeling delay",
+	205: "stall detection threshold",
+	206: "actual load value",
+	207: "extended error flags",
+	208: "driver error flags",
+	209: "encoder position",
+	210: "encoder prescaler",
+	211: "fullstep threshold",
+	212: "maximum encoder deviation",
+	213: "group index",
+	214: "power down delay",
+}
+
+// globalParamNames is the catalog of the bank 0 global parameters of the TMCM-351 firmware
+var globalParamNames = map[byte]string{
+	64:  "EEPROM magic",
+	65:  "RS232/RS485 baud rate",
+	66:  "serial address",
+	67:  "ASCII mode",
+	68:  "serial heartbeat",
+	69:  "CAN bit rate",
+	70:  "CAN reply ID",
+	71:  "CAN ID",
+	73:  "configuration EEPROM lock flag",
+	75:  "telegram pause time",
+	76:  "serial host address",
+	77:  "auto start mode",
+	80:  "shutdown pin functionality",
+	81:  "TMCL code protection",
+	82:  "CAN heartbeat",
+	83:  "CAN secondary address",
+	84:  "coordinate storage",
+	85:  "do not store user variables",
+	87:  "serial secondary address",
+	128: "TMCL application status",
+	129: "download mode",
+	130: "TMCL program counter",
+	132: "tick timer",
+	133: "random number",
+}
+
+// AxisParamName returns the name of an axis parameter
+func AxisParamName(index byte) string {
+	if s, ok := axisParamNames[index]; ok {
+		return s
+	}
+	return "axis parameter " + strconv.Itoa(int(index))
+}
+
+// GlobalParamName returns the name of a global parameter
+func GlobalParamName(index byte, bank byte) string {
+	switch bank {
+	case 0:
+		if s, ok := globalParamNames[index]; ok {
+			return s
+		}
+	case 2:
+		return "user variable " + strconv.Itoa(int(index))
+	}
+	return "global parameter " + strconv.Itoa(int(index)) + " of bank " + strconv.Itoa(int(bank))
+}
+
+// LookupAxisParam returns the number of the axis parameter with the given name, ignoring
+// case, spaces and punctuation, e.g. "Maximum-Positioning-Speed"
+func LookupAxisParam(name string) (byte, bool) {
+	return lookupName(axisParamNames, name)
+}
+
+// LookupGlobalParam returns the number of the bank 0 global parameter with the given name,
+// ignoring case, spaces and punctuation
+func LookupGlobalParam(name string) (byte, bool) {
+	return lookupName(globalParamNames, name)
+}
+
+// AxisParamNames returns the names of all known axis parameters by number
+func AxisParamNames() map[byte]string {
+	return copyNames(axisParamNames)
+}
+
+// GlobalParamNames returns the names of all known bank 0 global parameters by number
+func GlobalParamNames() map[byte]string {
+	return copyNames(globalParamNames)
+}
+
+// lookupName finds a name in a catalog
+func lookupName(names map[byte]string, name string) (byte, bool) {
+	key := normalizeName(name)
+	for index, s := range names {
+		if normalizeName(s) == key {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// normalizeName reduces a name to its lower case letters and digits
+func normalizeName(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, c := range []byte(strings.ToLower(s)) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+// copyNames returns a copy of a catalog
+func copyNames(names map[byte]string) map[byte]string {
+	res := make(map[byte]string, len(names))
+	for k, v := range names {
+		res[k] = v
+	}
+	return res
+}
+
+// String formats the request for logs and errors, e.g. "MVP ABS motor=1 value=1000"
+func (r Request) String() string {
+	op := Opcode(r.Cmd)
+	switch r.Cmd {
+	case 1, 2:
+		return fmt.Sprintf("%s motor=%d velocity=%d", op, r.MotorBank, r.Value)
+	case 3:
+		return fmt.Sprintf("%s motor=%d", op, r.MotorBank)
+	case 4:
+		mode := map[byte]string{ABS: "ABS", REL: "REL", COORD: "COORD"}[r.Type]
+		if mode == "" {
+			mode = strconv.Itoa(int(r.Type))
+		}
+		return fmt.Sprintf("%s %s motor=%d value=%d", op, mode, r.MotorBank, r.Value)
+	case 5, 6, 7, 8, 34:
+		return fmt.Sprintf("%s %q motor=%d value=%d", op, AxisParamName(r.Type), r.MotorBank, r.Value)
+	case 9, 10, 11, 12, 35:
+		return fmt.Sprintf("%s %q bank=%d value=%d", op, GlobalParamName(r.Type, r.MotorBank), r.MotorBank, r.Value)
+	case 14, 15:
+		return fmt.Sprintf("%s port=%d bank=%d value=%d", op, r.Type, r.MotorBank, r.Value)
+	case 30, 31, 32:
+		return fmt.Sprintf("%s coordinate=%d motor=%d value=%d", op, r.Type, r.MotorBank, r.Value)
+	}
+	return fmt.Sprintf("%s type=%d motor/bank=%d value=%d", op, r.Type, r.MotorBank, r.Value)
+}
@@ -0,0 +1,90 @@
+package tmcl
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// ParamDef describes an axis parameter and how its raw value maps to the Go type T. Values
+// set are checked against the parameter table of the module like all SAP commands.
+type ParamDef[T any] struct {
+	Param AxisParam
+
+	decode func(raw int) T
+	encode func(value T) int
+}
+
+// Param is a typed axis parameter of one motor
+type Param[T any] struct {
+	def  ParamDef[T]
+	axis *Axis
+}
+
+// IntParam defines a parameter with a raw 32 bit value
+func IntParam(p AxisParam) ParamDef[int32] {
+	return ParamDef[int32]{
+		Param:  p,
+		decode: func(raw int) int32 { return int32(raw) },
+		encode: func(v int32) int { return int(v) },
+	}
+}
+
+// BoolParam defines a parameter which is either 0 or 1
+func BoolParam(p AxisParam) ParamDef[bool] {
+	return ParamDef[bool]{
+		Param:  p,
+		decode: func(raw int) bool { return raw != 0 },
+		encode: boolValue,
+	}
+}
+
+// EnumParam defines a parameter whose values are the constants of an integer type
+func EnumParam[T ~int](p AxisParam) ParamDef[T] {
+	return ParamDef[T]{
+		Param:  p,
+		decode: func(raw int) T { return T(raw) },
+		encode: func(v T) int { return int(v) },
+	}
+}
+
+// ScaledParam defines a parameter in physical units, the physical value being the raw value
+// multiplied by scale. Values are rounded to the nearest raw value when set.
+func ScaledParam(p AxisParam, scale float64) ParamDef[float64] {
+	return ParamDef[float64]{
+		Param:  p,
+		decode: func(raw int) float64 { return float64(raw) * scale },
+		encode: func(v float64) int { return int(math.Round(v / scale)) },
+	}
+}
+
+// On returns the parameter of the motor of an axis. It takes the place of a method of Axis,
+// which could not be generic.
+func (d ParamDef[T]) On(a *Axis) Param[T] {
+	return Param[T]{def: d, axis: a}
+}
+
+// Get reads the parameter
+func (p Param[T]) Get() (T, error) {
+	raw, err := p.axis.Board.GAP(byte(p.def.Param), p.axis.Motor)
+	if err != nil {
+		var zero T
+		return zero, errors.Wrap(err, p.def.Param.String())
+	}
+	return p.def.decode(raw), nil
+}
+
+// Set writes the parameter
+func (p Param[T]) Set(value T) error {
+	return errors.Wrap(p.axis.Board.SAP(byte(p.def.Param), p.axis.Motor, p.def.encode(value)), p.def.Param.String())
+}
+
+// Store stores the parameter in the EEPROM
+func (p Param[T]) Store() error {
+	return errors.Wrap(p.axis.Board.STAP(byte(p.def.Param), p.axis.Motor), p.def.Param.String())
+}
+
+// Restore restores the parameter from the EEPROM
+func (p Param[T]) Restore() error {
+	return errors.Wrap(p.axis.Board.RSAP(byte(p.def.Param), p.axis.Motor), p.def.Param.String())
+}
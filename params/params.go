@@ -0,0 +1,61 @@
+// Package params contains typed definitions of the axis parameters of the TMCM-351 firmware,
+// built on the parameter numbers of package tmcl. A definition is bound to the motor of an
+// axis with On, e.g. params.MaxCurrent.On(axis).Get() returns the current in A. Go methods
+// cannot have type parameters, so there is no axis.Param(params.MaxCurrent) form.
+package params
+
+import (
+	tmcl "github.com/raceresult/go-tmcl"
+)
+
+// RampMode is the value of axis parameter 138
+type RampMode int
+
+const (
+	PositionMode RampMode = 0
+	SoftMode     RampMode = 1
+	VelocityMode RampMode = 2
+)
+
+// MicrostepResolution is the value of axis parameter 140
+type MicrostepResolution int
+
+const (
+	FullStep     MicrostepResolution = 0
+	HalfStep     MicrostepResolution = 1
+	Microsteps4  MicrostepResolution = 2
+	Microsteps8  MicrostepResolution = 3
+	Microsteps16 MicrostepResolution = 4
+	Microsteps32 MicrostepResolution = 5
+	Microsteps64 MicrostepResolution = 6
+)
+
+var (
+	TargetPosition         = tmcl.IntParam(tmcl.TargetPosition)
+	ActualPosition         = tmcl.IntParam(tmcl.ActualPosition)
+	TargetSpeed            = tmcl.IntParam(tmcl.TargetSpeed)
+	ActualSpeed            = tmcl.IntParam(tmcl.ActualSpeed)
+	MaxPositioningSpeed    = tmcl.IntParam(tmcl.MaxSpeed)
+	MaxAcceleration        = tmcl.IntParam(tmcl.MaxAcceleration)
+	MaxCurrent             = tmcl.ScaledParam(tmcl.RunCurrent, 2.8/255)
+	StandbyCurrent         = tmcl.ScaledParam(tmcl.StandbyCurrent, 2.8/255)
+	TargetPositionReached  = tmcl.BoolParam(tmcl.TargetPositionReached)
+	ReferenceSwitchStatus  = tmcl.BoolParam(tmcl.ReferenceSwitch)
+	RightLimitSwitchStatus = tmcl.BoolParam(tmcl.RightLimitSwitch)
+	LeftLimitSwitchStatus  = tmcl.BoolParam(tmcl.LeftLimitSwitch)
+	RightLimitSwitchOff    = tmcl.BoolParam(tmcl.RightLimitDisable)
+	LeftLimitSwitchOff     = tmcl.BoolParam(tmcl.LeftLimitDisable)
+	MinimumSpeed           = tmcl.IntParam(tmcl.MinSpeed)
+	ActualAcceleration     = tmcl.IntParam(tmcl.ActualAcceleration)
+	Ramp                   = tmcl.EnumParam[RampMode](tmcl.RampMode)
+	Microsteps             = tmcl.EnumParam[MicrostepResolution](tmcl.MicrostepResolution)
+	SoftStop               = tmcl.BoolParam(tmcl.SoftStop)
+	RampDivisor            = tmcl.IntParam(tmcl.RampDivisor)
+	PulseDivisor           = tmcl.IntParam(tmcl.PulseDivisor)
+	FreewheelingDelay      = tmcl.ScaledParam(tmcl.FreewheelingDelay, 0.001)
+	StallThreshold         = tmcl.IntParam(tmcl.StallDetectionThreshold)
+	ActualLoadValue        = tmcl.IntParam(tmcl.ActualLoad)
+	EncoderPosition        = tmcl.IntParam(tmcl.EncoderPosition)
+	MaxEncoderDeviation    = tmcl.IntParam(tmcl.MaxEncoderDeviation)
+	PowerDownDelay         = tmcl.ScaledParam(tmcl.PowerDownDelay, 0.01)
+)
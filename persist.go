@@ -0,0 +1,115 @@
+package tmcl
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PersistSet are the parameters StoreAll and RestoreAll work on: axis parameters of every
+// motor and global parameters by bank. Bank 0 needs no storing, the module writes those
+// parameters to the EEPROM right away.
+type PersistSet struct {
+	AxisParams []byte
+	Globals    map[byte][]byte
+}
+
+// defaultPersistSet returns the configuration related axis parameters and all user variables
+func (q *TMCL) defaultPersistSet() *PersistSet {
+	vars := make([]byte, userVariables)
+	for i := range vars {
+		vars[i] = byte(i)
+	}
+	return &PersistSet{
+		AxisParams: q.configAxisParams(),
+		Globals:    map[byte][]byte{2: vars},
+	}
+}
+
+// StoreAll stores the parameters of the set (nil for the configuration related axis parameters
+// of all motors and the user variables) in the EEPROM. It continues after errors and returns
+// them all at the end. With the default set, parameters the module does not have are skipped.
+func (q *TMCL) StoreAll(set *PersistSet, progress ProgressFunc) error {
+	return q.persistAll(set, progress, func(index, motorOrBank byte, global bool) error {
+		if global {
+			_, err := q.STGP(index, motorOrBank)
+			return err
+		}
+		return q.STAP(index, motorOrBank)
+	})
+}
+
+// RestoreAll restores the parameters of the set from the EEPROM, see StoreAll
+func (q *TMCL) RestoreAll(set *PersistSet, progress ProgressFunc) error {
+	return q.persistAll(set, progress, func(index, motorOrBank byte, global bool) error {
+		if global {
+			_, err := q.RSGP(index, motorOrBank)
+			return err
+		}
+		return q.RSAP(index, motorOrBank)
+	})
+}
+
+// persistAll runs fn for all parameters of the set and aggregates the errors
+func (q *TMCL) persistAll(set *PersistSet, progress ProgressFunc, fn func(index, motorOrBank byte, global bool) error) error {
+	skipMissing := set == nil
+	if set == nil {
+		set = q.defaultPersistSet()
+	}
+	axes := q.AxisCount()
+	if axes == 0 {
+		axes = 1
+	}
+
+	total := axes * len(set.AxisParams)
+	for _, indices := range set.Globals {
+		total += len(indices)
+	}
+
+	var errs paramErrors
+	var done int
+	do := func(name string, index, motorOrBank byte, global bool) {
+		err := fn(index, motorOrBank, global)
+		if err != nil && !(skipMissing && errors.Is(err, ErrWrongType)) {
+			errs = append(errs, errors.WithMessage(err, name))
+		}
+		done++
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+
+	for motor := 0; motor < axes; motor++ {
+		for _, index := range set.AxisParams {
+			do("motor "+strconv.Itoa(motor)+" "+AxisParamName(index), index, byte(motor), false)
+		}
+	}
+	for _, bank := range sortedKeys(set.Globals) {
+		for _, index := range set.Globals[bank] {
+			do(GlobalParamName(index, bank), index, bank, true)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// paramErrors are the errors of several parameters
+type paramErrors []error
+
+// Error implements the error interface
+func (e paramErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of the parameters
+func (e paramErrors) Unwrap() []error {
+	return e
+}
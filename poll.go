@@ -0,0 +1,138 @@
+package tmcl
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PollParam identifies a value read by a FastPoller, Cmd being GAP (6), GGP (10) or GIO (15)
+type PollParam struct {
+	Cmd         byte
+	TypeNo      byte
+	MotorOrBank byte
+}
+
+// FastPoller repeatedly reads a small set of parameters as fast as the connection allows
+type FastPoller struct {
+	q        *TMCL
+	key      int
+	frames   []byte
+	interval time.Duration
+	rate     rateMeter
+}
+
+// NewFastPoller creates a poller for the given parameters. Interval is the minimum time
+// between the start of two poll cycles, 0 polls without pause.
+func (q *TMCL) NewFastPoller(params []PollParam, interval time.Duration) *FastPoller {
+	key, frames := q.encodePollParams(params)
+	return &FastPoller{
+		q:        q,
+		key:      key,
+		frames:   frames,
+		interval: interval,
+	}
+}
+
+// Run polls until the context is done or an error occurs. After every cycle fn is called
+// with the values in the order of the parameters, the slice is reused for the next cycle.
+func (p *FastPoller) Run(ctx context.Context, fn func(values []int)) error {
+	n := len(p.frames) / frameSize
+	values := make([]int, n)
+	statuses := make([]byte, n)
+
+	last := time.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := p.q.pollBulk(p.key, p.frames, values, statuses); err != nil {
+			return err
+		}
+		fn(values)
+
+		// wait for next cycle
+		if p.interval > 0 {
+			if d := p.interval - time.Since(last); d > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(d):
+				}
+			}
+		}
+
+		// measure rate
+		now := time.Now()
+		p.rate.update(now.Sub(last))
+		last = now
+	}
+}
+
+// Rate returns the measured number of poll cycles per second
+func (p *FastPoller) Rate() float64 {
+	return p.rate.get()
+}
+
+// encodePollParams encodes the requests of the parameters and returns them with the scheduler
+// key of the first one
+func (q *TMCL) encodePollParams(params []PollParam) (int, []byte) {
+	key := globalKey
+	frames := make([]byte, len(params)*frameSize)
+	for i, p := range params {
+		q.encodeFrame(frames[i*frameSize:(i+1)*frameSize], p.Cmd, p.TypeNo, p.MotorOrBank, 0)
+		if i == 0 {
+			key = schedKey(p.Cmd, p.MotorOrBank)
+		}
+	}
+	return key, frames
+}
+
+// pollBulk reads the values of encoded poll requests while holding the command lock only once,
+// a reply with error status failing the whole cycle
+func (q *TMCL) pollBulk(key int, frames []byte, values []int, statuses []byte) error {
+	if err := q.execBulk(key, frames, values, statuses, nil); err != nil {
+		return err
+	}
+	for i, status := range statuses {
+		if !statusSuccess(status) {
+			f := frames[i*frameSize : (i+1)*frameSize]
+			var err error
+			q.do(globalKey, func() {
+				err = q.statusError(status, Request{Cmd: f[1], Type: f[2], MotorBank: f[3]})
+			})
+			return err
+		}
+	}
+	return nil
+}
+
+// rateMeter is a moving average of the number of cycles per second
+type rateMeter struct {
+	mutex sync.Mutex
+	rate  float64
+}
+
+// update feeds the duration of one cycle into the moving average
+func (r *rateMeter) update(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	v := float64(time.Second) / float64(d)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.rate == 0 {
+		r.rate = v
+	} else {
+		r.rate = 0.9*r.rate + 0.1*v
+	}
+}
+
+// get returns the current rate
+func (r *rateMeter) get() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.rate
+}
@@ -0,0 +1,88 @@
+package tmcl
+
+import "github.com/raceresult/go-tmcl/program"
+
+// Program builds a TMCL standalone program for DownloadProgram, see package program
+type Program = program.Program
+
+// CalcOp is the arithmetic operation of CALC and CALCX
+type CalcOp = program.CalcOp
+
+// operations of CALC and CALCX, CalcSwap is only available for CALCX
+const (
+	CalcAdd  = program.CalcAdd
+	CalcSub  = program.CalcSub
+	CalcMul  = program.CalcMul
+	CalcDiv  = program.CalcDiv
+	CalcMod  = program.CalcMod
+	CalcAnd  = program.CalcAnd
+	CalcOr   = program.CalcOr
+	CalcXor  = program.CalcXor
+	CalcNot  = program.CalcNot
+	CalcLoad = program.CalcLoad
+	CalcSwap = program.CalcSwap
+)
+
+// Condition is the condition of a conditional jump
+type Condition = program.Condition
+
+// conditions of JC
+const (
+	IfZero          = program.IfZero
+	IfNotZero       = program.IfNotZero
+	IfEqual         = program.IfEqual
+	IfNotEqual      = program.IfNotEqual
+	IfGreater       = program.IfGreater
+	IfGreaterEqual  = program.IfGreaterEqual
+	IfLower         = program.IfLower
+	IfLowerEqual    = program.IfLowerEqual
+	IfTimeoutError  = program.IfTimeoutError
+	IfExternalAlarm = program.IfExternalAlarm
+	IfShutdownError = program.IfShutdownError
+)
+
+// Interrupt is the number of an interrupt of a standalone program
+type Interrupt = program.Interrupt
+
+// interrupts of the TMCM-351, the timer periods are set with global parameters 0 to 2 of bank 3
+const (
+	InterruptTimer0          = program.InterruptTimer0
+	InterruptTimer1          = program.InterruptTimer1
+	InterruptTimer2          = program.InterruptTimer2
+	InterruptTargetReached0  = program.InterruptTargetReached0
+	InterruptStallGuard0     = program.InterruptStallGuard0
+	InterruptDeviation0      = program.InterruptDeviation0
+	InterruptLeftStopSwitch0 = program.InterruptLeftStopSwitch0
+	InterruptInputChange0    = program.InterruptInputChange0
+	InterruptGlobal          = program.InterruptGlobal
+)
+
+// NewProgram creates an empty program
+func NewProgram() *Program {
+	return program.NewProgram()
+}
+
+// TargetReachedInterrupt returns the interrupt triggered when a motor reached its target
+func TargetReachedInterrupt(motor byte) Interrupt {
+	return program.TargetReachedInterrupt(motor)
+}
+
+// StallGuardInterrupt returns the interrupt triggered when stallGuard detected a stall
+func StallGuardInterrupt(motor byte) Interrupt {
+	return program.StallGuardInterrupt(motor)
+}
+
+// DeviationInterrupt returns the interrupt triggered on an encoder deviation of a motor
+func DeviationInterrupt(motor byte) Interrupt {
+	return program.DeviationInterrupt(motor)
+}
+
+// StopSwitchInterrupt returns the interrupt of the left or right stop switch of a motor
+func StopSwitchInterrupt(motor byte, right bool) Interrupt {
+	return program.StopSwitchInterrupt(motor, right)
+}
+
+// InputChangeInterrupt returns the interrupt triggered when a digital input changed
+func InputChangeInterrupt(input byte) Interrupt {
+	return program.InputChangeInterrupt(input)
+}
@@ -0,0 +1,361 @@
+// Package program builds TMCL standalone programs, which are stored in the program memory of
+// a module with tmcl.TMCL.DownloadProgram and run without a host.
+package program
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// Instruction is one TMCL command of a standalone program
+type Instruction = protocol.Instruction
+
+// ErrorFlag selects the error flags cleared by CLE
+type ErrorFlag byte
+
+// error flags of CLE
+const (
+	ErrorFlagAll       ErrorFlag = 0
+	ErrorFlagTimeout   ErrorFlag = 1
+	ErrorFlagAlarm     ErrorFlag = 2
+	ErrorFlagDeviation ErrorFlag = 3
+	ErrorFlagPosition  ErrorFlag = 4
+	ErrorFlagShutdown  ErrorFlag = 5
+)
+
+// CalcOp is the arithmetic operation of CALC and CALCX
+type CalcOp byte
+
+// operations of CALC and CALCX, CalcSwap is only available for CALCX
+const (
+	CalcAdd  CalcOp = 0
+	CalcSub  CalcOp = 1
+	CalcMul  CalcOp = 2
+	CalcDiv  CalcOp = 3
+	CalcMod  CalcOp = 4
+	CalcAnd  CalcOp = 5
+	CalcOr   CalcOp = 6
+	CalcXor  CalcOp = 7
+	CalcNot  CalcOp = 8
+	CalcLoad CalcOp = 9
+	CalcSwap CalcOp = 10
+)
+
+// Condition is the condition of a conditional jump
+type Condition byte
+
+// conditions of JC
+const (
+	IfZero          Condition = 0
+	IfNotZero       Condition = 1
+	IfEqual         Condition = 2
+	IfNotEqual      Condition = 3
+	IfGreater       Condition = 4
+	IfGreaterEqual  Condition = 5
+	IfLower         Condition = 6
+	IfLowerEqual    Condition = 7
+	IfTimeoutError  Condition = 8
+	IfExternalAlarm Condition = 9
+	IfShutdownError Condition = 12
+)
+
+// Interrupt is the number of an interrupt of a standalone program
+type Interrupt byte
+
+// interrupts of the TMCM-351, the timer periods are set with global parameters 0 to 2 of bank 3
+const (
+	InterruptTimer0          Interrupt = 0
+	InterruptTimer1          Interrupt = 1
+	InterruptTimer2          Interrupt = 2
+	InterruptTargetReached0  Interrupt = 3
+	InterruptStallGuard0     Interrupt = 15
+	InterruptDeviation0      Interrupt = 21
+	InterruptLeftStopSwitch0 Interrupt = 27
+	InterruptInputChange0    Interrupt = 39
+	InterruptGlobal          Interrupt = 255
+)
+
+// TargetReachedInterrupt returns the interrupt triggered when a motor reached its target
+func TargetReachedInterrupt(motor byte) Interrupt {
+	return InterruptTargetReached0 + Interrupt(motor)
+}
+
+// StallGuardInterrupt returns the interrupt triggered when stallGuard detected a stall
+func StallGuardInterrupt(motor byte) Interrupt {
+	return InterruptStallGuard0 + Interrupt(motor)
+}
+
+// DeviationInterrupt returns the interrupt triggered on an encoder deviation of a motor
+func DeviationInterrupt(motor byte) Interrupt {
+	return InterruptDeviation0 + Interrupt(motor)
+}
+
+// StopSwitchInterrupt returns the interrupt of the left or right stop switch of a motor
+func StopSwitchInterrupt(motor byte, right bool) Interrupt {
+	i := InterruptLeftStopSwitch0 + 2*Interrupt(motor)
+	if right {
+		i++
+	}
+	return i
+}
+
+// InputChangeInterrupt returns the interrupt triggered when a digital input changed
+func InputChangeInterrupt(input byte) Interrupt {
+	return InterruptInputChange0 + Interrupt(input)
+}
+
+// Program builds a TMCL standalone program for tmcl.TMCL.DownloadProgram. All methods return the
+// program, so calls can be chained. Errors, e.g. jumps to unknown labels, are reported by
+// Build.
+type Program struct {
+	instructions []Instruction
+	labels       map[string]int
+	jumps        map[int]string
+	loops        int
+	err          error
+}
+
+// NewProgram creates an empty program
+func NewProgram() *Program {
+	return &Program{labels: make(map[string]int), jumps: make(map[int]string)}
+}
+
+// add appends an instruction
+func (p *Program) add(cmd byte, typeNo byte, motorOrBank byte, value int) *Program {
+	p.instructions = append(p.instructions, Instruction{Cmd: cmd, Type: typeNo, MotorBank: motorOrBank, Value: value})
+	return p
+}
+
+// jump appends an instruction whose value is the address of a label
+func (p *Program) jump(cmd byte, typeNo byte, label string) *Program {
+	p.jumps[len(p.instructions)] = label
+	return p.add(cmd, typeNo, 0, 0)
+}
+
+// Label marks the address of the next instruction as jump target
+func (p *Program) Label(name string) *Program {
+	if _, ok := p.labels[name]; ok && p.err == nil {
+		p.err = errors.Errorf("label %q defined twice", name)
+	}
+	p.labels[name] = len(p.instructions)
+	return p
+}
+
+// ROR is rotate right
+func (p *Program) ROR(motor byte, velocity int) *Program {
+	return p.add(1, 0, motor, velocity)
+}
+
+// ROL is rotate left
+func (p *Program) ROL(motor byte, velocity int) *Program {
+	return p.add(2, 0, motor, velocity)
+}
+
+// MST is motor stop
+func (p *Program) MST(motor byte) *Program {
+	return p.add(3, 0, motor, 0)
+}
+
+// MVP is moving an axis, mode is ABS, REL or COORD
+func (p *Program) MVP(mode byte, motor byte, value int) *Program {
+	return p.add(4, mode, motor, value)
+}
+
+// SAP is set axis parameter
+func (p *Program) SAP(index byte, motor byte, value int) *Program {
+	return p.add(5, index, motor, value)
+}
+
+// GAP is get axis parameter, it loads the accumulator
+func (p *Program) GAP(index byte, motor byte) *Program {
+	return p.add(6, index, motor, 0)
+}
+
+// SGP is set global parameter
+func (p *Program) SGP(index byte, bank byte, value int) *Program {
+	return p.add(9, index, bank, value)
+}
+
+// GGP is get global parameter, it loads the accumulator
+func (p *Program) GGP(index byte, bank byte) *Program {
+	return p.add(10, index, bank, 0)
+}
+
+// RFSStart starts the reference search of a motor
+func (p *Program) RFSStart(motor byte) *Program {
+	return p.add(13, 0, motor, 0)
+}
+
+// SIO is set output
+func (p *Program) SIO(port byte, bank byte, value bool) *Program {
+	var b int
+	if value {
+		b = 1
+	}
+	return p.add(14, port, bank, b)
+}
+
+// GIO is get input or output, it loads the accumulator
+func (p *Program) GIO(port byte, bank byte) *Program {
+	return p.add(15, port, bank, 0)
+}
+
+// CALC calculates with the accumulator and a constant
+func (p *Program) CALC(op CalcOp, value int) *Program {
+	return p.add(19, byte(op), 0, value)
+}
+
+// COMP compares the accumulator with a constant, for a following JC
+func (p *Program) COMP(value int) *Program {
+	return p.add(20, 0, 0, value)
+}
+
+// JC jumps to a label if the condition is met
+func (p *Program) JC(cond Condition, label string) *Program {
+	return p.jump(21, byte(cond), label)
+}
+
+// JA jumps to a label
+func (p *Program) JA(label string) *Program {
+	return p.jump(22, 0, label)
+}
+
+// CSUB calls the subroutine at a label
+func (p *Program) CSUB(label string) *Program {
+	return p.jump(23, 0, label)
+}
+
+// RSUB returns from a subroutine
+func (p *Program) RSUB() *Program {
+	return p.add(24, 0, 0, 0)
+}
+
+// EI enables an interrupt, InterruptGlobal enables interrupts at all
+func (p *Program) EI(i Interrupt) *Program {
+	return p.add(25, byte(i), 0, 0)
+}
+
+// DI disables an interrupt, InterruptGlobal disables all interrupts
+func (p *Program) DI(i Interrupt) *Program {
+	return p.add(26, byte(i), 0, 0)
+}
+
+// WaitTicks waits for the given number of timer ticks of 10ms
+func (p *Program) WaitTicks(ticks int) *Program {
+	return p.add(27, 0, 0, ticks)
+}
+
+// WaitPosition waits until a motor reached its target position, with a timeout in ticks of
+// 10ms or 0 for no timeout
+func (p *Program) WaitPosition(motor byte, timeoutTicks int) *Program {
+	return p.add(27, 1, motor, timeoutTicks)
+}
+
+// WaitReferenceSwitch waits until the reference switch of a motor was triggered
+func (p *Program) WaitReferenceSwitch(motor byte, timeoutTicks int) *Program {
+	return p.add(27, 2, motor, timeoutTicks)
+}
+
+// WaitLimitSwitch waits until a limit switch of a motor was triggered
+func (p *Program) WaitLimitSwitch(motor byte, timeoutTicks int) *Program {
+	return p.add(27, 3, motor, timeoutTicks)
+}
+
+// WaitRFS waits until the reference search of a motor is completed
+func (p *Program) WaitRFS(motor byte, timeoutTicks int) *Program {
+	return p.add(27, 4, motor, timeoutTicks)
+}
+
+// Stop ends the program
+func (p *Program) Stop() *Program {
+	return p.add(28, 0, 0, 0)
+}
+
+// SCO sets a coordinate
+func (p *Program) SCO(coordinate byte, motor byte, position int) *Program {
+	return p.add(30, coordinate, motor, position)
+}
+
+// CCO captures the actual position of a motor as coordinate
+func (p *Program) CCO(coordinate byte, motor byte) *Program {
+	return p.add(32, coordinate, motor, 0)
+}
+
+// CALCX calculates with the accumulator and the X register
+func (p *Program) CALCX(op CalcOp) *Program {
+	return p.add(33, byte(op), 0, 0)
+}
+
+// AAP copies the accumulator to an axis parameter
+func (p *Program) AAP(index byte, motor byte) *Program {
+	return p.add(34, index, motor, 0)
+}
+
+// AGP copies the accumulator to a global parameter
+func (p *Program) AGP(index byte, bank byte) *Program {
+	return p.add(35, index, bank, 0)
+}
+
+// CLE clears error flags
+func (p *Program) CLE(flag ErrorFlag) *Program {
+	return p.add(36, byte(flag), 0, 0)
+}
+
+// VECT sets the handler of an interrupt to the routine at a label
+func (p *Program) VECT(i Interrupt, label string) *Program {
+	return p.jump(37, byte(i), label)
+}
+
+// RETI returns from an interrupt handler
+func (p *Program) RETI() *Program {
+	return p.add(38, 0, 0, 0)
+}
+
+// ACO copies the accumulator to a coordinate
+func (p *Program) ACO(coordinate byte, motor byte) *Program {
+	return p.add(39, coordinate, motor, 0)
+}
+
+// Loop repeats the instructions added by body forever
+func (p *Program) Loop(body func(p *Program)) *Program {
+	p.loops++
+	label := ".loop" + strconv.Itoa(p.loops)
+	p.Label(label)
+	body(p)
+	return p.JA(label)
+}
+
+// Subroutine adds a subroutine which can be called with CSUB(name). It should be placed
+// after Stop, so that it is not run without being called.
+func (p *Program) Subroutine(name string, body func(p *Program)) *Program {
+	p.Label(name)
+	body(p)
+	return p.RSUB()
+}
+
+// Handler adds an interrupt handler which is installed with VECT(i, name) and enabled with
+// EI(i) and EI(InterruptGlobal). Like a subroutine it should be placed after Stop.
+func (p *Program) Handler(name string, body func(p *Program)) *Program {
+	p.Label(name)
+	body(p)
+	return p.RETI()
+}
+
+// Build resolves the labels and returns the instructions
+func (p *Program) Build() ([]Instruction, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	program := make([]Instruction, len(p.instructions))
+	copy(program, p.instructions)
+	for i, label := range p.jumps {
+		addr, ok := p.labels[label]
+		if !ok {
+			return nil, errors.Errorf("instruction %d: unknown label %q", i, label)
+		}
+		program[i].Value = addr
+	}
+	return program, nil
+}
@@ -0,0 +1,28 @@
+package protocol
+
+import "encoding/binary"
+
+// Instruction is one TMCL command of a standalone program, as it is stored in the program
+// memory of a module
+type Instruction struct {
+	Cmd       byte
+	Type      byte
+	MotorBank byte
+	Value     int
+}
+
+// Encode writes the instruction as request telegram including checksum into bts, which must
+// be at least FrameSize bytes long
+func (in Instruction) Encode(bts []byte, address byte) {
+	EncodeRequest(bts, address, in.Cmd, in.Type, in.MotorBank, in.Value)
+}
+
+// DecodeInstruction reads an instruction from a request telegram, the checksum is not checked
+func DecodeInstruction(bts []byte) Instruction {
+	return Instruction{
+		Cmd:       bts[1],
+		Type:      bts[2],
+		MotorBank: bts[3],
+		Value:     int(int32(binary.BigEndian.Uint32(bts[4:8]))),
+	}
+}
@@ -0,0 +1,129 @@
+// Package protocol implements the binary TMCL telegram format used over RS232, RS485 and USB
+package protocol
+
+import (
+	"encoding/binary"
+)
+
+// FrameSize is the length of a request or reply telegram
+const FrameSize = 9
+
+// EncodeRequest writes a request telegram including checksum into bts, which must be at least
+// FrameSize bytes long
+func EncodeRequest(bts []byte, address byte, cmd byte, typeNo byte, motorOrBank byte, value int) {
+	bts[0] = address
+	bts[1] = cmd
+	bts[2] = typeNo
+	bts[3] = motorOrBank
+	binary.BigEndian.PutUint32(bts[4:8], uint32(value))
+	bts[8] = Checksum(bts[:8])
+}
+
+// ReplyValue returns the signed value of a reply telegram
+func ReplyValue(bts []byte) int {
+	return int(int32(binary.BigEndian.Uint32(bts[4:8])))
+}
+
+// ValidChecksum returns true if the last byte of the telegram is the checksum of the others
+func ValidChecksum(bts []byte) bool {
+	return bts[FrameSize-1] == Checksum(bts[:FrameSize-1])
+}
+
+// PlausibleReply returns true if bts looks like a reply telegram: the checksum is valid and
+// the status is a known status code. It is used to find the start of a reply after garbage.
+func PlausibleReply(bts []byte) bool {
+	if !ValidChecksum(bts) {
+		return false
+	}
+	_, ok := statusTexts[bts[2]]
+	return ok
+}
+
+// Checksum calculates the checksum by adding up all bytes
+func Checksum(bts []byte) byte {
+	var x byte
+	for _, b := range bts {
+		x += b
+	}
+	return x
+}
+
+// mnemonics are the names of the TMCL commands
+var mnemonics = map[byte]string{
+	1:   "ROR",
+	2:   "ROL",
+	3:   "MST",
+	4:   "MVP",
+	5:   "SAP",
+	6:   "GAP",
+	7:   "STAP",
+	8:   "RSAP",
+	9:   "SGP",
+	10:  "GGP",
+	11:  "STGP",
+	12:  "RSGP",
+	13:  "RFS",
+	14:  "SIO",
+	15:  "GIO",
+	19:  "CALC",
+	20:  "COMP",
+	21:  "JC",
+	22:  "JA",
+	23:  "CSUB",
+	24:  "RSUB",
+	25:  "EI",
+	26:  "DI",
+	27:  "WAIT",
+	28:  "STOP",
+	29:  "SAC",
+	30:  "SCO",
+	31:  "GCO",
+	32:  "CCO",
+	33:  "CALCX",
+	34:  "AAP",
+	35:  "AGP",
+	36:  "CLE",
+	37:  "VECT",
+	38:  "RETI",
+	39:  "ACO",
+	128: "STOP_APPLICATION",
+	129: "RUN_APPLICATION",
+	130: "STEP_APPLICATION",
+	131: "RESET_APPLICATION",
+	132: "START_DOWNLOAD",
+	133: "QUIT_DOWNLOAD",
+	134: "READ_MEMORY",
+	135: "GET_APPLICATION_STATUS",
+	136: "GET_FIRMWARE_VERSION",
+	137: "RESTORE_FACTORY_SETTINGS",
+	139: "ENTER_ASCII_MODE",
+}
+
+// Mnemonic returns the name of a command number
+func Mnemonic(cmd byte) (string, bool) {
+	s, ok := mnemonics[cmd]
+	return s, ok
+}
+
+// statusTexts are the status codes documented for all TMCL modules
+var statusTexts = map[byte]string{
+	1:   "wrong checksum",
+	2:   "invalid command",
+	3:   "wrong type",
+	4:   "invalid value",
+	5:   "configuration EEPROM locked",
+	6:   "command not available",
+	100: "successfully executed",
+	101: "command loaded into TMCL program EEPROM",
+}
+
+// StatusText returns the description of a status code
+func StatusText(status byte) (string, bool) {
+	s, ok := statusTexts[status]
+	return s, ok
+}
+
+// StatusSuccess returns true if the status code reports a successfully executed command
+func StatusSuccess(status byte) bool {
+	return status == 100 || status == 101
+}
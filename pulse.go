@@ -0,0 +1,179 @@
+package tmcl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// offRetries is the number of attempts to turn an output off after a pulse
+const offRetries = 3
+
+// OutputPulse is an output turned on for a limited time
+type OutputPulse struct {
+	q    *TMCL
+	port byte
+	bank byte
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// SetOutputFor turns an output of DigitalOutputBank on and turns it off again after the
+// duration. The output is turned off even if turning it on reported an error, since the
+// command may have been executed anyway.
+func (q *TMCL) SetOutputFor(port byte, d time.Duration) (*OutputPulse, error) {
+	return q.PulseOutput(port, DigitalOutputBank, d)
+}
+
+// PulseOutput is like SetOutputFor for an output of any bank. The timing is done by the host,
+// so the pulse is longer by the latency of the connection; a PulseTrigger is exact to
+// the tick.
+func (q *TMCL) PulseOutput(port byte, bank byte, d time.Duration) (*OutputPulse, error) {
+	p := &OutputPulse{q: q, port: port, bank: bank, done: make(chan struct{})}
+	if err := q.SIO(port, bank, true); err != nil {
+		p.off()
+		return nil, err
+	}
+	time.AfterFunc(d, p.off)
+	return p, nil
+}
+
+// Cancel turns the output off before the duration elapsed and returns the result
+func (p *OutputPulse) Cancel() error {
+	p.off()
+	return p.err
+}
+
+// Wait blocks until the output was turned off and returns the result
+func (p *OutputPulse) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// off turns the output off once, retrying on errors
+func (p *OutputPulse) off() {
+	p.once.Do(func() {
+		for i := 0; i < offRetries; i++ {
+			if p.err = p.q.SIO(p.port, p.bank, false); p.err == nil {
+				break
+			}
+		}
+		close(p.done)
+	})
+}
+
+// ToggleOutput inverts an output of DigitalOutputBank and returns the new state
+func (q *TMCL) ToggleOutput(port byte) (bool, error) {
+	v, err := q.GIO(port, DigitalOutputBank)
+	if err != nil {
+		return false, err
+	}
+	state := v == 0
+	return state, q.SIO(port, DigitalOutputBank, state)
+}
+
+// ToggleOutputEvery inverts an output every period until the context is done, e.g. to flash
+// a lamp, and turns it off at the end. It returns the error of the context or of SIO.
+func (q *TMCL) ToggleOutputEvery(ctx context.Context, port byte, bank byte, period time.Duration) error {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	state := true
+	for {
+		if err := q.SIOContext(ctx, port, bank, state); err != nil {
+			_ = q.SIO(port, bank, false)
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			if err := q.SIO(port, bank, false); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		state = !state
+	}
+}
+
+// PulseProgram returns a standalone program pulsing an output whenever the user variable is
+// set to the pulse duration in ticks of 10ms. The program sets the variable back to 0 when the
+// output was turned off again.
+func PulseProgram(port byte, bank byte, variable byte) ([]Instruction, error) {
+	return NewProgram().
+		Label("idle").
+		GGP(variable, 2).
+		COMP(0).
+		JC(IfEqual, "idle").
+		SIO(port, bank, true).
+		Label("on").
+		WaitTicks(1).
+		CALC(CalcSub, 1).
+		COMP(0).
+		JC(IfGreater, "on").
+		SIO(port, bank, false).
+		SGP(variable, 2, 0).
+		JA("idle").
+		Build()
+}
+
+// PulseTrigger pulses an output timed by the module, independent of the latency of the
+// connection, e.g. for triggering cameras
+type PulseTrigger struct {
+	q        *TMCL
+	variable byte
+}
+
+// InstallPulseTrigger downloads and starts the program of PulseProgram. The program takes
+// over the program memory of the module, replacing any standalone program stored there. It
+// is downloaded only here, so pulses do not write the EEPROM.
+func (q *TMCL) InstallPulseTrigger(port byte, bank byte, variable byte) (*PulseTrigger, error) {
+	program, err := PulseProgram(port, bank, variable)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.StopApplication(); err != nil {
+		return nil, err
+	}
+	if err := q.SGP(variable, 2, 0); err != nil {
+		return nil, err
+	}
+	if err := q.DownloadProgram(program, nil); err != nil {
+		return nil, err
+	}
+	if err := q.RunApplicationAt(0); err != nil {
+		return nil, err
+	}
+	return &PulseTrigger{q: q, variable: variable}, nil
+}
+
+// Pulse turns the output on for the duration, rounded to ticks of 10ms. It returns an error
+// if the previous pulse did not end yet.
+func (t *PulseTrigger) Pulse(d time.Duration) error {
+	ticks := int((d + tickDuration/2) / tickDuration)
+	if ticks < 1 {
+		return errors.Errorf("pulse duration %v shorter than a tick", d)
+	}
+	busy, err := t.Busy()
+	if err != nil {
+		return err
+	}
+	if busy {
+		return errors.New("previous pulse still running")
+	}
+	return t.q.SGP(t.variable, 2, ticks)
+}
+
+// Busy returns true while a pulse runs
+func (t *PulseTrigger) Busy() (bool, error) {
+	v, err := t.q.GGP(t.variable, 2)
+	return v != 0, err
+}
+
+// Stop stops the program, the output keeps its state
+func (t *PulseTrigger) Stop() error {
+	return t.q.StopApplication()
+}
@@ -0,0 +1,174 @@
+package tmcl
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// reconnectPollInterval is the pause between two attempts to reach a module at new settings
+const reconnectPollInterval = 500 * time.Millisecond
+
+// ChangeBaudRate writes the baud rate to the module (global parameter 65, stored in the EEPROM
+// by the module), reopens the serial port at the new baud rate and waits until the module
+// answers there. The module only switches after a power cycle, which has to happen while
+// ChangeBaudRate waits. If the context is done before, the port is switched back and, if the
+// module still answers, the old baud rate is written again, so that it does not come up at
+// a baud rate nobody expects. Only connections opened by NewTMCL can change the baud rate.
+func (q *TMCL) ChangeBaudRate(ctx context.Context, baud int) error {
+	code, err := baudRateCode(baud)
+	if err != nil {
+		return err
+	}
+	var oldBaud int
+	var serial bool
+	q.do(globalKey, func() {
+		oldBaud = q.baudRate
+		serial = q.ownsPort && q.dial == nil && q.ComPort != ""
+	})
+	if !serial {
+		return errors.New("baud rate can only be changed on serial ports opened by NewTMCL")
+	}
+	oldCode, err := baudRateCode(oldBaud)
+	if err != nil {
+		return err
+	}
+
+	// the settings have to reach the EEPROM
+	locked, err := q.EEPROMLocked()
+	if err != nil {
+		return err
+	}
+	if locked {
+		return errors.New("configuration EEPROM is locked")
+	}
+	if err := q.SGP(globalBaudRate, 0, code); err != nil {
+		return err
+	}
+	if v, err := q.GGP(globalBaudRate, 0); err != nil {
+		return err
+	} else if v != code {
+		return errors.Errorf("baud rate code %d written, %d read back", code, v)
+	}
+
+	// wait for the module at the new baud rate
+	q.switchBaudRate(baud)
+	if err := q.waitReachable(ctx); err == nil {
+		return nil
+	}
+
+	// not switched: back to the old baud rate and keep the module from switching later
+	q.switchBaudRate(oldBaud)
+	if err := q.SGP(globalBaudRate, 0, oldCode); err != nil {
+		return errors.Wrapf(err, "module did not answer at %d baud and the old baud rate could not be restored, it will use %d baud after the next power up", baud, baud)
+	}
+	return errors.Wrapf(ctx.Err(), "module did not answer at %d baud, old baud rate restored", baud)
+}
+
+// switchBaudRate closes the port, so that it is reopened at the given baud rate
+func (q *TMCL) switchBaudRate(baud int) {
+	q.do(globalKey, func() {
+		q.closePort()
+		q.baudRate = baud
+	})
+}
+
+// waitReachable reads the firmware version until the module answers or the context is done
+func (q *TMCL) waitReachable(ctx context.Context) error {
+	for {
+		if _, err := q.GetVersionContext(ctx); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(reconnectPollInterval):
+		}
+	}
+}
+
+// ChangeModuleAddress sets the serial address of the module (global parameter 66, stored in the
+// EEPROM by the module) and sends all further commands to the new address. The module
+// answers at the new address right away, which is verified by reading its firmware version.
+func (q *TMCL) ChangeModuleAddress(addr byte) error {
+	if addr == 0 {
+		return errors.New("module address 0 not permitted")
+	}
+	if err := q.setModuleAddress(addr); err != nil {
+		return err
+	}
+	if _, err := q.GetVersion(); err != nil {
+		return errors.Wrapf(err, "module not answering at new address %d", addr)
+	}
+	return nil
+}
+
+// setModuleAddress writes global parameter 66 and switches to the new address, accepting the
+// reply from either address
+func (q *TMCL) setModuleAddress(addr byte) error {
+	if err := q.checkUsable(); err != nil {
+		return err
+	}
+	var err error
+	q.do(globalKey, func() {
+		err = q.setModuleAddressLocked(addr)
+	})
+	return err
+}
+
+// setModuleAddressLocked implements setModuleAddress, must be called with the command lock
+// held
+func (q *TMCL) setModuleAddressLocked(addr byte) error {
+	req, err := q.checkRequest(Request{Cmd: 9, Type: globalSerialAddress, Value: int(addr)})
+	if err != nil {
+		return err
+	}
+	if err := q.OpenPort(); err != nil {
+		return err
+	}
+	q.encodeFrame(q.tx[:], req.Cmd, req.Type, req.MotorBank, req.Value)
+	if err := q.flushInput(); err != nil {
+		return err
+	}
+	sent := time.Now()
+	if err := q.writeFrame(q.tx[:]); err != nil {
+		return err
+	}
+
+	oldAddr := q.address
+	q.address = 0
+	value, status, err := q.readReply(context.Background(), req.Cmd, sent, q.currentTimeout())
+	if err == nil {
+		err = q.statusError(status, req)
+	}
+	q.logResult(req, value, err, sent)
+	if err != nil {
+		q.address = oldAddr
+		return err
+	}
+	q.address = addr
+	return nil
+}
+
+// ChangeModuleAddress changes the serial address of a module of the bus, see
+// TMCL.ChangeModuleAddress. The handle of the module is then returned by Module for the new
+// address.
+func (b *Bus) ChangeModuleAddress(oldAddr, newAddr byte) error {
+	b.mutex.Lock()
+	if _, ok := b.modules[newAddr]; ok {
+		b.mutex.Unlock()
+		return errors.Errorf("module address %d already in use", newAddr)
+	}
+	b.mutex.Unlock()
+
+	q := b.Module(oldAddr)
+	err := q.ChangeModuleAddress(newAddr)
+	if q.ModuleAddress() == newAddr {
+		b.mutex.Lock()
+		delete(b.modules, oldAddr)
+		b.modules[newAddr] = q
+		b.mutex.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package tmcl
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// MotorConfig binds a motor name to a connection and axis
+type MotorConfig struct {
+	// Port and BaudRate of the serial port the module is connected to
+	Port     string `json:"port"`
+	BaudRate int    `json:"baudRate"`
+
+	// Axis is the motor number on the module
+	Axis byte `json:"axis"`
+
+	// Mechanics converts mm, degrees and rpm to the units of the module
+	Mechanics Mechanics `json:"mechanics"`
+
+	// Limits are the safety limits of the motor
+	Limits Limits `json:"limits"`
+
+	// Profile are the axis parameters set when the motor is first used, by parameter index
+	Profile map[byte]int `json:"profile"`
+}
+
+// RegistryConfig is the configuration of a Registry, usually loaded from a file
+type RegistryConfig struct {
+	Motors map[string]MotorConfig `json:"motors"`
+}
+
+// NamedMotor is a motor of the registry, ready to use as Axis with the mechanics of its
+// configuration
+type NamedMotor struct {
+	*Axis
+	Name   string
+	Conn   *TMCL
+	Config MotorConfig
+}
+
+// Registry maps motor names to connections and axes, so that application code never
+// references port paths and motor numbers
+type Registry struct {
+	config RegistryConfig
+
+	mutex  sync.Mutex
+	conns  map[string]*TMCL
+	motors map[string]*NamedMotor
+}
+
+// NewRegistry creates a registry from a configuration
+func NewRegistry(cfg RegistryConfig) *Registry {
+	return &Registry{
+		config: cfg,
+		conns:  make(map[string]*TMCL),
+		motors: make(map[string]*NamedMotor),
+	}
+}
+
+// LoadRegistry creates a registry from a JSON configuration
+func LoadRegistry(r io.Reader) (*Registry, error) {
+	var cfg RegistryConfig
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return nil, errors.Wrap(err, "motor registry")
+	}
+	return NewRegistry(cfg), nil
+}
+
+// Names returns the names of all configured motors in alphabetical order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.config.Motors))
+	for name := range r.config.Motors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Motor returns the motor with the given name. On first use its limits and profile are applied.
+func (r *Registry) Motor(name string) (*NamedMotor, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if m, ok := r.motors[name]; ok {
+		return m, nil
+	}
+	cfg, ok := r.config.Motors[name]
+	if !ok {
+		return nil, errors.Errorf("motor %q not configured", name)
+	}
+
+	// one connection per port
+	conn, ok := r.conns[cfg.Port]
+	if !ok {
+		conn = NewTMCL(cfg.Port, cfg.BaudRate)
+		r.conns[cfg.Port] = conn
+	}
+
+	// apply limits first, so that the profile is checked against them
+	conn.SetLimits(cfg.Axis, cfg.Limits)
+	indices := make([]int, 0, len(cfg.Profile))
+	for index := range cfg.Profile {
+		indices = append(indices, int(index))
+	}
+	sort.Ints(indices)
+	for _, index := range indices {
+		if err := conn.SAP(byte(index), cfg.Axis, cfg.Profile[byte(index)]); err != nil {
+			return nil, errors.Wrapf(err, "motor %q", name)
+		}
+	}
+
+	m := &NamedMotor{Axis: conn.Axis(cfg.Axis, cfg.Mechanics), Name: name, Conn: conn, Config: cfg}
+	r.motors[name] = m
+	return m, nil
+}
+
+// Close closes all connections of the registry
+func (r *Registry) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, conn := range r.conns {
+		conn.ClosePort()
+	}
+}
@@ -0,0 +1,81 @@
+package tmcl
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// Reply is a reply telegram of the board
+type Reply struct {
+	HostAddress   byte
+	ModuleAddress byte
+	Status        byte
+	Cmd           byte
+	Value         int
+
+	// Raw is the telegram as received, including the checksum
+	Raw [frameSize]byte
+}
+
+// replyFromFrame splits a reply telegram into its fields
+func replyFromFrame(bts []byte) Reply {
+	r := Reply{
+		HostAddress:   bts[0],
+		ModuleAddress: bts[1],
+		Status:        bts[2],
+		Cmd:           bts[3],
+		Value:         protocol.ReplyValue(bts),
+	}
+	copy(r.Raw[:], bts)
+	return r
+}
+
+// EncodeFrame returns the request telegram including checksum as sent by a connection without
+// module address, see EncodeFrameAddress
+func EncodeFrame(req Request) [frameSize]byte {
+	return EncodeFrameAddress(0, req)
+}
+
+// EncodeFrameAddress returns the request telegram to the module with the given address
+// including checksum
+func EncodeFrameAddress(address byte, req Request) [frameSize]byte {
+	var bts [frameSize]byte
+	protocol.EncodeRequest(bts[:], address, req.Cmd, req.Type, req.MotorBank, req.Value)
+	return bts
+}
+
+// DecodeReply checks the length and checksum of a reply telegram and splits it into its
+// fields. An error status code is not an error of DecodeReply.
+func DecodeReply(bts []byte) (Reply, error) {
+	if len(bts) != frameSize {
+		return Reply{}, errors.Errorf("reply has %d bytes instead of %d", len(bts), frameSize)
+	}
+	if !protocol.ValidChecksum(bts) {
+		return Reply{}, ErrChecksum
+	}
+	return replyFromFrame(bts), nil
+}
+
+// ExecRaw calls a command on the board and returns the complete reply telegram, e.g. for
+// module specific commands or debugging. A reply with an error status is returned together
+// with its *Error.
+func (q *TMCL) ExecRaw(req Request) (Reply, error) {
+	return q.ExecRawContext(context.Background(), req)
+}
+
+// ExecRawContext is ExecRaw with a context
+func (q *TMCL) ExecRawContext(ctx context.Context, req Request) (Reply, error) {
+	q.KeepAlive()
+	var raw [frameSize]byte
+	var received bool
+	_, err := q.execCapture(ctx, req, func(bts []byte) {
+		copy(raw[:], bts)
+		received = true
+	})
+	if !received {
+		return Reply{}, err
+	}
+	return replyFromFrame(raw[:]), err
+}
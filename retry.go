@@ -0,0 +1,70 @@
+package tmcl
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy defines how commands are repeated after a timeout or a reply with invalid
+// checksum
+type RetryPolicy struct {
+	// Retries is the number of repetitions, 0 disables retrying
+	Retries int
+
+	// Backoff is the pause before the first repetition, it doubles with every further one
+	Backoff time.Duration
+
+	// MaxBackoff limits the pause between two repetitions, if not 0
+	MaxBackoff time.Duration
+
+	// AllCommands also repeats commands changing the state of the board, e.g. motion or SAP.
+	// By default only reads like GAP, GGP and GIO are repeated, because a command whose
+	// reply was lost may already have been executed.
+	AllCommands bool
+}
+
+// SetRetryPolicy sets when commands are repeated after transient errors on the line. Commands
+// writing to the EEPROM are repeated according to SetStoreRetry instead.
+func (q *TMCL) SetRetryPolicy(p RetryPolicy) {
+	q.do(globalKey, func() {
+		q.retry = p
+	})
+}
+
+// isLineError returns true for errors caused by a glitch on the line
+func isLineError(err error) bool {
+	cause := errors.Cause(err)
+	return cause == ErrTimeout || cause == ErrChecksum
+}
+
+// transactRetry sends a request, repeating it after line errors according to the retry policy,
+// must be called with the command lock held
+func (q *TMCL) transactRetry(ctx context.Context, req Request) (int, error) {
+	p := q.retry
+	if p.Retries <= 0 || req.NoReply || (!p.AllCommands && !isReadCommand(req.Cmd, req.Type)) {
+		return q.transact(ctx, req)
+	}
+
+	delay := p.Backoff
+	for i := 0; ; i++ {
+		v, err := q.transact(ctx, req)
+		if err == nil || i >= p.Retries || !isLineError(err) {
+			return v, err
+		}
+		q.logRetry(req, i+1, err)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return 0, ctx.Err()
+		case <-t.C:
+		}
+		delay *= 2
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			delay = p.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package tmcl_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/protocol"
+	"github.com/raceresult/go-tmcl/tmcltest"
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// glitchConn is a connection to a simulated module corrupting the checksum of the next
+// replies and counting the requests sent
+type glitchConn struct {
+	transport.Transport
+
+	mutex   sync.Mutex
+	bad     int
+	read    int
+	written int
+}
+
+// corrupt makes the checksum of the next n replies invalid
+func (c *glitchConn) corrupt(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.bad = n
+	c.written = 0
+}
+
+// Write counts the requests
+func (c *glitchConn) Write(b []byte) (int, error) {
+	c.mutex.Lock()
+	c.written += len(b) / protocol.FrameSize
+	c.mutex.Unlock()
+	return c.Transport.Write(b)
+}
+
+// Read flips the checksum byte of the replies to be corrupted
+func (c *glitchConn) Read(b []byte) (int, error) {
+	n, err := c.Transport.Read(b)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i := 0; i < n; i++ {
+		c.read++
+		if c.read%protocol.FrameSize == 0 && c.bad > 0 {
+			b[i] ^= 0xff
+			c.bad--
+		}
+	}
+	return n, err
+}
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  tmcl.RetryPolicy
+		write   bool
+		bad     int
+		err     error
+		written int
+	}{
+		{name: "disabled", bad: 1, err: tmcl.ErrChecksum, written: 1},
+		{name: "read repeated", policy: tmcl.RetryPolicy{Retries: 2}, bad: 1, written: 2},
+		{name: "retries used up", policy: tmcl.RetryPolicy{Retries: 2}, bad: 3, err: tmcl.ErrChecksum, written: 3},
+		{name: "clean", policy: tmcl.RetryPolicy{Retries: 2}, written: 1},
+		{name: "write not repeated", policy: tmcl.RetryPolicy{Retries: 2}, write: true, bad: 1, err: tmcl.ErrChecksum, written: 1},
+		{name: "write repeated", policy: tmcl.RetryPolicy{Retries: 2, AllCommands: true}, write: true, bad: 1, written: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tmcltest.NewModule()
+			m.SetAxisParam(0, 4, 123)
+			conn := &glitchConn{Transport: m.Conn()}
+			q := tmcl.NewWithPort(conn)
+			q.SetResyncLimit(0)
+			q.SetRetryPolicy(tt.policy)
+
+			// the first command also detects the module
+			if _, err := q.GAP(4, 0); err != nil {
+				t.Fatal(err)
+			}
+			conn.corrupt(tt.bad)
+
+			var err error
+			if tt.write {
+				err = q.SAP(4, 0, 123)
+			} else {
+				var v int
+				v, err = q.GAP(4, 0)
+				if err == nil && v != 123 {
+					t.Errorf("value %d, want 123", v)
+				}
+			}
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error %v, want %v", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if conn.written != tt.written {
+				t.Errorf("%d requests sent, want %d", conn.written, tt.written)
+			}
+		})
+	}
+}
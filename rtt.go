@@ -0,0 +1,102 @@
+package tmcl
+
+import (
+	"sync"
+	"time"
+)
+
+// rttSamples is the number of round trip times kept to derive the adaptive timeout
+const rttSamples = 128
+
+// minRTTSamples is the number of measurements needed before the adaptive timeout is used
+const minRTTSamples = 8
+
+// adaptiveTimeout configures how timeouts are derived from measured round trip times
+type adaptiveTimeout struct {
+	factor   float64
+	min, max time.Duration
+}
+
+// rttTracker keeps the most recent round trip times
+type rttTracker struct {
+	mutex   sync.Mutex
+	samples [rttSamples]time.Duration
+	sorted  [rttSamples]time.Duration
+	n, pos  int
+}
+
+// add records a round trip time
+func (t *rttTracker) add(d time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.samples[t.pos] = d
+	t.pos = (t.pos + 1) % rttSamples
+	if t.n < rttSamples {
+		t.n++
+	}
+}
+
+// percentile returns the p-th percentile (0..1) of the recorded round trip times and
+// the number of samples it is based on
+func (t *rttTracker) percentile(p float64) (time.Duration, int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.n == 0 {
+		return 0, 0
+	}
+
+	// insertion sort into the scratch buffer, avoids allocations on every reply
+	sorted := t.sorted[:t.n]
+	copy(sorted, t.samples[:t.n])
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && sorted[j] < sorted[j-1]; j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+	i := int(p * float64(len(sorted)-1))
+	return sorted[i], len(sorted)
+}
+
+// SetTimeout sets a fixed timeout for the reply of the board and turns off adaptive timeouts
+func (q *TMCL) SetTimeout(d time.Duration) {
+	q.do(globalKey, func() {
+		q.timeout = d
+		q.adaptive = nil
+	})
+}
+
+// SetAdaptiveTimeout derives the reply timeout from the measured round trip times: the 99th
+// percentile multiplied by factor, bounded by min and max. Until enough round trips have
+// been measured, max is used.
+func (q *TMCL) SetAdaptiveTimeout(factor float64, min, max time.Duration) {
+	q.do(globalKey, func() {
+		q.adaptive = &adaptiveTimeout{factor: factor, min: min, max: max}
+	})
+}
+
+// RoundTripTime returns the median and the 99th percentile of the recently measured round trip times
+func (q *TMCL) RoundTripTime() (median, p99 time.Duration) {
+	median, _ = q.rtt.percentile(0.5)
+	p99, _ = q.rtt.percentile(0.99)
+	return median, p99
+}
+
+// currentTimeout returns the timeout to wait for a reply, must be called with the command lock held
+func (q *TMCL) currentTimeout() time.Duration {
+	a := q.adaptive
+	if a == nil {
+		return q.timeout
+	}
+	p99, n := q.rtt.percentile(0.99)
+	if n < minRTTSamples {
+		return a.max
+	}
+	d := time.Duration(float64(p99) * a.factor)
+	if d < a.min {
+		d = a.min
+	}
+	if d > a.max {
+		d = a.max
+	}
+	return d
+}
@@ -0,0 +1,63 @@
+package tmcl
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// FoundModule is a module answering during a bus scan
+type FoundModule struct {
+	Address byte
+	Version Version
+
+	// Status is the status code if the module answered with an error, 0 otherwise
+	Status byte
+}
+
+// ScanBus probes the given module addresses (nil for 1 to 255) by reading the firmware version
+// and returns the modules that replied, also those replying with an error status. Addresses
+// not answering take the reply timeout each, so a full scan takes a while; the context can
+// cancel it, returning the modules found so far. Only errors of the context or of the port end
+// the scan.
+func (b *Bus) ScanBus(ctx context.Context, addresses []byte) ([]FoundModule, error) {
+	if addresses == nil {
+		addresses = make([]byte, 0, 255)
+		for addr := 1; addr <= 255; addr++ {
+			addresses = append(addresses, byte(addr))
+		}
+	}
+
+	var found []FoundModule
+	for _, addr := range addresses {
+		if err := ctx.Err(); err != nil {
+			return found, err
+		}
+
+		// probe with a handle of its own, so that no handles are left for missing modules
+		b.mutex.Lock()
+		q, ok := b.modules[addr]
+		if !ok {
+			q = b.newModule(addr, nil)
+		}
+		b.mutex.Unlock()
+
+		v, err := q.GetVersionContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return found, ctx.Err()
+			}
+			var boardErr *Error
+			if errors.As(err, &boardErr) {
+				found = append(found, FoundModule{Address: addr, Status: boardErr.Code})
+				continue
+			}
+			if !isLineError(err) {
+				return found, err
+			}
+			continue
+		}
+		found = append(found, FoundModule{Address: addr, Version: v})
+	}
+	return found, nil
+}
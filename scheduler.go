@@ -0,0 +1,306 @@
+package tmcl
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// globalKey is the scheduler key of commands not addressing a motor
+const globalKey = -1
+
+// urgentKey is the scheduler key of urgent commands, which skip the queues of all other keys
+const urgentKey = -2
+
+// numKeys is the number of distinct scheduler keys (all motor bytes plus globalKey)
+const numKeys = 257
+
+// jobBuffer is the number of commands pushed onto the channel before the writer takes them
+const jobBuffer = 64
+
+// scheduler runs the commands of a port on a single writer goroutine. Commands are pushed
+// onto a channel, the writer queues them per motor and serves the queues round robin, so a
+// busy motor cannot starve the others, or in FIFO order if enabled. Motors with a higher
+// priority are always served first. Urgent commands skip all queues. The writer is started
+// with the first command and ends when no command is left.
+type scheduler struct {
+	jobs chan *job
+
+	mutex    sync.Mutex
+	running  bool
+	pending  int
+	fifo     bool
+	seq      uint64
+	priority map[int]int
+
+	waiting    int
+	maxWaiting int
+	canceled   uint64
+
+	// queues of the writer, only accessed by the writer goroutine
+	last   int
+	queues map[int][]*job
+	urgent []*job
+}
+
+// job is a command pushed onto the channel, done is closed when the writer ran it
+type job struct {
+	key   int
+	seq   uint64
+	fn    func()
+	state int32
+	done  chan struct{}
+	panic interface{}
+}
+
+// states of a job
+const (
+	jobQueued int32 = iota
+	jobStarted
+	jobCanceled
+)
+
+// newScheduler creates a scheduler, the writer is started with the first command
+func newScheduler() *scheduler {
+	return &scheduler{jobs: make(chan *job, jobBuffer)}
+}
+
+// run pushes fn onto the channel and waits until the writer ran it. If the context is done
+// before the writer started fn, it is dropped and the error of the context returned.
+func (s *scheduler) run(ctx context.Context, key int, fn func()) error {
+	j := s.push(key, fn)
+	select {
+	case s.jobs <- j:
+	case <-ctx.Done():
+		s.cancel(j)
+		return ctx.Err()
+	}
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		if s.cancel(j) {
+			return ctx.Err()
+		}
+		<-j.done
+	}
+	if j.panic != nil {
+		panic(j.panic)
+	}
+	return nil
+}
+
+// push creates a job, counts it as waiting and starts the writer if it is not running
+func (s *scheduler) push(key int, fn func()) *job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.seq++
+	s.pending++
+	s.waiting++
+	if s.waiting > s.maxWaiting {
+		s.maxWaiting = s.waiting
+	}
+	if !s.running {
+		s.running = true
+		go s.serve()
+	}
+	return &job{key: key, seq: s.seq, fn: fn, done: make(chan struct{})}
+}
+
+// cancel drops a job not started yet, returning false if the writer started it already
+func (s *scheduler) cancel(j *job) bool {
+	if !atomic.CompareAndSwapInt32(&j.state, jobQueued, jobCanceled) {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending--
+	s.waiting--
+	s.canceled++
+	return true
+}
+
+// serve is the writer goroutine, it runs the queued jobs one by one until none is pending
+func (s *scheduler) serve() {
+	for {
+		s.receive()
+		j := s.next()
+		if j == nil {
+			s.mutex.Lock()
+			if s.pending == 0 {
+				s.running = false
+				s.mutex.Unlock()
+				return
+			}
+			s.mutex.Unlock()
+			s.enqueue(<-s.jobs)
+			continue
+		}
+		if !atomic.CompareAndSwapInt32(&j.state, jobQueued, jobStarted) {
+			// canceled while waiting
+			continue
+		}
+
+		s.mutex.Lock()
+		s.waiting--
+		s.mutex.Unlock()
+		runJob(j)
+		s.mutex.Lock()
+		s.pending--
+		s.mutex.Unlock()
+	}
+}
+
+// runJob runs the function of a job, a panic is passed on to the goroutine waiting for it
+func runJob(j *job) {
+	defer close(j.done)
+	defer func() { j.panic = recover() }()
+	j.fn()
+}
+
+// receive moves the jobs pushed onto the channel into the queues
+func (s *scheduler) receive() {
+	for {
+		select {
+		case j := <-s.jobs:
+			s.enqueue(j)
+		default:
+			return
+		}
+	}
+}
+
+// enqueue adds a job to the queue of its key
+func (s *scheduler) enqueue(j *job) {
+	if j.key == urgentKey {
+		s.urgent = append(s.urgent, j)
+		return
+	}
+	if s.queues == nil {
+		s.queues = make(map[int][]*job)
+	}
+	s.queues[j.key] = append(s.queues[j.key], j)
+}
+
+// next removes the job to be run next from the queues, urgent ones first, and returns nil if
+// none is queued
+func (s *scheduler) next() *job {
+	if len(s.urgent) != 0 {
+		j := s.urgent[0]
+		s.urgent = s.urgent[1:]
+		return j
+	}
+
+	key, ok := s.nextKey()
+	if !ok {
+		return nil
+	}
+	queue := s.queues[key]
+	j := queue[0]
+	if len(queue) == 1 {
+		delete(s.queues, key)
+	} else {
+		s.queues[key] = queue[1:]
+	}
+	s.last = key
+	return j
+}
+
+// nextKey returns the key to be served next: the highest priority wins, within the same
+// priority the oldest command in FIFO mode, otherwise the first key after the one served last
+func (s *scheduler) nextKey() (int, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var found bool
+	var bestKey, bestPrio, bestDist int
+	for key, queue := range s.queues {
+		prio := s.priority[key]
+		dist := (key - s.last - 1 + 2*numKeys) % numKeys
+		if s.fifo {
+			dist = int(queue[0].seq)
+		}
+		if !found || prio > bestPrio || (prio == bestPrio && dist < bestDist) {
+			found = true
+			bestKey, bestPrio, bestDist = key, prio, dist
+		}
+	}
+	return bestKey, found
+}
+
+// setFIFO switches between FIFO and round robin order
+func (s *scheduler) setFIFO(fifo bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fifo = fifo
+}
+
+// queueStats returns the number of waiting commands, the maximum so far and the number of
+// commands canceled while waiting
+func (s *scheduler) queueStats() (waiting, maxWaiting int, canceled uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.waiting, s.maxWaiting, s.canceled
+}
+
+// resetQueueStats resets the maximum queue length and the canceled commands
+func (s *scheduler) resetQueueStats() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxWaiting = s.waiting
+	s.canceled = 0
+}
+
+// setPriority sets the priority of a key, 0 being the default
+func (s *scheduler) setPriority(key int, priority int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.priority == nil {
+		s.priority = make(map[int]int)
+	}
+	if priority == 0 {
+		delete(s.priority, key)
+	} else {
+		s.priority[key] = priority
+	}
+}
+
+// schedKey returns the scheduler key of a command
+func schedKey(cmd byte, motorOrBank byte) int {
+	switch cmd {
+	case 3:
+		// stopping a motor must not wait behind other commands
+		return urgentKey
+	case 1, 2, 4, 5, 6, 7, 8, 13, 34:
+		return int(motorOrBank)
+	}
+	return globalKey
+}
+
+// run runs fn on the writer, within the transaction if q is the handle of one
+func (q *TMCL) run(ctx context.Context, key int, fn func()) error {
+	if q.txn != nil {
+		if ok, err := q.txn.run(ctx, fn); ok {
+			return err
+		}
+	}
+	return q.cmdLock.run(ctx, key, fn)
+}
+
+// do runs fn on the writer and waits until it ran, for changes of settings used by the writer
+// and for sequences of transfers which must not be interleaved with other commands
+func (q *TMCL) do(key int, fn func()) {
+	_ = q.run(context.Background(), key, fn)
+}
+
+// SetMotorPriority sets the priority used when commands for several motors are waiting for
+// the bus. Commands of motors with higher priority are sent first, motors with equal
+// priority (default 0) take turns.
+func (q *TMCL) SetMotorPriority(motor byte, priority int) {
+	q.cmdLock.setPriority(int(motor), priority)
+}
+
+// SetFIFO makes waiting commands be sent in the order they were issued, regardless of the
+// motor they address. Motor priorities and urgent commands still take precedence.
+func (q *TMCL) SetFIFO(fifo bool) {
+	q.cmdLock.setFIFO(fifo)
+}
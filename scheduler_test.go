@@ -0,0 +1,264 @@
+package tmcl_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/tmcltest"
+)
+
+// recorder is a Logger keeping the requests in the order they were sent
+type recorder struct {
+	mutex sync.Mutex
+	reqs  []tmcl.Request
+}
+
+func (r *recorder) Command(req tmcl.Request, value int, d time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reqs = append(r.reqs, req)
+}
+
+func (r *recorder) Error(req tmcl.Request, err error, d time.Duration) {}
+
+func (r *recorder) Timeout(req tmcl.Request, d time.Duration) {}
+
+func (r *recorder) Retry(req tmcl.Request, attempt int, err error) {}
+
+// values returns the values of the SAP requests sent
+func (r *recorder) values() []int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var values []int
+	for _, req := range r.reqs {
+		if req.Cmd == 5 {
+			values = append(values, req.Value)
+		}
+	}
+	return values
+}
+
+// queued is a command waiting for the bus in a test
+type queued struct {
+	motor  byte
+	urgent bool
+}
+
+// waitQueued waits until n commands were pushed onto the channel of the writer
+func waitQueued(t *testing.T, q *tmcl.TMCL, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for tmcl.QueuedCommands(q) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d commands queued instead of %d", tmcl.QueuedCommands(q), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// queue issues the commands from goroutines of their own while a transaction holds the
+// bus, each one as SAP with its index as value, and returns the errors once all were sent
+func queue(t *testing.T, q *tmcl.TMCL, cmds []queued) []error {
+	t.Helper()
+	errs := make([]error, len(cmds))
+	var wg sync.WaitGroup
+	err := q.WithTransaction(func(tx *tmcl.TMCL) error {
+		for i, c := range cmds {
+			wg.Add(1)
+			go func(i int, c queued) {
+				defer wg.Done()
+				_, errs[i] = q.ExecRequest(tmcl.Request{Cmd: 5, Type: 4, MotorBank: c.motor, Value: i, Urgent: c.urgent})
+			}(i, c)
+			waitQueued(t, q, i+1)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		fifo     bool
+		priority map[byte]int
+		cmds     []queued
+		want     []int
+	}{
+		{
+			name: "round robin",
+			cmds: []queued{{motor: 1}, {motor: 1}, {motor: 1}, {motor: 2}, {motor: 2}, {motor: 0}},
+			want: []int{5, 0, 3, 1, 4, 2},
+		},
+		{
+			name: "busy motor does not starve others",
+			cmds: []queued{{motor: 2}, {motor: 2}, {motor: 2}, {motor: 2}, {motor: 1}},
+			want: []int{4, 0, 1, 2, 3},
+		},
+		{
+			name:     "priority",
+			priority: map[byte]int{2: 1},
+			cmds:     []queued{{motor: 1}, {motor: 1}, {motor: 2}, {motor: 2}},
+			want:     []int{2, 3, 0, 1},
+		},
+		{
+			name:     "negative priority",
+			priority: map[byte]int{0: -1},
+			cmds:     []queued{{motor: 0}, {motor: 2}, {motor: 1}},
+			want:     []int{2, 1, 0},
+		},
+		{
+			name: "urgent lane",
+			cmds: []queued{{motor: 1}, {motor: 2}, {motor: 1, urgent: true}, {motor: 0, urgent: true}},
+			want: []int{2, 3, 0, 1},
+		},
+		{
+			name:     "urgent before priority",
+			priority: map[byte]int{1: 5},
+			cmds:     []queued{{motor: 1}, {motor: 2, urgent: true}},
+			want:     []int{1, 0},
+		},
+		{
+			name: "round robin without fifo",
+			cmds: []queued{{motor: 2}, {motor: 1}, {motor: 2}, {motor: 1}},
+			want: []int{1, 0, 3, 2},
+		},
+		{
+			name: "fifo",
+			fifo: true,
+			cmds: []queued{{motor: 2}, {motor: 1}, {motor: 2}, {motor: 1}},
+			want: []int{0, 1, 2, 3},
+		},
+		{
+			name:     "fifo with priority",
+			fifo:     true,
+			priority: map[byte]int{1: 1},
+			cmds:     []queued{{motor: 2}, {motor: 1}, {motor: 2}, {motor: 1}},
+			want:     []int{1, 3, 0, 2},
+		},
+		{
+			name: "fifo with urgent",
+			fifo: true,
+			cmds: []queued{{motor: 2}, {motor: 1}, {motor: 2, urgent: true}},
+			want: []int{2, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			q := tmcltest.NewModule().Connect(tmcl.WithLogger(rec))
+			q.SetFIFO(tt.fifo)
+			for motor, prio := range tt.priority {
+				q.SetMotorPriority(motor, prio)
+			}
+
+			for i, err := range queue(t, q, tt.cmds) {
+				if err != nil {
+					t.Errorf("command %d: %v", i, err)
+				}
+			}
+			if got := rec.values(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order %v, want %v", got, tt.want)
+			}
+			if s := q.Stats(); s.QueueLength != 0 || s.MaxQueueLength < len(tt.cmds) {
+				t.Errorf("queue length %d, max %d", s.QueueLength, s.MaxQueueLength)
+			}
+		})
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	rec := &recorder{}
+	q := tmcltest.NewModule().Connect(tmcl.WithLogger(rec))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	err := q.WithTransaction(func(tx *tmcl.TMCL) error {
+		go func() {
+			_, err := q.ExecRequestContext(ctx, tmcl.Request{Cmd: 5, Type: 4, Value: 1})
+			done <- err
+		}()
+		waitQueued(t, q, 1)
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("error %v, want context.Canceled", err)
+		}
+		return tx.SAP(4, 0, 2)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SAP(4, 0, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rec.values(); !reflect.DeepEqual(got, []int{2, 3}) {
+		t.Errorf("sent %v, the canceled command must not be sent", got)
+	}
+	if s := q.Stats(); s.CanceledWaiting != 1 || s.QueueLength != 0 {
+		t.Errorf("canceled %d, queue length %d", s.CanceledWaiting, s.QueueLength)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	rec := &recorder{}
+	q := tmcltest.NewModule().Connect(tmcl.WithLogger(rec))
+
+	done := make(chan error, 1)
+	err := q.WithTransaction(func(tx *tmcl.TMCL) error {
+		go func() { done <- q.SAP(4, 1, 100) }()
+		waitQueued(t, q, 1)
+		for i := 0; i < 3; i++ {
+			if err := tx.SAP(4, 0, i); err != nil {
+				return err
+			}
+		}
+		return tx.WithTransaction(func(nested *tmcl.TMCL) error {
+			// the settings use the bus as well
+			nested.SetResyncLimit(4)
+			return nested.SAP(4, 0, 3)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.values(); !reflect.DeepEqual(got, []int{0, 1, 2, 3, 100}) {
+		t.Errorf("order %v, the transaction must not be interleaved", got)
+	}
+}
+
+func TestSchedulerConcurrent(t *testing.T) {
+	s := tmcltest.NewSimulator()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(motor byte) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := s.SAP(4, motor, i); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := s.GAP(4, motor); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(byte(g % 3))
+	}
+	wg.Wait()
+	if s.Stats().QueueLength != 0 {
+		t.Errorf("queue length %d after all commands", s.Stats().QueueLength)
+	}
+}
@@ -0,0 +1,87 @@
+package sessionlog
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// Print writes the records of a session log as text, one line per record with time,
+// direction and data, followed by the decoded telegrams. Received data is joined to
+// telegrams across records.
+func Print(w io.Writer, r io.Reader) error {
+	lr, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+	var rx, lastSent []byte
+	for {
+		rec, err := lr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s %s % x\n", rec.Time.Format("15:04:05.000000"), rec.Direction, rec.Data); err != nil {
+			return err
+		}
+
+		var frames [][]byte
+		if rec.Direction == Sent {
+			rx = nil
+			lastSent = rec.Data
+			if len(rec.Data) == protocol.FrameSize {
+				frames = append(frames, rec.Data)
+			}
+		} else {
+			rx = append(rx, rec.Data...)
+			for len(rx) >= protocol.FrameSize {
+				frames = append(frames, rx[:protocol.FrameSize])
+				rx = rx[protocol.FrameSize:]
+			}
+		}
+		for _, f := range frames {
+			text := describe(rec.Direction, f)
+			if rec.Direction == Received && isVersionRequest(lastSent) {
+				text = fmt.Sprintf("version string to %d: %q", f[0], f[1:])
+			}
+			if _, err := fmt.Fprintf(w, "\t%s\n", text); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// describe decodes a telegram
+func describe(d Direction, f []byte) string {
+	checksum := ""
+	if !protocol.ValidChecksum(f) {
+		checksum = " (invalid checksum)"
+	}
+	if d == Sent {
+		in := protocol.DecodeInstruction(f)
+		return fmt.Sprintf("request to %d: %s type=%d motor/bank=%d value=%d%s", f[0], mnemonic(in.Cmd), in.Type, in.MotorBank, in.Value, checksum)
+	}
+	status, ok := protocol.StatusText(f[2])
+	if !ok {
+		status = "unknown status"
+	}
+	return fmt.Sprintf("reply from %d to %d: %s status=%d (%s) value=%d%s", f[1], f[0], mnemonic(f[3]), f[2], status, protocol.ReplyValue(f), checksum)
+}
+
+// mnemonic returns the name of a command
+func mnemonic(cmd byte) string {
+	if s, ok := protocol.Mnemonic(cmd); ok {
+		return s
+	}
+	return "CMD" + strconv.Itoa(int(cmd))
+}
+
+// isVersionRequest returns true for a request of the version string, whose reply is no
+// regular telegram
+func isVersionRequest(req []byte) bool {
+	return len(req) == protocol.FrameSize && req[1] == 136 && req[2] == 0
+}
@@ -0,0 +1,177 @@
+// Package sessionlog implements a compact binary log of all data exchanged with TMCL modules,
+// with timestamps, for the analysis of failures in the field
+//
+// A log starts with the 8 byte magic "TMCLLOG1" and the start time in unix nanoseconds as
+// big endian int64. Each record follows as the time since the previous one in microseconds
+// as uvarint, the direction byte, the data length byte and the data.
+package sessionlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// magic identifies a session log
+const magic = "TMCLLOG1"
+
+// ErrFormat is returned when reading data that is no session log
+var ErrFormat = errors.New("invalid session log")
+
+// Direction tells whether data was sent or received
+type Direction byte
+
+const (
+	Sent     Direction = 0
+	Received Direction = 1
+)
+
+// String returns > for sent and < for received data
+func (d Direction) String() string {
+	if d == Received {
+		return "<"
+	}
+	return ">"
+}
+
+// Record is data sent or received at once
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	Data      []byte
+}
+
+// Writer writes records to a session log, it is safe for concurrent use
+type Writer struct {
+	mutex sync.Mutex
+	w     io.Writer
+	last  time.Time
+	err   error
+}
+
+// NewWriter returns a writer to w, the header is written with the first record
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Err returns the first error writing the log, after which no more records are written
+func (q *Writer) Err() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.err
+}
+
+// Write writes a record with the current time, data longer than 255 bytes is split up
+func (q *Writer) Write(direction Direction, data []byte) error {
+	return q.WriteRecord(Record{Time: time.Now(), Direction: direction, Data: data})
+}
+
+// WriteRecord writes a record, data longer than 255 bytes is split up
+func (q *Writer) WriteRecord(r Record) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.err != nil {
+		return q.err
+	}
+
+	var buf bytes.Buffer
+	if q.last.IsZero() {
+		buf.WriteString(magic)
+		_ = binary.Write(&buf, binary.BigEndian, r.Time.UnixNano())
+		q.last = r.Time
+	}
+	data := r.Data
+	for first := true; first || len(data) > 0; first = false {
+		n := len(data)
+		if n > 255 {
+			n = 255
+		}
+		delta := r.Time.Sub(q.last).Microseconds()
+		if delta < 0 {
+			delta = 0
+		}
+		var tmp [binary.MaxVarintLen64]byte
+		buf.Write(tmp[:binary.PutUvarint(tmp[:], uint64(delta))])
+		buf.WriteByte(byte(r.Direction))
+		buf.WriteByte(byte(n))
+		buf.Write(data[:n])
+		data = data[n:]
+		q.last = q.last.Add(time.Duration(delta) * time.Microsecond)
+	}
+	_, q.err = q.w.Write(buf.Bytes())
+	return q.err
+}
+
+// Reader reads the records of a session log
+type Reader struct {
+	r    *bufio.Reader
+	last time.Time
+}
+
+// NewReader checks the header of a session log and returns a reader of its records
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var header [len(magic) + 8]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrFormat
+		}
+		return nil, err
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, ErrFormat
+	}
+	start := int64(binary.BigEndian.Uint64(header[len(magic):]))
+	return &Reader{r: br, last: time.Unix(0, start)}, nil
+}
+
+// Next returns the next record, io.EOF at the end of the log
+func (q *Reader) Next() (Record, error) {
+	delta, err := binary.ReadUvarint(q.r)
+	if err != nil {
+		return Record{}, err
+	}
+	var head [2]byte
+	if _, err := io.ReadFull(q.r, head[:]); err != nil {
+		return Record{}, errors.Wrap(ErrFormat, "truncated record")
+	}
+	r := Record{Direction: Direction(head[0]), Data: make([]byte, head[1])}
+	if r.Direction != Sent && r.Direction != Received {
+		return Record{}, errors.Wrapf(ErrFormat, "invalid direction %d", head[0])
+	}
+	if _, err := io.ReadFull(q.r, r.Data); err != nil {
+		return Record{}, errors.Wrap(ErrFormat, "truncated record")
+	}
+	q.last = q.last.Add(time.Duration(delta) * time.Microsecond)
+	r.Time = q.last
+	return r, nil
+}
+
+// NewReplay returns a transport replaying the session log, see transport.NewReplay
+func NewReplay(r io.Reader) (*transport.Replay, error) {
+	lr, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	var rec bytes.Buffer
+	for {
+		r, err := lr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Data) > 0 {
+			fmt.Fprintf(&rec, "%s %x\n", r.Direction, r.Data)
+		}
+	}
+	return transport.NewReplay(&rec)
+}
@@ -0,0 +1,135 @@
+package sim
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// conn is an in-process connection to a module, the requests are handled while writing
+// and the replies are buffered until read
+type conn struct {
+	module *Module
+
+	mutex    sync.Mutex
+	in       []byte
+	out      []byte
+	deadline time.Time
+	closed   bool
+	// changed is closed and replaced whenever a blocked Read has to check again
+	changed chan struct{}
+}
+
+// newConn returns a connection to the module
+func newConn(m *Module) *conn {
+	return &conn{module: m, changed: make(chan struct{})}
+}
+
+// Write passes complete request telegrams to the module
+func (q *conn) Write(b []byte) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return 0, os.ErrClosed
+	}
+	q.in = append(q.in, b...)
+	for len(q.in) >= protocol.FrameSize {
+		if reply := q.module.handle(q.in[:protocol.FrameSize]); reply != nil {
+			q.out = append(q.out, reply...)
+		}
+		q.in = q.in[protocol.FrameSize:]
+	}
+	q.notify()
+	return len(b), nil
+}
+
+// Read returns buffered replies, waiting until there are some or the deadline expired
+func (q *conn) Read(b []byte) (int, error) {
+	for {
+		q.mutex.Lock()
+		if len(q.out) > 0 {
+			n := copy(b, q.out)
+			q.out = q.out[n:]
+			q.mutex.Unlock()
+			return n, nil
+		}
+		if q.closed {
+			q.mutex.Unlock()
+			return 0, io.EOF
+		}
+		deadline, changed := q.deadline, q.changed
+		q.mutex.Unlock()
+
+		if deadline.IsZero() {
+			<-changed
+			continue
+		}
+		d := time.Until(deadline)
+		if d <= 0 {
+			return 0, os.ErrDeadlineExceeded
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-changed:
+		case <-t.C:
+		}
+		t.Stop()
+	}
+}
+
+// Close ends the connection, a blocked Read returns io.EOF
+func (q *conn) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	q.notify()
+	return nil
+}
+
+// Flush discards replies not read yet and an incomplete request
+func (q *conn) Flush() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.in = nil
+	q.out = nil
+	return nil
+}
+
+// SetDeadline sets the deadline for Read
+func (q *conn) SetDeadline(t time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.deadline = t
+	q.notify()
+	return nil
+}
+
+// notify wakes up a blocked Read, must be called with the mutex held
+func (q *conn) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Serve answers the requests read from rw until it returns an error, e.g. to simulate a
+// module on a pseudo terminal or a network connection. io.EOF is not returned.
+func (q *Module) Serve(rw io.ReadWriter) error {
+	buf := make([]byte, protocol.FrameSize)
+	for {
+		if _, err := io.ReadFull(rw, buf); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		reply := q.handle(buf)
+		if reply == nil {
+			continue
+		}
+		if _, err := rw.Write(reply); err != nil {
+			return err
+		}
+	}
+}
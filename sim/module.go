@@ -0,0 +1,408 @@
+// Package sim simulates TMCL modules, so that applications can be tested without hardware
+package sim
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/protocol"
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// global parameters with special meaning for the simulation
+const (
+	globalSerialAddress = 66
+	globalEEPROMLock    = 73
+
+	// values written to globalEEPROMLock
+	eepromLock   = 1234
+	eepromUnlock = 4321
+)
+
+const (
+	// allPorts is the port number of SIO and GIO addressing all digital ports of a bank
+	allPorts = 255
+
+	// digitalPorts is the number of digital inputs and outputs
+	digitalPorts = 8
+)
+
+// Module simulates the module side of the TMCL protocol: it answers request telegrams,
+// stores axis and global parameters, moves virtual motors and has IO banks
+type Module struct {
+	mutex       sync.Mutex
+	address     byte
+	hostAddress byte
+	version     tmcl.Version
+	now         func() time.Time
+	last        time.Time
+
+	motors        []*motor
+	globals       map[[2]byte]int
+	storedGlobals map[[2]byte]int
+	io            map[[2]byte]int
+	coordinates   map[byte]int
+	running       bool
+}
+
+// Option configures a Module when it is created
+type Option func(q *Module)
+
+// WithAddress sets the module address (default 1). Requests to address 0 are answered as
+// well, as sent by connections without module address.
+func WithAddress(addr byte) Option {
+	return func(q *Module) {
+		q.address = addr
+	}
+}
+
+// WithHostAddress sets the host address replies are sent to (default 2)
+func WithHostAddress(addr byte) Option {
+	return func(q *Module) {
+		q.hostAddress = addr
+	}
+}
+
+// WithVersion sets module type and firmware revision (default 351V4.45)
+func WithVersion(v tmcl.Version) Option {
+	return func(q *Module) {
+		q.version = v
+	}
+}
+
+// WithMotors sets the number of motors (default 3)
+func WithMotors(n int) Option {
+	return func(q *Module) {
+		q.motors = make([]*motor, n)
+	}
+}
+
+// WithClock sets the clock the motors move by (default time.Now), e.g. to step the
+// simulation manually
+func WithClock(now func() time.Time) Option {
+	return func(q *Module) {
+		q.now = now
+	}
+}
+
+// NewModule returns a simulated module
+func NewModule(opts ...Option) *Module {
+	q := &Module{
+		address:       1,
+		hostAddress:   2,
+		version:       tmcl.Version{ModuleType: 351, Major: 4, Minor: 45},
+		now:           time.Now,
+		motors:        make([]*motor, 3),
+		globals:       map[[2]byte]int{},
+		storedGlobals: map[[2]byte]int{},
+		io:            map[[2]byte]int{},
+		coordinates:   map[byte]int{},
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	for i := range q.motors {
+		q.motors[i] = newMotor()
+	}
+	q.globals[[2]byte{0, globalSerialAddress}] = int(q.address)
+	q.last = q.now()
+	return q
+}
+
+// Conn returns a new connection to the module, to be passed to tmcl.NewWithPort
+func (q *Module) Conn() transport.Transport {
+	return newConn(q)
+}
+
+// Connect returns a TMCL connection to the module
+func (q *Module) Connect(opts ...tmcl.Option) *tmcl.TMCL {
+	return tmcl.NewWithPort(q.Conn(), opts...)
+}
+
+// SetInput sets a digital input
+func (q *Module) SetInput(port byte, value bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.io[[2]byte{tmcl.DigitalInputBank, port}] = boolInt(value)
+}
+
+// SetAnalogInput sets the raw value of an analog input
+func (q *Module) SetAnalogInput(port byte, value int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.io[[2]byte{tmcl.AnalogInputBank, port}] = value
+}
+
+// Output returns the state of a digital output
+func (q *Module) Output(port byte) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.io[[2]byte{tmcl.DigitalOutputBank, port}] != 0
+}
+
+// AxisParam returns an axis parameter as the module would report it
+func (q *Module) AxisParam(motor byte, index byte) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.update()
+	return q.motors[motor].get(index)
+}
+
+// SetAxisParam sets an axis parameter, including read only ones, e.g. to prepare a test
+func (q *Module) SetAxisParam(motor byte, index byte, value int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.update()
+	q.motors[motor].set(index, value)
+}
+
+// SetSwitches adds end switches to a motor, which stop it and report their state in axis
+// parameters 9 to 11 like the switches of a real module
+func (q *Module) SetSwitches(motor byte, s Switches) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.update()
+	q.motors[motor].switches = &s
+}
+
+// RemoveSwitches removes the end switches of a motor
+func (q *Module) RemoveSwitches(motor byte) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.update()
+	q.motors[motor].switches = nil
+}
+
+// GlobalParam returns a global parameter
+func (q *Module) GlobalParam(bank byte, index byte) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.globals[[2]byte{bank, index}]
+}
+
+// SetGlobalParam sets a global parameter
+func (q *Module) SetGlobalParam(bank byte, index byte, value int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.globals[[2]byte{bank, index}] = value
+}
+
+// update moves the motors up to the current time, must be called with the mutex held
+func (q *Module) update() {
+	now := q.now()
+	dt := now.Sub(q.last)
+	if dt <= 0 {
+		return
+	}
+	q.last = now
+	for _, m := range q.motors {
+		m.advance(dt)
+	}
+}
+
+// handle processes a request telegram and returns the reply, nil if the request is not
+// addressed to the module
+func (q *Module) handle(req []byte) []byte {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if req[0] != q.address && req[0] != 0 {
+		return nil
+	}
+	if !protocol.ValidChecksum(req) {
+		return q.reply(tmcl.StatusWrongChecksum, req[1], 0)
+	}
+	q.update()
+
+	in := protocol.DecodeInstruction(req)
+	if in.Cmd == 136 && in.Type == 0 {
+		// version string: host address and 8 characters without checksum
+		reply := []byte(fmt.Sprintf("%c%-8s", q.hostAddress, fmt.Sprintf("%dV%d%02d", q.version.ModuleType, q.version.Major, q.version.Minor)))
+		return reply[:protocol.FrameSize]
+	}
+
+	// the reply to a new serial address still comes from the old one
+	from := q.address
+	value, status := q.execute(in)
+	return q.replyFrom(from, status, in.Cmd, value)
+}
+
+// reply returns a reply telegram from the module, must be called with the mutex held
+func (q *Module) reply(status byte, cmd byte, value int) []byte {
+	return q.replyFrom(q.address, status, cmd, value)
+}
+
+// replyFrom returns a reply telegram from the given module address
+func (q *Module) replyFrom(address byte, status byte, cmd byte, value int) []byte {
+	bts := make([]byte, protocol.FrameSize)
+	bts[0] = q.hostAddress
+	bts[1] = address
+	bts[2] = status
+	bts[3] = cmd
+	binary.BigEndian.PutUint32(bts[4:8], uint32(value))
+	bts[8] = protocol.Checksum(bts[:8])
+	return bts
+}
+
+// execute runs a command and returns the reply value and status, must be called with the
+// mutex held
+func (q *Module) execute(in protocol.Instruction) (int, byte) {
+	// motion and axis parameter commands
+	switch in.Cmd {
+	case 1, 2, 3, 4, 5, 6, 7, 8, 13:
+		if int(in.MotorBank) >= len(q.motors) {
+			return 0, tmcl.StatusInvalidValue
+		}
+	}
+
+	switch in.Cmd {
+	case 1:
+		q.motors[in.MotorBank].rotate(in.Value)
+	case 2:
+		q.motors[in.MotorBank].rotate(-in.Value)
+	case 3:
+		q.motors[in.MotorBank].rotate(0)
+	case 4:
+		m := q.motors[in.MotorBank]
+		switch in.Type {
+		case tmcl.ABS:
+			m.moveTo(in.Value)
+		case tmcl.REL:
+			m.moveTo(m.target + in.Value)
+		case tmcl.COORD:
+			c, ok := q.coordinates[byte(in.Value)]
+			if !ok {
+				return 0, tmcl.StatusInvalidValue
+			}
+			m.moveTo(c)
+		default:
+			return 0, tmcl.StatusWrongType
+		}
+	case 5:
+		if readOnly[in.Type] {
+			return 0, tmcl.StatusWrongType
+		}
+		q.motors[in.MotorBank].set(in.Type, in.Value)
+	case 6:
+		return q.motors[in.MotorBank].get(in.Type), tmcl.StatusOK
+	case 7:
+		if q.eepromLocked() {
+			return 0, tmcl.StatusEEPROMLocked
+		}
+		m := q.motors[in.MotorBank]
+		m.stored[in.Type] = m.get(in.Type)
+	case 8:
+		m := q.motors[in.MotorBank]
+		if v, ok := m.stored[in.Type]; ok {
+			m.set(in.Type, v)
+		}
+	case 9:
+		key := [2]byte{in.MotorBank, in.Type}
+		switch {
+		case key == [2]byte{0, globalEEPROMLock}:
+			switch in.Value {
+			case eepromLock:
+				q.globals[key] = 1
+			case eepromUnlock:
+				q.globals[key] = 0
+			default:
+				return 0, tmcl.StatusInvalidValue
+			}
+			return in.Value, tmcl.StatusOK
+		case in.MotorBank == 0 && in.Type >= 64 && in.Type < 128 && q.eepromLocked():
+			// these parameters are stored in the EEPROM right away
+			return 0, tmcl.StatusEEPROMLocked
+		case key == [2]byte{0, globalSerialAddress}:
+			q.address = byte(in.Value)
+		}
+		q.globals[key] = in.Value
+	case 10:
+		return q.globals[[2]byte{in.MotorBank, in.Type}], tmcl.StatusOK
+	case 11:
+		if q.eepromLocked() {
+			return 0, tmcl.StatusEEPROMLocked
+		}
+		key := [2]byte{in.MotorBank, in.Type}
+		q.storedGlobals[key] = q.globals[key]
+	case 12:
+		key := [2]byte{in.MotorBank, in.Type}
+		if v, ok := q.storedGlobals[key]; ok {
+			q.globals[key] = v
+		}
+	case 13:
+		m := q.motors[in.MotorBank]
+		switch in.Type {
+		case 0:
+			m.referenceSearch()
+		case 1:
+			m.rotate(0)
+		case 2:
+			return boolInt(m.searching), tmcl.StatusOK
+		default:
+			return 0, tmcl.StatusWrongType
+		}
+	case 14:
+		if in.MotorBank != tmcl.DigitalOutputBank {
+			return 0, tmcl.StatusWrongType
+		}
+		if in.Type == allPorts {
+			for port := byte(0); port < digitalPorts; port++ {
+				q.io[[2]byte{in.MotorBank, port}] = in.Value >> port & 1
+			}
+			break
+		}
+		q.io[[2]byte{in.MotorBank, in.Type}] = boolInt(in.Value != 0)
+	case 15:
+		if in.Type == allPorts {
+			if in.MotorBank == tmcl.AnalogInputBank {
+				return 0, tmcl.StatusWrongType
+			}
+			var bits int
+			for port := byte(0); port < digitalPorts; port++ {
+				if q.io[[2]byte{in.MotorBank, port}] != 0 {
+					bits |= 1 << port
+				}
+			}
+			return bits, tmcl.StatusOK
+		}
+		return q.io[[2]byte{in.MotorBank, in.Type}], tmcl.StatusOK
+	case 30:
+		q.coordinates[in.Type] = in.Value
+	case 31:
+		return q.coordinates[in.Type], tmcl.StatusOK
+	case 32:
+		if int(in.MotorBank) >= len(q.motors) {
+			return 0, tmcl.StatusInvalidValue
+		}
+		q.coordinates[in.Type] = q.motors[in.MotorBank].get(byte(tmcl.ActualPosition))
+	case 128:
+		q.running = false
+	case 129:
+		q.running = true
+	case 130, 131:
+	case 135:
+		return boolInt(q.running), tmcl.StatusOK
+	case 136:
+		v := q.version
+		return v.ModuleType<<16 | v.Major<<8 | v.Minor, tmcl.StatusOK
+	default:
+		return 0, tmcl.StatusInvalidCommand
+	}
+	return in.Value, tmcl.StatusOK
+}
+
+// eepromLocked returns true if the EEPROM lock global parameter is set
+func (q *Module) eepromLocked() bool {
+	return q.globals[[2]byte{0, globalEEPROMLock}] != 0
+}
+
+// boolInt converts a flag to its parameter value
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
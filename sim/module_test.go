@@ -0,0 +1,230 @@
+package sim
+
+import (
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// step is a request sent to the module in a test, after advancing the clock by wait, and
+// the reply expected, status 0 meaning tmcl.StatusOK
+type step struct {
+	wait   time.Duration
+	in     protocol.Instruction
+	status byte
+	value  int
+}
+
+// clock is the manually advanced clock of a module in a test
+type clock struct {
+	now time.Time
+}
+
+// Now returns the time of the clock
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+// exchange sends a request telegram to the module and returns the reply telegram
+func exchange(t *testing.T, conn io.ReadWriter, in protocol.Instruction) []byte {
+	t.Helper()
+	req := make([]byte, protocol.FrameSize)
+	in.Encode(req, 1)
+	if _, err := conn.Write(req); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, protocol.FrameSize)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestModule(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(m *Module)
+		steps []step
+	}{
+		{
+			name: "axis parameter",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 6, Type: 4}, value: 1000},
+				{in: protocol.Instruction{Cmd: 5, Type: 4, MotorBank: 1, Value: 200}, value: 200},
+				{in: protocol.Instruction{Cmd: 6, Type: 4, MotorBank: 1}, value: 200},
+				{in: protocol.Instruction{Cmd: 6, Type: 4, MotorBank: 0}, value: 1000},
+			},
+		},
+		{
+			name: "read-only axis parameter",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 5, Type: byte(tmcl.ActualSpeed), Value: 1}, status: tmcl.StatusWrongType},
+			},
+		},
+		{
+			name: "motor out of range",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 6, Type: 4, MotorBank: 3}, status: tmcl.StatusInvalidValue},
+			},
+		},
+		{
+			name: "store and restore",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 5, Type: 6, Value: 100}, value: 100},
+				{in: protocol.Instruction{Cmd: 7, Type: 6}},
+				{in: protocol.Instruction{Cmd: 5, Type: 6, Value: 50}, value: 50},
+				{in: protocol.Instruction{Cmd: 8, Type: 6}},
+				{in: protocol.Instruction{Cmd: 6, Type: 6}, value: 100},
+			},
+		},
+		{
+			name: "eeprom lock",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 9, Type: globalEEPROMLock, Value: eepromLock}, value: eepromLock},
+				{in: protocol.Instruction{Cmd: 7, Type: 6}, status: tmcl.StatusEEPROMLocked},
+				{in: protocol.Instruction{Cmd: 9, Type: 65, Value: 1}, status: tmcl.StatusEEPROMLocked},
+				{in: protocol.Instruction{Cmd: 9, Type: globalEEPROMLock, Value: eepromUnlock}, value: eepromUnlock},
+				{in: protocol.Instruction{Cmd: 7, Type: 6}},
+			},
+		},
+		{
+			name: "global parameter",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 10, Type: globalSerialAddress}, value: 1},
+				{in: protocol.Instruction{Cmd: 9, Type: 0, MotorBank: 2, Value: -7}, value: -7},
+				{in: protocol.Instruction{Cmd: 10, Type: 0, MotorBank: 2}, value: -7},
+			},
+		},
+		{
+			name:  "inputs",
+			setup: func(m *Module) { m.SetInput(1, true); m.SetInput(3, true); m.SetAnalogInput(0, 512) },
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 15, Type: 1, MotorBank: tmcl.DigitalInputBank}, value: 1},
+				{in: protocol.Instruction{Cmd: 15, Type: 2, MotorBank: tmcl.DigitalInputBank}, value: 0},
+				{in: protocol.Instruction{Cmd: 15, Type: allPorts, MotorBank: tmcl.DigitalInputBank}, value: 0x0a},
+				{in: protocol.Instruction{Cmd: 15, Type: 0, MotorBank: tmcl.AnalogInputBank}, value: 512},
+				{in: protocol.Instruction{Cmd: 15, Type: allPorts, MotorBank: tmcl.AnalogInputBank}, status: tmcl.StatusWrongType},
+			},
+		},
+		{
+			name: "outputs",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 14, Type: allPorts, MotorBank: tmcl.DigitalOutputBank, Value: 0x05}, value: 0x05},
+				{in: protocol.Instruction{Cmd: 14, Type: 0, MotorBank: tmcl.DigitalOutputBank, Value: 0}, value: 0},
+				{in: protocol.Instruction{Cmd: 15, Type: allPorts, MotorBank: tmcl.DigitalOutputBank}, value: 0x04},
+				{in: protocol.Instruction{Cmd: 14, Type: 0, MotorBank: tmcl.DigitalInputBank, Value: 1}, status: tmcl.StatusWrongType},
+			},
+		},
+		{
+			name: "move to position",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 4, Type: tmcl.ABS, Value: 1000}, value: 1000},
+				{in: protocol.Instruction{Cmd: 6, Type: byte(tmcl.TargetPositionReached)}, value: 0},
+				{wait: time.Second, in: protocol.Instruction{Cmd: 6, Type: byte(tmcl.ActualPosition)}, value: 1000},
+				{in: protocol.Instruction{Cmd: 6, Type: byte(tmcl.TargetPositionReached)}, value: 1},
+				{in: protocol.Instruction{Cmd: 4, Type: tmcl.REL, Value: -300}, value: -300},
+				{wait: time.Second, in: protocol.Instruction{Cmd: 6, Type: byte(tmcl.ActualPosition)}, value: 700},
+			},
+		},
+		{
+			name: "coordinates",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 30, Type: 1, Value: 500}, value: 500},
+				{in: protocol.Instruction{Cmd: 31, Type: 1}, value: 500},
+				{in: protocol.Instruction{Cmd: 4, Type: tmcl.COORD, Value: 1}, value: 1},
+				{wait: time.Second, in: protocol.Instruction{Cmd: 6, Type: byte(tmcl.ActualPosition)}, value: 500},
+				{in: protocol.Instruction{Cmd: 4, Type: tmcl.COORD, Value: 2}, status: tmcl.StatusInvalidValue},
+				{in: protocol.Instruction{Cmd: 4, Type: 7}, status: tmcl.StatusWrongType},
+			},
+		},
+		{
+			name: "rotate and stop",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 1, Value: 100}, value: 100},
+				{wait: time.Second, in: protocol.Instruction{Cmd: 6, Type: byte(tmcl.ActualSpeed)}, value: 100},
+				{in: protocol.Instruction{Cmd: 3}},
+				{wait: time.Second, in: protocol.Instruction{Cmd: 6, Type: byte(tmcl.ActualSpeed)}, value: 0},
+			},
+		},
+		{
+			name:  "reference search",
+			setup: func(m *Module) { m.SetSwitches(0, Switches{Left: -200, Right: 5000}) },
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 13, Type: 0}},
+				{in: protocol.Instruction{Cmd: 13, Type: 2}, value: 1},
+				{wait: 5 * time.Second, in: protocol.Instruction{Cmd: 13, Type: 2}, value: 0},
+				{in: protocol.Instruction{Cmd: 6, Type: byte(tmcl.ActualPosition)}, value: 0},
+			},
+		},
+		{
+			name: "application",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 129}},
+				{in: protocol.Instruction{Cmd: 135}, value: 1},
+				{in: protocol.Instruction{Cmd: 128}},
+				{in: protocol.Instruction{Cmd: 135}, value: 0},
+			},
+		},
+		{
+			name: "firmware version",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 136, Type: 1}, value: 351<<16 | 4<<8 | 45},
+			},
+		},
+		{
+			name: "invalid command",
+			steps: []step{
+				{in: protocol.Instruction{Cmd: 200}, status: tmcl.StatusInvalidCommand},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &clock{now: time.Unix(0, 0)}
+			m := NewModule(WithClock(c.Now))
+			if tt.setup != nil {
+				tt.setup(m)
+			}
+			conn := m.Conn()
+			for i, s := range tt.steps {
+				c.now = c.now.Add(s.wait)
+				reply := exchange(t, conn, s.in)
+				if !protocol.ValidChecksum(reply) {
+					t.Fatalf("step %d: checksum invalid", i)
+				}
+				if reply[0] != 2 || reply[1] != 1 || reply[3] != s.in.Cmd {
+					t.Fatalf("step %d: reply % x", i, reply)
+				}
+				want := s.status
+				if want == 0 {
+					want = tmcl.StatusOK
+				}
+				status := reply[2]
+				value := int(int32(binary.BigEndian.Uint32(reply[4:8])))
+				if status != want || (status == tmcl.StatusOK && value != s.value) {
+					t.Errorf("step %d: status %d, value %d, want %d, %d", i, status, value, want, s.value)
+				}
+			}
+		})
+	}
+}
+
+func TestModuleAddress(t *testing.T) {
+	m := NewModule(WithAddress(3))
+	conn := m.Conn()
+	req := make([]byte, protocol.FrameSize)
+	protocol.Instruction{Cmd: 6, Type: 4}.Encode(req, 1)
+	if _, err := conn.Write(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := conn.Read(req); err == nil {
+		t.Fatalf("module replied with %d bytes to another address", n)
+	}
+}
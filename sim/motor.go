@@ -0,0 +1,265 @@
+package sim
+
+import (
+	"math"
+	"time"
+
+	tmcl "github.com/raceresult/go-tmcl"
+)
+
+const (
+	// clockFrequency is the clock of the TMC429 motion controller the units depend on
+	clockFrequency = 16e6
+
+	// simStep is the time step the motion is integrated with
+	simStep = time.Millisecond
+)
+
+// readOnly are the axis parameters that cannot be set with SAP
+var readOnly = map[byte]bool{
+	byte(tmcl.ActualSpeed):           true,
+	byte(tmcl.TargetPositionReached): true,
+	byte(tmcl.ReferenceSwitch):       true,
+	byte(tmcl.RightLimitSwitch):      true,
+	byte(tmcl.LeftLimitSwitch):       true,
+	byte(tmcl.ActualAcceleration):    true,
+	byte(tmcl.ActualLoad):            true,
+}
+
+// Switches are the end switches of a motor: the left one is active at and below Left, the
+// right one at and above Right, both in microsteps from the power up position
+type Switches struct {
+	Left, Right int
+}
+
+// motor is a virtual motor: the plain axis parameters and the state of the motion, position
+// in microsteps from the power up position and speed in microsteps per second. The position
+// counter of the module is the position minus the origin.
+type motor struct {
+	params map[byte]int
+	stored map[byte]int
+
+	position    float64
+	origin      float64
+	speed       float64
+	target      int
+	positioning bool
+	searching   bool
+	switches    *Switches
+}
+
+// newMotor returns a motor at rest with the default parameters
+func newMotor() *motor {
+	return &motor{
+		params: map[byte]int{
+			byte(tmcl.MaxSpeed):        1000,
+			byte(tmcl.MaxAcceleration): 500,
+			byte(tmcl.RunCurrent):      128,
+			byte(tmcl.StandbyCurrent):  32,
+			byte(tmcl.RampDivisor):     7,
+			byte(tmcl.PulseDivisor):    3,
+		},
+		stored: map[byte]int{},
+	}
+}
+
+// velocityFactor converts internal velocity units to microsteps per second
+func (q *motor) velocityFactor() float64 {
+	return clockFrequency / (math.Exp2(float64(q.params[byte(tmcl.PulseDivisor)])) * 2048 * 32)
+}
+
+// accelerationFactor converts internal acceleration units to microsteps per second²
+func (q *motor) accelerationFactor() float64 {
+	div := q.params[byte(tmcl.PulseDivisor)] + q.params[byte(tmcl.RampDivisor)] + 29
+	return clockFrequency * clockFrequency / math.Exp2(float64(div))
+}
+
+// counter returns the position counter
+func (q *motor) counter() float64 {
+	return q.position - q.origin
+}
+
+// get returns the value of an axis parameter
+func (q *motor) get(index byte) int {
+	switch tmcl.AxisParam(index) {
+	case tmcl.TargetPosition:
+		return q.target
+	case tmcl.ActualPosition:
+		return int(math.Round(q.counter()))
+	case tmcl.ActualSpeed:
+		return int(math.Round(q.speed / q.velocityFactor()))
+	case tmcl.TargetPositionReached:
+		return boolInt(q.reached())
+	case tmcl.ReferenceSwitch, tmcl.LeftLimitSwitch:
+		return boolInt(q.leftActive())
+	case tmcl.RightLimitSwitch:
+		return boolInt(q.rightActive())
+	}
+	return q.params[index]
+}
+
+// set sets an axis parameter
+func (q *motor) set(index byte, value int) {
+	switch tmcl.AxisParam(index) {
+	case tmcl.TargetPosition:
+		q.moveTo(value)
+	case tmcl.ActualPosition:
+		q.origin = q.position - float64(value)
+	case tmcl.TargetSpeed:
+		q.rotate(value)
+	case tmcl.ActualSpeed:
+		q.speed = float64(value) * q.velocityFactor()
+	case tmcl.TargetPositionReached, tmcl.ReferenceSwitch, tmcl.LeftLimitSwitch, tmcl.RightLimitSwitch:
+	default:
+		q.params[index] = value
+	}
+}
+
+// leftActive returns true if the left switch is active
+func (q *motor) leftActive() bool {
+	return q.switches != nil && math.Round(q.position) <= float64(q.switches.Left)
+}
+
+// rightActive returns true if the right switch is active
+func (q *motor) rightActive() bool {
+	return q.switches != nil && math.Round(q.position) >= float64(q.switches.Right)
+}
+
+// reached returns true if the motor stands at the target position
+func (q *motor) reached() bool {
+	return q.speed == 0 && int(math.Round(q.counter())) == q.target
+}
+
+// rotate switches to velocity mode with the given speed in internal units, 0 stopping
+func (q *motor) rotate(velocity int) {
+	q.positioning = false
+	q.searching = false
+	q.params[byte(tmcl.TargetSpeed)] = velocity
+}
+
+// moveTo starts positioning to the given target
+func (q *motor) moveTo(target int) {
+	q.searching = false
+	q.positioning = true
+	q.target = target
+}
+
+// referenceSearch starts driving left with the reference search speed (axis parameter 194,
+// 0 meaning the maximum positioning speed) until the left switch is active, where the
+// position is set to 0. The search modes of axis parameter 193 are not distinguished.
+// Without switches the reference is found at the current position at once.
+func (q *motor) referenceSearch() {
+	q.rotate(0)
+	if q.switches == nil {
+		q.speed = 0
+		q.origin = q.position
+		q.target = 0
+		return
+	}
+	q.searching = true
+}
+
+// referenceFound ends the reference search at the current position
+func (q *motor) referenceFound() {
+	q.searching = false
+	q.speed = 0
+	q.origin = q.position
+	q.target = 0
+	q.params[byte(tmcl.TargetSpeed)] = 0
+}
+
+// advance moves the motor by dt
+func (q *motor) advance(dt time.Duration) {
+	for dt > 0 {
+		if q.speed == 0 && q.desired() == 0 {
+			return
+		}
+		h := simStep
+		if dt < h {
+			h = dt
+		}
+		q.step(h.Seconds())
+		dt -= h
+	}
+}
+
+// desired returns the speed the motor accelerates or decelerates to, 0 when an active and
+// enabled end switch blocks the direction
+func (q *motor) desired() float64 {
+	var want float64
+	switch {
+	case q.searching:
+		v := q.params[194]
+		if v == 0 {
+			v = q.params[byte(tmcl.MaxSpeed)]
+		}
+		want = -float64(v) * q.velocityFactor()
+	case q.positioning:
+		// fastest speed that can still stop at the target
+		accel := float64(q.params[byte(tmcl.MaxAcceleration)]) * q.accelerationFactor()
+		d := float64(q.target) - q.counter()
+		want = math.Min(float64(q.params[byte(tmcl.MaxSpeed)])*q.velocityFactor(), math.Sqrt(2*accel*math.Abs(d)))
+		if d < 0 {
+			want = -want
+		}
+	default:
+		// the maximum positioning speed limits the velocity mode as well
+		v := q.params[byte(tmcl.TargetSpeed)]
+		if limit := q.params[byte(tmcl.MaxSpeed)]; v > limit {
+			v = limit
+		} else if v < -limit {
+			v = -limit
+		}
+		want = float64(v) * q.velocityFactor()
+	}
+	if q.blocked(want) {
+		return 0
+	}
+	return want
+}
+
+// blocked returns true if an active and enabled end switch keeps the motor from moving
+// in the direction of the speed
+func (q *motor) blocked(speed float64) bool {
+	if speed < 0 {
+		return q.leftActive() && q.params[byte(tmcl.LeftLimitDisable)] == 0
+	}
+	if speed > 0 {
+		return q.rightActive() && q.params[byte(tmcl.RightLimitDisable)] == 0
+	}
+	return false
+}
+
+// step integrates the motion over h seconds with a trapezoidal ramp
+func (q *motor) step(h float64) {
+	if q.searching && q.leftActive() {
+		q.referenceFound()
+		return
+	}
+
+	// an end switch stops the motor at once unless the soft stop flag is set
+	if q.blocked(q.speed) && q.params[byte(tmcl.SoftStop)] == 0 {
+		q.speed = 0
+	}
+
+	accel := float64(q.params[byte(tmcl.MaxAcceleration)]) * q.accelerationFactor()
+	want := q.desired()
+	switch {
+	case accel <= 0:
+		q.speed = want
+	case want > q.speed:
+		q.speed = math.Min(want, q.speed+accel*h)
+	default:
+		q.speed = math.Max(want, q.speed-accel*h)
+	}
+
+	before := float64(q.target) - q.counter()
+	q.position += q.speed * h
+	if q.positioning {
+		after := float64(q.target) - q.counter()
+		if math.Abs(after) < 0.5 || (before > 0) != (after > 0) {
+			q.position = q.origin + float64(q.target)
+			q.speed = 0
+		}
+	}
+}
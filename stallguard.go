@@ -0,0 +1,224 @@
+package tmcl
+
+import "github.com/pkg/errors"
+
+// stallGuard and coolStep parameters of the TMC26x modules, StallGuardThreshold being the
+// threshold of stallGuard2
+const (
+	coolStepMinCurrent  AxisParam = 168
+	coolStepDownStep    AxisParam = 169
+	coolStepHysteresis  AxisParam = 170
+	coolStepUpStep      AxisParam = 171
+	coolStepStart       AxisParam = 172
+	stallGuardFilter    AxisParam = 173
+	stopOnStall         AxisParam = 181
+	coolStepSpeed       AxisParam = 182
+	coolStepSlowCurrent AxisParam = 183
+)
+
+// StallGuardConfig is the stall detection of a motor
+type StallGuardConfig struct {
+	// Threshold is the sensitivity of the detection: -64..63 with stallGuard2, lower values
+	// detecting earlier, or 0..7 with the stallGuard of the TMCM-351, 0 disabling it
+	Threshold int `json:"threshold"`
+
+	// Filter reads the load value only every four full steps, for more precise but slower
+	// detection. stallGuard2 only.
+	Filter bool `json:"filter"`
+
+	// StopSpeed is the speed above which the motor is stopped on a stall, 0 never stopping.
+	// stallGuard2 only.
+	StopSpeed int `json:"stopSpeed"`
+}
+
+// CoolStepConfig is the load dependent current control of a motor with stallGuard2. The
+// current is raised when the load value drops below Start*32 and lowered when it exceeds
+// (Start+Hysteresis+1)*32.
+type CoolStepConfig struct {
+	// Start is the lower load threshold divided by 32, 0 disabling coolStep
+	Start int `json:"start"`
+
+	// Hysteresis is the width of the load range without change, divided by 32
+	Hysteresis int `json:"hysteresis"`
+
+	// UpStep 0..3 raises the current by 1, 2, 4 or 8 current steps at a time
+	UpStep int `json:"upStep"`
+
+	// DownStep 0..3 lowers the current by one step every 32, 8, 2 or 1 load measurements
+	DownStep int `json:"downStep"`
+
+	// QuarterMinCurrent lowers the current down to a quarter of the run current instead of half
+	QuarterMinCurrent bool `json:"quarterMinCurrent"`
+
+	// ThresholdSpeed is the speed above which coolStep is active, below the motor is driven
+	// with SlowRunCurrent
+	ThresholdSpeed int `json:"thresholdSpeed"`
+	SlowRunCurrent int `json:"slowRunCurrent"`
+}
+
+// configField is a value of a typed configuration with its valid range
+type configField struct {
+	name     string
+	value    int
+	min, max int
+}
+
+// checkFields returns ErrParamRange for the first value outside of its range
+func checkFields(fields []configField) error {
+	for _, f := range fields {
+		if f.value < f.min || f.value > f.max {
+			return errors.Wrapf(ErrParamRange, "%s %d not in %d..%d", f.name, f.value, f.min, f.max)
+		}
+	}
+	return nil
+}
+
+// params returns the axis parameter values of the configuration for the driver family
+func (c StallGuardConfig) params(family driverFamily) ([]AxisParam, []int, error) {
+	switch family {
+	case tmc249Driver:
+		if c.Filter || c.StopSpeed != 0 {
+			return nil, nil, errors.Wrap(ErrUnsupported, "stallGuard filter and stop speed need stallGuard2")
+		}
+		if err := checkFields([]configField{{"stallGuard threshold", c.Threshold, 0, 7}}); err != nil {
+			return nil, nil, err
+		}
+		return []AxisParam{StallDetectionThreshold}, []int{c.Threshold}, nil
+	case tmc26xDriver:
+		if err := checkFields([]configField{
+			{"stallGuard threshold", c.Threshold, -64, 63},
+			{"stop speed", c.StopSpeed, 0, 2047},
+		}); err != nil {
+			return nil, nil, err
+		}
+		return []AxisParam{StallGuardThreshold, stallGuardFilter, stopOnStall},
+			[]int{c.Threshold, boolValue(c.Filter), c.StopSpeed}, nil
+	}
+	return nil, nil, errors.Wrap(ErrUnsupported, "stallGuard")
+}
+
+// params returns the axis parameter values of the configuration
+func (c CoolStepConfig) params() ([]AxisParam, []int, error) {
+	if err := checkFields([]configField{
+		{"coolStep start", c.Start, 0, 15},
+		{"coolStep hysteresis", c.Hysteresis, 0, 15},
+		{"coolStep up step", c.UpStep, 0, 3},
+		{"coolStep down step", c.DownStep, 0, 3},
+		{"coolStep threshold speed", c.ThresholdSpeed, 0, 2047},
+		{"slow run current", c.SlowRunCurrent, 0, 255},
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	// the start threshold last, as it enables coolStep
+	params := []AxisParam{
+		coolStepHysteresis, coolStepUpStep, coolStepDownStep, coolStepMinCurrent,
+		coolStepSpeed, coolStepSlowCurrent, coolStepStart,
+	}
+	values := []int{
+		c.Hysteresis, c.UpStep, c.DownStep, boolValue(c.QuarterMinCurrent),
+		c.ThresholdSpeed, c.SlowRunCurrent, c.Start,
+	}
+	return params, values, nil
+}
+
+// SetStallGuard validates the stall detection configuration for the module type and writes
+// it to the motor. ErrUnsupported is returned for modules without stallGuard or for options
+// their driver does not have.
+func (q *TMCL) SetStallGuard(motor byte, c StallGuardConfig) error {
+	family, t := q.driver()
+	params, values, err := c.params(family)
+	if err != nil {
+		return errors.Wrapf(err, "module type %d", t)
+	}
+	return q.setParams(motor, params, values)
+}
+
+// stallThresholdParam returns the axis parameter of the stallGuard threshold of the module
+// after checking the threshold against its range. Modules of other driver families are
+// assumed to use parameter 174 as most TMCL modules with stallGuard2.
+func (q *TMCL) stallThresholdParam(threshold int) (AxisParam, error) {
+	family, t := q.driver()
+	if family != tmc249Driver && family != tmc26xDriver {
+		return StallGuardThreshold, nil
+	}
+	params, _, err := StallGuardConfig{Threshold: threshold}.params(family)
+	if err != nil {
+		return 0, errors.Wrapf(err, "module type %d", t)
+	}
+	return params[0], nil
+}
+
+// ReadStallGuard reads the stall detection configuration of the motor
+func (q *TMCL) ReadStallGuard(motor byte) (StallGuardConfig, error) {
+	var c StallGuardConfig
+	switch family, t := q.driver(); family {
+	case tmc249Driver:
+		v, err := q.getParams(motor, StallDetectionThreshold)
+		if err != nil {
+			return c, err
+		}
+		c.Threshold = v[0]
+	case tmc26xDriver:
+		v, err := q.getParams(motor, StallGuardThreshold, stallGuardFilter, stopOnStall)
+		if err != nil {
+			return c, err
+		}
+		c.Threshold, c.Filter, c.StopSpeed = v[0], v[1] != 0, v[2]
+	default:
+		return c, errors.Wrapf(ErrUnsupported, "module type %d: stallGuard", t)
+	}
+	return c, nil
+}
+
+// SetCoolStep validates the coolStep configuration and writes it to the motor. ErrUnsupported
+// is returned for modules without coolStep.
+func (q *TMCL) SetCoolStep(motor byte, c CoolStepConfig) error {
+	if family, t := q.driver(); family != tmc26xDriver {
+		return errors.Wrapf(ErrUnsupported, "module type %d: coolStep", t)
+	}
+	params, values, err := c.params()
+	if err != nil {
+		return err
+	}
+	return q.setParams(motor, params, values)
+}
+
+// ReadCoolStep reads the coolStep configuration of the motor
+func (q *TMCL) ReadCoolStep(motor byte) (CoolStepConfig, error) {
+	var c CoolStepConfig
+	if family, t := q.driver(); family != tmc26xDriver {
+		return c, errors.Wrapf(ErrUnsupported, "module type %d: coolStep", t)
+	}
+	v, err := q.getParams(motor, coolStepStart, coolStepHysteresis, coolStepUpStep, coolStepDownStep,
+		coolStepMinCurrent, coolStepSpeed, coolStepSlowCurrent)
+	if err != nil {
+		return c, err
+	}
+	c.Start, c.Hysteresis, c.UpStep, c.DownStep = v[0], v[1], v[2], v[3]
+	c.QuarterMinCurrent, c.ThresholdSpeed, c.SlowRunCurrent = v[4] != 0, v[5], v[6]
+	return c, nil
+}
+
+// setParams sets axis parameters of a motor in order, the error naming the failed parameter
+func (q *TMCL) setParams(motor byte, params []AxisParam, values []int) error {
+	for i, p := range params {
+		if err := q.SAP(byte(p), motor, values[i]); err != nil {
+			return errors.Wrap(err, AxisParamName(byte(p)))
+		}
+	}
+	return nil
+}
+
+// getParams reads axis parameters of a motor, the error naming the failed parameter
+func (q *TMCL) getParams(motor byte, params ...AxisParam) ([]int, error) {
+	values := make([]int, len(params))
+	for i, p := range params {
+		v, err := q.GAP(byte(p), motor)
+		if err != nil {
+			return nil, errors.Wrap(err, AxisParamName(byte(p)))
+		}
+		values[i] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,73 @@
+package tmcl
+
+import "sync/atomic"
+
+// Stats contains counters about the communication with the board
+type Stats struct {
+	Commands       uint64
+	BoardErrors    uint64
+	Timeouts       uint64
+	ChecksumErrors uint64
+	SkippedBytes   uint64
+	StaleReplies   uint64
+	Flushes        uint64
+	BytesSent      uint64
+	BytesReceived  uint64
+
+	// QueueLength is the number of commands waiting for the bus, MaxQueueLength the
+	// maximum since the last reset and CanceledWaiting the commands whose context was
+	// done before they were sent
+	QueueLength     int
+	MaxQueueLength  int
+	CanceledWaiting uint64
+}
+
+// counters holds the statistics, all fields are accessed atomically so that counting never
+// has to wait for the command lock
+type counters struct {
+	commands       uint64
+	boardErrors    uint64
+	timeouts       uint64
+	checksumErrors uint64
+	skippedBytes   uint64
+	staleReplies   uint64
+	flushes        uint64
+	bytesSent      uint64
+	bytesReceived  uint64
+}
+
+// Stats returns a snapshot of the communication statistics
+func (q *TMCL) Stats() Stats {
+	c := &q.stats
+	waiting, maxWaiting, canceled := q.cmdLock.queueStats()
+	return Stats{
+		Commands:       atomic.LoadUint64(&c.commands),
+		BoardErrors:    atomic.LoadUint64(&c.boardErrors),
+		Timeouts:       atomic.LoadUint64(&c.timeouts),
+		ChecksumErrors: atomic.LoadUint64(&c.checksumErrors),
+		SkippedBytes:   atomic.LoadUint64(&c.skippedBytes),
+		StaleReplies:   atomic.LoadUint64(&c.staleReplies),
+		Flushes:        atomic.LoadUint64(&c.flushes),
+		BytesSent:      atomic.LoadUint64(&c.bytesSent),
+		BytesReceived:  atomic.LoadUint64(&c.bytesReceived),
+
+		QueueLength:     waiting,
+		MaxQueueLength:  maxWaiting,
+		CanceledWaiting: canceled,
+	}
+}
+
+// ResetStats sets all statistics counters to zero
+func (q *TMCL) ResetStats() {
+	c := &q.stats
+	atomic.StoreUint64(&c.commands, 0)
+	atomic.StoreUint64(&c.boardErrors, 0)
+	atomic.StoreUint64(&c.timeouts, 0)
+	atomic.StoreUint64(&c.checksumErrors, 0)
+	atomic.StoreUint64(&c.skippedBytes, 0)
+	atomic.StoreUint64(&c.staleReplies, 0)
+	atomic.StoreUint64(&c.flushes, 0)
+	atomic.StoreUint64(&c.bytesSent, 0)
+	atomic.StoreUint64(&c.bytesReceived, 0)
+	q.cmdLock.resetQueueStats()
+}
@@ -0,0 +1,115 @@
+package tmcl
+
+import (
+	"fmt"
+
+	"github.com/raceresult/go-tmcl/protocol"
+)
+
+// SetStatusCodes registers texts for module specific status codes, overriding the standard
+// texts, used in the error messages of this connection
+func (q *TMCL) SetStatusCodes(codes map[byte]string) {
+	q.do(globalKey, func() {
+		q.statusTexts = codes
+	})
+}
+
+// statusText returns the description of a status code
+func (q *TMCL) statusText(status byte) string {
+	if s, ok := q.statusTexts[status]; ok {
+		return s
+	}
+	if s, ok := statusTexts(status); ok {
+		return s
+	}
+	return "unknown error"
+}
+
+// statusSuccess returns true if the status code reports a successfully executed command
+func statusSuccess(status byte) bool {
+	return protocol.StatusSuccess(status)
+}
+
+// status codes of replies
+const (
+	StatusWrongChecksum       byte = 1
+	StatusInvalidCommand      byte = 2
+	StatusWrongType           byte = 3
+	StatusInvalidValue        byte = 4
+	StatusEEPROMLocked        byte = 5
+	StatusCommandNotAvailable byte = 6
+	StatusOK                  byte = 100
+	StatusLoaded              byte = 101 // command stored in the program memory in download mode
+)
+
+// Error is the error of a reply with an error status code. It matches the sentinel of its
+// status code with errors.Is, e.g. errors.Is(err, tmcl.ErrWrongType).
+type Error struct {
+	// Code is the status code of the reply
+	Code byte
+
+	// Command, TypeNo, MotorOrBank and Value are the fields of the failed request
+	Command     byte
+	TypeNo      byte
+	MotorOrBank byte
+	Value       int
+
+	// Text describes the status code
+	Text string
+}
+
+// sentinels of the documented error status codes, to be matched with errors.Is
+var (
+	ErrWrongChecksum       = &Error{Code: StatusWrongChecksum, Text: "wrong checksum"}
+	ErrInvalidCommand      = &Error{Code: StatusInvalidCommand, Text: "invalid command"}
+	ErrWrongType           = &Error{Code: StatusWrongType, Text: "wrong type"}
+	ErrInvalidValue        = &Error{Code: StatusInvalidValue, Text: "invalid value"}
+	ErrEEPROMLocked        = &Error{Code: StatusEEPROMLocked, Text: "configuration EEPROM locked"}
+	ErrCommandNotAvailable = &Error{Code: StatusCommandNotAvailable, Text: "command not available"}
+)
+
+// Error returns the error message including the failed request
+func (e *Error) Error() string {
+	if e.Command == 0 {
+		return fmt.Sprintf("board returned error code %d (%s)", e.Code, e.Text)
+	}
+	req := Request{Cmd: e.Command, Type: e.TypeNo, MotorBank: e.MotorOrBank, Value: e.Value}
+	return fmt.Sprintf("%s: board returned error code %d (%s)", req, e.Code, e.Text)
+}
+
+// Is returns true if target is the sentinel of the status code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Command == 0 && t.Code == e.Code
+}
+
+// statusTexts returns the standard description of a status code
+func statusTexts(status byte) (string, bool) {
+	return protocol.StatusText(status)
+}
+
+// statusError converts the status code of a reply to an error including the failing command,
+// must be called with the command lock held
+func (q *TMCL) statusError(status byte, req Request) error {
+	if statusSuccess(status) {
+		return nil
+	}
+	return &Error{
+		Code:        status,
+		Command:     req.Cmd,
+		TypeNo:      req.Type,
+		MotorOrBank: req.MotorBank,
+		Value:       req.Value,
+		Text:        q.statusText(status),
+	}
+}
+
+// plausibleReply returns true if bts looks like a reply telegram with a standard or module
+// specific status code, must be called with the command lock held
+func (q *TMCL) plausibleReply(bts []byte) bool {
+	if protocol.PlausibleReply(bts) {
+		return true
+	}
+	_, ok := q.statusTexts[bts[2]]
+	return ok && protocol.ValidChecksum(bts)
+}
@@ -0,0 +1,93 @@
+package tmcl
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// stopAllMotors is the number of motors stopped by StopAll if the module type is unknown
+const stopAllMotors = 6
+
+// StopAll stops all motors of the module. It does not wait for queued commands, but is sent
+// as soon as the command in flight finished.
+func (q *TMCL) StopAll() error {
+	if err := q.checkUsable(); err != nil {
+		return err
+	}
+	var err error
+	q.do(urgentKey, func() {
+		err = q.stopAll()
+	})
+	return err
+}
+
+// shutdown stops a running standalone application, so that it cannot start the motors
+// again, and all motors, skipping the queue of waiting commands
+func (q *TMCL) shutdown() error {
+	if err := q.checkUsable(); err != nil {
+		return err
+	}
+	var err error
+	q.do(urgentKey, func() {
+		_, appErr := q.transact(context.Background(), Request{Cmd: 128})
+		if err = q.stopAll(); err == nil {
+			err = appErr
+		}
+	})
+	return err
+}
+
+// stopAll sends MST to all motors, must be called with the command lock held
+func (q *TMCL) stopAll() error {
+	q.detectModule()
+	n := q.axisCount
+	known := n != 0
+	if !known {
+		n = stopAllMotors
+	}
+
+	var firstErr error
+	for motor := 0; motor < n; motor++ {
+		_, err := q.transact(context.Background(), Request{Cmd: 3, MotorBank: byte(motor)})
+		if err == nil {
+			continue
+		}
+		// without knowing the module, motors beyond the first may not exist
+		var be *Error
+		if !known && motor > 0 && errors.As(err, &be) {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// StopAll stops all motors of all modules of the bus, see TMCL.StopAll
+func (b *Bus) StopAll() error {
+	b.mutex.Lock()
+	modules := make([]*TMCL, 0, len(b.modules))
+	for _, q := range b.modules {
+		if err := q.checkUsable(); err != nil {
+			if err == ErrReentrant {
+				b.mutex.Unlock()
+				return err
+			}
+			continue
+		}
+		modules = append(modules, q)
+	}
+	b.mutex.Unlock()
+
+	var firstErr error
+	_ = b.cmdLock.run(context.Background(), urgentKey, func() {
+		for _, q := range modules {
+			if err := q.stopAll(); err != nil && firstErr == nil {
+				firstErr = errors.Wrapf(err, "module %d", q.address)
+			}
+		}
+	})
+	return firstErr
+}
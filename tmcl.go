@@ -1,33 +1,241 @@
 package tmcl
 
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
+	"io"
+	"net"
+	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/tarm/serial"
+	"github.com/raceresult/go-tmcl/protocol"
+	"github.com/raceresult/go-tmcl/sessionlog"
+	"github.com/raceresult/go-tmcl/transport"
 )
 
-const timeout = time.Second
+// defaultTimeout is the time to wait for a reply if not set otherwise
+const defaultTimeout = time.Second
+
+// defaultPollInterval is the pause between two reads while waiting for a reply
+const defaultPollInterval = time.Millisecond
+
+// defaultResyncLimit is the number of bytes skipped at most to find a reply after garbage
+const defaultResyncLimit = 2 * frameSize
+
+// frameSize is the length of a request or reply telegram
+const frameSize = protocol.FrameSize
+
+// errors of the communication, to be matched with errors.Is
+var (
+	// ErrNoPort is returned if the port was released and there is nothing to reopen
+	ErrNoPort = errors.New("no port: the existing port was released")
+
+	// ErrTimeout is returned if no reply arrived within the reply timeout
+	ErrTimeout = errors.New("timeout")
+
+	// ErrChecksum is returned if a reply with invalid checksum arrived
+	ErrChecksum = errors.New("checksum invalid")
+
+	// ErrShortWrite is returned if the port did not accept the complete telegram
+	ErrShortWrite = errors.New("telegram not written completely")
+)
 
 // TMCL is the main api object to connect to a TMCL board
 type TMCL struct {
-	ComPort  string
-	baudRate int
+	*connState
+
+	// txn is set on the TMCL object passed to the function of WithTransaction and runs its
+	// commands within the transaction
+	txn *txn
+}
+
+// connState is the state of a connection, shared by a TMCL object with its transactions
+type connState struct {
+	// stats must be the first field to guarantee 64 bit alignment for atomic access
+	stats counters
+	// lastActivity is the time of the last command or keep alive in unix nanoseconds,
+	// must follow stats to be 64 bit aligned as well
+	lastActivity int64
+	// callbackGoroutine is the goroutine running a callback with the command lock held
+	callbackGoroutine int64
+	closed            int32
+
+	ComPort     string
+	baudRate    int
+	address     byte
+	hostAddress byte
+
+	port            io.ReadWriteCloser
+	ownsPort        bool
+	stopOnClose     bool
+	dial            func() (io.ReadWriteCloser, error)
+	readTimeout     time.Duration
+	cmdLock         *scheduler
+	pipelineDepth   int
+	timeout         time.Duration
+	pollInterval    time.Duration
+	adaptive        *adaptiveTimeout
+	rtt             rttTracker
+	limits          map[byte]Limits
+	commandFilter   func(cmd byte, typeNo byte) bool
+	interlocks      []Interlock
+	onInterlock     func(InterlockEvent)
+	moved           [256]bool
+	failSafe        *FailSafe
+	shutdownCtx     context.Context
+	currentCeiling  map[byte]int
+	statusTexts     map[byte]string
+	axisCount       int
+	moduleType      int
+	moduleDetected  bool
+	detectRetry     time.Time
+	storeRetries    int
+	storeRetryDelay time.Duration
+	resyncLimit     int
+	retry           RetryPolicy
+	flushPolicy     FlushPolicy
+	// desynced is set when a read failed and a late reply may still arrive
+	desynced bool
+	// allPortsUnsupported is set per bank when the module rejected reading all ports at once
+	allPortsUnsupported [3]atomic.Bool
+	// capture receives the reply telegrams of the current request, see ExecRaw
+	capture func(bts []byte)
+
+	// observers of the communication
+	logger     Logger
+	tracer     Tracer
+	sessionLog *sessionlog.Writer
+
+	// buffers reused for every command, protected by cmdLock
+	tx [frameSize]byte
+	rx [frameSize]byte
+
+	watchdogMutex sync.Mutex
+	watchdogStop  chan struct{}
+}
+
+// deadliner is implemented by transports that can interrupt a blocking read, e.g.
+// transport.Transport or net.Conn
+type deadliner interface {
+	SetDeadline(t time.Time) error
+}
 
-	port     *serial.Port
-	cmdMutex sync.Mutex
+// Option configures a TMCL object when it is created
+type Option func(q *TMCL)
+
+// WithTimeout sets the time to wait for a reply
+func WithTimeout(d time.Duration) Option {
+	return func(q *TMCL) {
+		q.timeout = d
+	}
+}
+
+// WithModuleAddress sets the serial address of the module (global parameter 66)
+func WithModuleAddress(addr byte) Option {
+	return func(q *TMCL) {
+		q.address = addr
+	}
+}
+
+// WithHostAddress makes the TMCL object only accept replies sent to the given host address
+// (global parameter 76 of the module, 2 by default). By default the host address is not checked.
+func WithHostAddress(addr byte) Option {
+	return func(q *TMCL) {
+		q.hostAddress = addr
+	}
+}
+
+// WithPortOwnership makes the TMCL object close a port passed to NewWithPort when ClosePort
+// or Close is called. By default the caller stays responsible for closing it.
+func WithPortOwnership() Option {
+	return func(q *TMCL) {
+		q.ownsPort = true
+	}
+}
+
+// WithStopOnClose makes Close stop all motors before the port is closed
+func WithStopOnClose() Option {
+	return func(q *TMCL) {
+		q.stopOnClose = true
+	}
+}
+
+// WithSessionLog writes all data sent and received to the session log, e.g. for the
+// analysis of intermittent failures. Errors writing the log are reported by its Err method.
+func WithSessionLog(w *sessionlog.Writer) Option {
+	return func(q *TMCL) {
+		q.sessionLog = w
+	}
 }
 
 // NewTMCL creates a new TMCL object
-func NewTMCL(comPort string, baudRate int) *TMCL {
-	return &TMCL{
-		ComPort:  comPort,
-		baudRate: baudRate,
+func NewTMCL(comPort string, baudRate int, opts ...Option) *TMCL {
+	q := newTMCL(opts)
+	q.ComPort = comPort
+	q.baudRate = baudRate
+	q.ownsPort = true
+	q.watchShutdown()
+	return q
+}
+
+// NewWithPort creates a new TMCL object communicating over an already open serial port or any
+// other connection, e.g. a TCP socket. If the port implements transport.Transport or has a
+// SetDeadline method, it is used to end blocking reads when the reply timeout expires.
+func NewWithPort(port io.ReadWriteCloser, opts ...Option) *TMCL {
+	q := newTMCL(nil)
+	q.port = port
+	for _, opt := range opts {
+		opt(q)
+	}
+	q.watchShutdown()
+	return q
+}
+
+// NewTCP creates a new TMCL object for modules with Ethernet interface, which accept TMCL
+// telegrams on a TCP port, usually transport.DefaultTCPPort. The connection is established with
+// the first command and re-established after it broke.
+func NewTCP(host string, port int, opts ...Option) *TMCL {
+	q := newTMCL(opts)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	q.dial = func() (io.ReadWriteCloser, error) {
+		return transport.NewTCP(address, q.timeout), nil
+	}
+	q.ownsPort = true
+	q.watchShutdown()
+	return q
+}
+
+// newTMCL creates a TMCL object with default settings and applies the options
+func newTMCL(opts []Option) *TMCL {
+	q := &TMCL{connState: &connState{
+		cmdLock:         newScheduler(),
+		pipelineDepth:   1,
+		timeout:         defaultTimeout,
+		pollInterval:    defaultPollInterval,
+		storeRetries:    defaultStoreRetries,
+		storeRetryDelay: defaultStoreRetryDelay,
+		resyncLimit:     defaultResyncLimit,
+	}}
+	for _, opt := range opts {
+		opt(q)
 	}
+	return q
+}
+
+// UseExistingPort replaces the port by an already open one. The caller stays responsible for
+// closing it, ClosePort only releases it.
+func (q *TMCL) UseExistingPort(port io.ReadWriteCloser) {
+	q.do(globalKey, func() {
+		if q.port != nil && q.ownsPort {
+			_ = q.port.Close()
+		}
+		q.port = port
+		q.ownsPort = false
+		q.dial = nil
+	})
 }
 
 // OpenPort opens the serial port
@@ -35,9 +243,19 @@ func (q *TMCL) OpenPort() error {
 	if q.port != nil {
 		return nil
 	}
+	if q.dial != nil {
+		port, err := q.dial()
+		if err != nil {
+			return err
+		}
+		q.port = port
+		return nil
+	}
+	if !q.ownsPort || q.ComPort == "" {
+		return ErrNoPort
+	}
 
-	c := &serial.Config{Name: q.ComPort, Baud: q.baudRate}
-	port, err := serial.OpenPort(c)
+	port, err := transport.OpenSerial(q.ComPort, q.baudRate, q.readTimeout)
 	if err != nil {
 		return err
 	}
@@ -45,89 +263,323 @@ func (q *TMCL) OpenPort() error {
 	return nil
 }
 
-// ClosePort closes the serial port
+// ClosePort applies the fail-safe state, if configured, and closes the serial port. A port
+// passed to NewWithPort or UseExistingPort is only released, not closed, unless
+// WithPortOwnership was given.
 func (q *TMCL) ClosePort() {
+	var open bool
+	q.do(globalKey, func() {
+		open = q.port != nil
+	})
+	if !open {
+		return
+	}
+	_ = q.ApplyFailSafe()
+	q.do(globalKey, q.closePort)
+}
+
+// closePort closes or releases the port, must be called with the command lock held
+func (q *TMCL) closePort() {
 	if q.port == nil {
 		return
 	}
-	_ = q.port.Close()
+	if q.ownsPort {
+		_ = q.port.Close()
+	}
 	q.port = nil
+	q.desynced = false
 }
 
-// Exec is the general function to call a command on the board
+// Request is a command sent to the board
+type Request struct {
+	Cmd       byte
+	Type      byte
+	MotorBank byte
+	Value     int
+
+	// Timeout overrides the reply timeout of the connection for this request
+	Timeout time.Duration
+
+	// NoReply is set for commands the board does not reply to, e.g. restore factory settings
+	NoReply bool
+
+	// Urgent requests skip the queue of waiting commands, as MST always does
+	Urgent bool
+}
+
+// Exec is the general function to call a command on the board, the reply value is
+// interpreted as signed 32 bit number
 func (q *TMCL) Exec(cmd byte, typeNo byte, motorOrBank byte, value int) (int, error) {
+	return q.ExecRequest(Request{Cmd: cmd, Type: typeNo, MotorBank: motorOrBank, Value: value})
+}
+
+// ExecRequest calls a command on the board with per request options
+func (q *TMCL) ExecRequest(req Request) (int, error) {
+	return q.ExecRequestContext(context.Background(), req)
+}
+
+// ExecContext calls a command on the board. The context can cancel waiting for the bus and
+// for the reply, its deadline shortens the reply timeout.
+func (q *TMCL) ExecContext(ctx context.Context, cmd byte, typeNo byte, motorOrBank byte, value int) (int, error) {
+	return q.ExecRequestContext(ctx, Request{Cmd: cmd, Type: typeNo, MotorBank: motorOrBank, Value: value})
+}
+
+// ExecRequestContext calls a command on the board with per request options and a context
+func (q *TMCL) ExecRequestContext(ctx context.Context, req Request) (int, error) {
+	q.KeepAlive()
+	return q.execRequest(ctx, req)
+}
+
+// ExecUnsigned calls a command and returns the reply value as unsigned 32 bit number, for
+// bit fields and counters
+func (q *TMCL) ExecUnsigned(cmd byte, typeNo byte, motorOrBank byte, value uint32) (uint32, error) {
+	v, err := q.Exec(cmd, typeNo, motorOrBank, int(int32(value)))
+	return uint32(v), err
+}
+
+// exec executes a command without counting as activity of the application
+func (q *TMCL) exec(cmd byte, typeNo byte, motorOrBank byte, value int) (int, error) {
+	return q.execRequest(context.Background(), Request{Cmd: cmd, Type: typeNo, MotorBank: motorOrBank, Value: value})
+}
+
+// execRequest executes a request without counting as activity of the application
+func (q *TMCL) execRequest(ctx context.Context, req Request) (int, error) {
+	return q.execCapture(ctx, req, nil)
+}
+
+// execCapture is execRequest calling capture with every reply telegram received, if not nil
+func (q *TMCL) execCapture(ctx context.Context, req Request, capture func(bts []byte)) (value int, err error) {
+	if q.tracer != nil {
+		end := q.tracer.Start(ctx, req)
+		defer func() { end(value, err) }()
+	}
+	if err := q.checkUsable(); err != nil {
+		return 0, err
+	}
+
 	// one command at a time
-	q.cmdMutex.Lock()
-	defer q.cmdMutex.Unlock()
+	key := schedKey(req.Cmd, req.MotorBank)
+	if req.Urgent {
+		key = urgentKey
+	}
+	if runErr := q.run(ctx, key, func() { value, err = q.execLocked(ctx, req, capture) }); runErr != nil {
+		return 0, runErr
+	}
+	return value, err
+}
 
-	// open port if not done yet
-	if err := q.OpenPort(); err != nil {
+// execLocked checks and sends a request, run by the writer
+func (q *TMCL) execLocked(ctx context.Context, req Request, capture func(bts []byte)) (int, error) {
+	req, err := q.checkRequest(req)
+	if err != nil {
 		return 0, err
 	}
+	q.capture = capture
+	defer func() { q.capture = nil }()
 
-	// create command
-	bts := make([]byte, 9)
-	bts[1] = cmd
-	bts[2] = typeNo
-	bts[3] = motorOrBank
-	buff := new(bytes.Buffer)
-	if err := binary.Write(buff, binary.BigEndian, uint32(value)); err != nil {
+	if isStoreCommand(req.Cmd, req.Type, req.MotorBank) {
+		return q.transactStore(ctx, req)
+	}
+	return q.transactRetry(ctx, req)
+}
+
+// checkRequest runs all checks of a request before it is sent and returns it with the values
+// to be sent, must be called with the command lock held
+func (q *TMCL) checkRequest(req Request) (Request, error) {
+	// check if command is permitted at all
+	if err := q.checkCommandFilter(req.Cmd, req.Type); err != nil {
+		return req, err
+	}
+
+	// check motor number
+	if err := q.checkMotor(req.Cmd, req.MotorBank); err != nil {
+		return req, err
+	}
+
+	// check parameter ranges of the module
+	if err := q.checkParamRange(req.Cmd, req.Type, req.Value); err != nil {
+		return req, err
+	}
+
+	// check safety limits
+	if err := q.checkLimits(req.Cmd, req.Type, req.MotorBank, req.Value); err != nil {
+		return req, err
+	}
+
+	// never exceed the current ceiling
+	value, err := q.applyCurrentCeiling(req.Cmd, req.Type, req.MotorBank, req.Value)
+	if err != nil {
+		return req, err
+	}
+	req.Value = value
+
+	// check interlocks before any motion
+	if err := q.checkInterlocks(req.Cmd, req.Type, req.MotorBank); err != nil {
+		return req, err
+	}
+	return req, nil
+}
+
+// transact sends a request and waits for its reply, must be called with the command lock held
+func (q *TMCL) transact(ctx context.Context, req Request) (int, error) {
+	// open port if not done yet
+	if err := q.OpenPort(); err != nil {
 		return 0, err
 	}
-	bts[4] = buff.Bytes()[0]
-	bts[5] = buff.Bytes()[1]
-	bts[6] = buff.Bytes()[2]
-	bts[7] = buff.Bytes()[3]
 
-	// calc checksum
-	bts[8] = calcChecksum(bts[:8])
+	// create command
+	q.encodeFrame(q.tx[:], req.Cmd, req.Type, req.MotorBank, req.Value)
 
 	// send
-	if _, err := q.port.Write(bts); err != nil {
+	if err := q.flushInput(); err != nil {
 		return 0, err
 	}
+	sent := time.Now()
+	if err := q.writeFrame(q.tx[:]); err != nil {
+		return 0, err
+	}
+	if req.NoReply {
+		return 0, nil
+	}
 
 	// wait for response
-	start := time.Now()
-	var buf []byte
-	for {
-		buf2 := make([]byte, 9)
-		n, err := q.port.Read(buf2)
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = q.currentTimeout()
+	}
+	value, status, err := q.readReply(ctx, req.Cmd, sent, timeout)
+	if err != nil {
+		q.logResult(req, 0, err, sent)
+		return 0, err
+	}
+	if q.capture != nil {
+		q.capture(q.rx[:])
+	}
+	if err := q.statusError(status, req); err != nil {
+		atomic.AddUint64(&q.stats.boardErrors, 1)
+		q.logResult(req, 0, err, sent)
+		return 0, err
+	}
+	q.logResult(req, value, nil, sent)
+	return value, nil
+}
+
+// writeFrame sends a request telegram with a single write, so that it is not split up
+// into several packets on USB or network links
+func (q *TMCL) writeFrame(bts []byte) error {
+	atomic.AddUint64(&q.stats.commands, 1)
+	n, err := q.port.Write(bts)
+	atomic.AddUint64(&q.stats.bytesSent, uint64(n))
+	if q.sessionLog != nil && n > 0 {
+		_ = q.sessionLog.Write(sessionlog.Sent, bts[:n])
+	}
+	if err == nil && n != len(bts) {
+		err = ErrShortWrite
+	}
+	return err
+}
+
+// readReply waits for the reply telegram of the command sent at the given time and returns
+// its value and status code. Replies of other modules on the bus, to other hosts and to
+// other commands are skipped.
+func (q *TMCL) readReply(ctx context.Context, cmd byte, sent time.Time, timeout time.Duration) (value int, status byte, err error) {
+	defer func() {
 		if err != nil {
-			return 0, err
+			q.desynced = true
 		}
-		if n != 0 {
-			buf = append(buf, buf2[:n]...)
-		}
-		if len(buf) < 9 {
-			if time.Since(start) > timeout {
-				return 0, errors.New("timeout")
+	}()
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(sent) < timeout {
+		timeout = deadline.Sub(sent)
+	}
+	// let blocking reads return in time
+	if t, ok := q.port.(deadliner); ok {
+		_ = t.SetDeadline(sent.Add(timeout))
+		defer func() { _ = t.SetDeadline(time.Time{}) }()
+	}
+
+	buf := q.rx[:]
+	var n, skipped int
+	var staleEcho byte
+	for {
+		if err := q.readFull(ctx, buf, n, sent, timeout); err != nil {
+			if staleEcho != 0 && err == ErrTimeout {
+				return 0, 0, echoError(cmd, staleEcho)
 			}
+			return 0, 0, err
+		}
 
-			time.Sleep(time.Millisecond)
+		// on garbage drop the first byte and look for a reply in the remaining ones
+		if !q.plausibleReply(buf) {
+			if skipped == 0 {
+				atomic.AddUint64(&q.stats.checksumErrors, 1)
+			}
+			if skipped >= q.resyncLimit {
+				return 0, 0, ErrChecksum
+			}
+			skipped++
+			atomic.AddUint64(&q.stats.skippedBytes, 1)
+			n = copy(buf, buf[1:])
 			continue
 		}
+		n = 0
 
-		// check checksum
-		if buf[8] != calcChecksum(buf[:8]) {
-			return 0, errors.New("checksum invalid")
+		// skip replies of other modules or to other hosts
+		if q.address != 0 && buf[1] != q.address {
+			continue
+		}
+		if q.hostAddress != 0 && buf[0] != q.hostAddress {
+			continue
 		}
 
-		// check status code
-		if buf[2] != 100 {
-			return 0, errors.New("board returned error code " + strconv.Itoa(int(buf[2])))
+		// drop stale replies to earlier commands, e.g. one that timed out
+		if buf[3] != cmd {
+			staleEcho = buf[3]
+			atomic.AddUint64(&q.stats.staleReplies, 1)
+			continue
 		}
+		q.rtt.add(time.Since(sent))
 
 		// return result
-		return int(binary.BigEndian.Uint32(buf[4:8])), nil
+		return protocol.ReplyValue(buf), buf[2], nil
 	}
 }
 
-// calcChecksum calculates the checksum by adding up all bytes
-func calcChecksum(bts []byte) byte {
-	var x byte
-	for _, b := range bts {
-		x += b
+// readFull reads from the port until buf is filled, n bytes of it being there already, or
+// the timeout since sent expired
+func (q *TMCL) readFull(ctx context.Context, buf []byte, n int, sent time.Time, timeout time.Duration) error {
+	for n < len(buf) {
+		m, err := q.port.Read(buf[n:])
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			atomic.AddUint64(&q.stats.timeouts, 1)
+			return ErrTimeout
+		}
+		if err != nil {
+			return err
+		}
+		if m != 0 {
+			if q.sessionLog != nil {
+				_ = q.sessionLog.Write(sessionlog.Received, buf[n:n+m])
+			}
+			n += m
+			atomic.AddUint64(&q.stats.bytesReceived, uint64(m))
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if time.Since(sent) > timeout {
+			atomic.AddUint64(&q.stats.timeouts, 1)
+			return ErrTimeout
+		}
+		time.Sleep(q.pollInterval)
 	}
-	return x
+	return nil
+}
+
+// encodeFrame writes a request telegram including module address and checksum into bts
+func (q *TMCL) encodeFrame(bts []byte, cmd byte, typeNo byte, motorOrBank byte, value int) {
+	protocol.EncodeRequest(bts, q.address, cmd, typeNo, motorOrBank, value)
 }
@@ -0,0 +1,117 @@
+package tmcl_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/protocol"
+	"github.com/raceresult/go-tmcl/tmcltest"
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// noisyConn is a connection to a simulated module which receives noise before the replies
+type noisyConn struct {
+	transport.Transport
+
+	mutex sync.Mutex
+	noise []byte
+}
+
+// inject makes the next read return noise
+func (c *noisyConn) inject(noise []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.noise = noise
+}
+
+// Read returns the noise first
+func (c *noisyConn) Read(b []byte) (int, error) {
+	c.mutex.Lock()
+	if len(c.noise) != 0 {
+		n := copy(b, c.noise)
+		c.noise = c.noise[n:]
+		c.mutex.Unlock()
+		return n, nil
+	}
+	c.mutex.Unlock()
+	return c.Transport.Read(b)
+}
+
+// replyFrame returns a reply telegram of the simulated module
+func replyFrame(cmd byte, value int) []byte {
+	return replyFrameFrom(2, 1, cmd, value)
+}
+
+// replyFrameFrom returns a reply telegram of the given module to the given host
+func replyFrameFrom(host byte, module byte, cmd byte, value int) []byte {
+	bts := []byte{host, module, tmcl.StatusOK, cmd, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(bts[4:8], uint32(value))
+	bts[8] = protocol.Checksum(bts[:8])
+	return bts
+}
+
+func TestReplyResync(t *testing.T) {
+	tests := []struct {
+		name        string
+		noise       []byte
+		opts        []tmcl.Option
+		resyncLimit int
+		err         error
+		skipped     uint64
+		stale       uint64
+	}{
+		{name: "clean", resyncLimit: 18},
+		{name: "one byte", noise: []byte{0x55}, resyncLimit: 18, skipped: 1},
+		{name: "partial telegram", noise: replyFrame(6, 7)[:5], resyncLimit: 18, skipped: 5},
+		{name: "at the limit", noise: bytes.Repeat([]byte{0x55}, 18), resyncLimit: 18, skipped: 18},
+		{name: "beyond the limit", noise: bytes.Repeat([]byte{0x55}, 19), resyncLimit: 18, err: tmcl.ErrChecksum, skipped: 18},
+		{name: "no resync", noise: []byte{0x55}, err: tmcl.ErrChecksum},
+		{name: "stale reply", noise: replyFrame(5, 0), resyncLimit: 18, stale: 1},
+		{name: "garbage and stale reply", noise: append([]byte{0xaa, 0x55}, replyFrame(5, 0)...), resyncLimit: 18, skipped: 2, stale: 1},
+		{name: "other module", noise: replyFrameFrom(2, 5, 6, 0), opts: []tmcl.Option{tmcl.WithModuleAddress(1)}},
+		{name: "other host", noise: replyFrameFrom(3, 1, 6, 0), opts: []tmcl.Option{tmcl.WithHostAddress(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tmcltest.NewModule()
+			m.SetAxisParam(0, 4, 123)
+			conn := &noisyConn{Transport: m.Conn()}
+			q := tmcl.NewWithPort(conn, tt.opts...)
+			q.SetResyncLimit(tt.resyncLimit)
+
+			// the first command also detects the module
+			if _, err := q.GAP(4, 0); err != nil {
+				t.Fatal(err)
+			}
+			conn.inject(tt.noise)
+
+			v, err := q.GAP(4, 0)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error %v, want %v", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if v != 123 {
+				t.Errorf("value %d, want 123", v)
+			}
+
+			s := q.Stats()
+			if s.SkippedBytes != tt.skipped || s.StaleReplies != tt.stale {
+				t.Errorf("skipped %d, stale %d, want %d, %d", s.SkippedBytes, s.StaleReplies, tt.skipped, tt.stale)
+			}
+			if tt.skipped != 0 && s.ChecksumErrors != 1 {
+				t.Errorf("%d checksum errors, want 1", s.ChecksumErrors)
+			}
+
+			// the next command is in sync again
+			if v, err := q.GAP(4, 0); err != nil || v != 123 {
+				t.Errorf("next command: %d, %v", v, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Package tmclotel emits an OpenTelemetry span for every request of a TMCL connection
+package tmclotel
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	tmcl "github.com/raceresult/go-tmcl"
+)
+
+// instrumentationName is the name of the tracer
+const instrumentationName = "github.com/raceresult/go-tmcl"
+
+// tracer implements tmcl.Tracer
+type tracer struct {
+	t trace.Tracer
+}
+
+// New returns a tmcl.Tracer starting a client span per request with a tracer of tp, as
+// child of the span in the context of the caller. The span covers waiting for the bus.
+func New(tp trace.TracerProvider) tmcl.Tracer {
+	return tracer{t: tp.Tracer(instrumentationName)}
+}
+
+// Start implements tmcl.Tracer
+func (q tracer) Start(ctx context.Context, req tmcl.Request) func(value int, err error) {
+	op := tmcl.Opcode(req.Cmd).String()
+	_, span := q.t.Start(ctx, "tmcl "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("tmcl.command", op),
+			attribute.Int("tmcl.type", int(req.Type)),
+			attribute.Int("tmcl.motor_bank", int(req.MotorBank)),
+			attribute.Int("tmcl.value", req.Value),
+		))
+
+	return func(value int, err error) {
+		defer span.End()
+		if err == nil {
+			span.SetAttributes(
+				attribute.Int("tmcl.status_code", int(tmcl.StatusOK)),
+				attribute.Int("tmcl.reply", value))
+			return
+		}
+		var boardErr *tmcl.Error
+		if errors.As(err, &boardErr) {
+			span.SetAttributes(attribute.Int("tmcl.status_code", int(boardErr.Code)))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
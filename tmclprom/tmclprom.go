@@ -0,0 +1,77 @@
+// Package tmclprom exports the command metrics of TMCL connections as Prometheus collector, so
+// that they can be registered next to the other metrics of an application
+package tmclprom
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	tmcl "github.com/raceresult/go-tmcl"
+)
+
+// Collector implements prometheus.Collector over the metrics of a connection, labeled by
+// command
+type Collector struct {
+	m *tmcl.Metrics
+
+	commands *prometheus.Desc
+	errors   *prometheus.Desc
+	timeouts *prometheus.Desc
+	retries  *prometheus.Desc
+	duration *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a collector of the metrics. The labels are added to all series to tell
+// several connections apart, e.g. by port.
+func NewCollector(m *tmcl.Metrics, labels map[string]string) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, []string{"command"}, labels)
+	}
+	return &Collector{
+		m:        m,
+		commands: desc("tmcl_commands_total", "Number of requests sent to the module."),
+		errors:   desc("tmcl_command_errors_total", "Number of requests that failed for other reasons than a timeout."),
+		timeouts: desc("tmcl_command_timeouts_total", "Number of requests without reply in time."),
+		retries:  desc("tmcl_command_retries_total", "Number of repeated requests."),
+		duration: desc("tmcl_command_duration_seconds", "Time from sending a request until its reply arrived."),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.commands
+	ch <- c.errors
+	ch <- c.timeouts
+	ch <- c.retries
+	ch <- c.duration
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	bounds := c.m.Buckets()
+	for op, s := range c.m.Snapshot() {
+		command := op.String()
+		ch <- prometheus.MustNewConstMetric(c.commands, prometheus.CounterValue, float64(s.Count), command)
+		ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(s.Errors), command)
+		ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.Timeouts), command)
+		ch <- prometheus.MustNewConstMetric(c.retries, prometheus.CounterValue, float64(s.Retries), command)
+
+		buckets := make(map[float64]uint64, len(bounds))
+		for i, bound := range bounds {
+			buckets[bound.Seconds()] = s.Buckets[i]
+		}
+		ch <- prometheus.MustNewConstHistogram(c.duration, s.Count, s.LatencySum.Seconds(), buckets, command)
+	}
+}
+
+// Handler returns an HTTP handler serving only the metrics of the connection, e.g. mounted at
+// /metrics. Applications with own metrics register a Collector with their registry instead.
+func Handler(m *tmcl.Metrics, labels map[string]string) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector(m, labels))
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
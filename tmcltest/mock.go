@@ -0,0 +1,271 @@
+package tmcltest
+
+import (
+	"fmt"
+	"sync"
+
+	tmcl "github.com/raceresult/go-tmcl"
+)
+
+// Response is a scripted result of a command
+type Response struct {
+	Value int
+	Err   error
+}
+
+// scriptKey selects the requests a script applies to, anyTarget matching every type and
+// motor/bank
+type scriptKey struct {
+	cmd, typeNo, motorOrBank byte
+	anyTarget                bool
+}
+
+// MockBoard implements tmcl.Board without a module: it records all calls as requests and
+// returns scripted responses, so that the exact command sequence of application code can be
+// checked. Unscripted commands succeed with value 0, except GAP and GGP returning the
+// value last set with SAP and SGP.
+type MockBoard struct {
+	mutex   sync.Mutex
+	calls   []tmcl.Request
+	scripts map[scriptKey][]Response
+	params  map[scriptKey]int
+}
+
+var _ tmcl.Board = (*MockBoard)(nil)
+
+// NewMockBoard returns a mock board without scripts
+func NewMockBoard() *MockBoard {
+	return &MockBoard{
+		scripts: map[scriptKey][]Response{},
+		params:  map[scriptKey]int{},
+	}
+}
+
+// Script sets the responses to a command, used one per call in the given order, the last
+// one repeating
+func (q *MockBoard) Script(cmd byte, responses ...Response) {
+	q.script(scriptKey{cmd: cmd, anyTarget: true}, responses)
+}
+
+// ScriptRequest is Script for the requests with the given type and motor/bank only, which
+// takes precedence over Script
+func (q *MockBoard) ScriptRequest(cmd byte, typeNo byte, motorOrBank byte, responses ...Response) {
+	q.script(scriptKey{cmd: cmd, typeNo: typeNo, motorOrBank: motorOrBank}, responses)
+}
+
+// script stores the responses of a script key
+func (q *MockBoard) script(key scriptKey, responses []Response) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(responses) == 0 {
+		delete(q.scripts, key)
+		return
+	}
+	q.scripts[key] = responses
+}
+
+// Calls returns all requests received so far
+func (q *MockBoard) Calls() []tmcl.Request {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return append([]tmcl.Request(nil), q.calls...)
+}
+
+// Count returns how often a command was called
+func (q *MockBoard) Count(cmd byte) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var n int
+	for _, c := range q.calls {
+		if c.Cmd == cmd {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset forgets the recorded calls, scripts and parameters
+func (q *MockBoard) Reset() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.calls = nil
+	q.scripts = map[scriptKey][]Response{}
+	q.params = map[scriptKey]int{}
+}
+
+// Exec records a request and returns its response
+func (q *MockBoard) Exec(cmd byte, typeNo byte, motorOrBank byte, value int) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.calls = append(q.calls, tmcl.Request{Cmd: cmd, Type: typeNo, MotorBank: motorOrBank, Value: value})
+
+	for _, key := range []scriptKey{{cmd: cmd, typeNo: typeNo, motorOrBank: motorOrBank}, {cmd: cmd, anyTarget: true}} {
+		responses, ok := q.scripts[key]
+		if !ok {
+			continue
+		}
+		r := responses[0]
+		if len(responses) > 1 {
+			q.scripts[key] = responses[1:]
+		}
+		return r.Value, r.Err
+	}
+
+	// parameters read back what was set
+	switch cmd {
+	case 5, 9:
+		q.params[scriptKey{cmd: cmd, typeNo: typeNo, motorOrBank: motorOrBank}] = value
+	case 6, 10:
+		return q.params[scriptKey{cmd: cmd - 1, typeNo: typeNo, motorOrBank: motorOrBank}], nil
+	}
+	return 0, nil
+}
+
+// ROR is rotate right
+func (q *MockBoard) ROR(motor byte, velocity int) error {
+	_, err := q.Exec(1, 0, motor, velocity)
+	return err
+}
+
+// ROL is rotate left
+func (q *MockBoard) ROL(motor byte, velocity int) error {
+	_, err := q.Exec(2, 0, motor, velocity)
+	return err
+}
+
+// MST is motor stop
+func (q *MockBoard) MST(motor byte) error {
+	_, err := q.Exec(3, 0, motor, 0)
+	return err
+}
+
+// MVP is move to position
+func (q *MockBoard) MVP(mode byte, motor byte, value int) error {
+	_, err := q.Exec(4, mode, motor, value)
+	return err
+}
+
+// RFSStart starts the reference search of a motor
+func (q *MockBoard) RFSStart(motor byte) error {
+	_, err := q.Exec(13, 0, motor, 0)
+	return err
+}
+
+// RFSStop aborts the reference search of a motor
+func (q *MockBoard) RFSStop(motor byte) error {
+	_, err := q.Exec(13, 1, motor, 0)
+	return err
+}
+
+// RFSStatus returns true while the reference search of a motor is running
+func (q *MockBoard) RFSStatus(motor byte) (bool, error) {
+	v, err := q.Exec(13, 2, motor, 0)
+	return v != 0, err
+}
+
+// SAP is set axis parameter
+func (q *MockBoard) SAP(index byte, motor byte, value int) error {
+	_, err := q.Exec(5, index, motor, value)
+	return err
+}
+
+// GAP is get axis parameter
+func (q *MockBoard) GAP(index byte, motor byte) (int, error) {
+	return q.Exec(6, index, motor, 0)
+}
+
+// STAP is store axis parameter
+func (q *MockBoard) STAP(index byte, motor byte) error {
+	_, err := q.Exec(7, index, motor, 0)
+	return err
+}
+
+// RSAP is restore axis parameter
+func (q *MockBoard) RSAP(index byte, motor byte) error {
+	_, err := q.Exec(8, index, motor, 0)
+	return err
+}
+
+// SGP is set global parameter
+func (q *MockBoard) SGP(index byte, bank byte, value int) error {
+	_, err := q.Exec(9, index, bank, value)
+	return err
+}
+
+// GGP is get global parameter
+func (q *MockBoard) GGP(index byte, bank byte) (int, error) {
+	return q.Exec(10, index, bank, 0)
+}
+
+// STGP is store global parameter
+func (q *MockBoard) STGP(index byte, bank byte) (int, error) {
+	return q.Exec(11, index, bank, 0)
+}
+
+// RSGP is restore global parameter
+func (q *MockBoard) RSGP(index byte, bank byte) (int, error) {
+	return q.Exec(12, index, bank, 0)
+}
+
+// SIO is set io
+func (q *MockBoard) SIO(port byte, bank byte, value bool) error {
+	_, err := q.Exec(14, port, bank, boolInt(value))
+	return err
+}
+
+// GIO is get io
+func (q *MockBoard) GIO(port byte, bank byte) (int, error) {
+	return q.Exec(15, port, bank, 0)
+}
+
+// StopApplication stops the standalone application
+func (q *MockBoard) StopApplication() error {
+	_, err := q.Exec(128, 0, 0, 0)
+	return err
+}
+
+// RunApplication starts the standalone application
+func (q *MockBoard) RunApplication() error {
+	_, err := q.Exec(129, 0, 0, 0)
+	return err
+}
+
+// RunApplicationAt starts the standalone application at the given address
+func (q *MockBoard) RunApplicationAt(address int) error {
+	_, err := q.Exec(129, 1, 0, address)
+	return err
+}
+
+// StepApplication executes the next instruction of the standalone application
+func (q *MockBoard) StepApplication() error {
+	_, err := q.Exec(130, 0, 0, 0)
+	return err
+}
+
+// ResetApplication resets the program counter of the standalone application
+func (q *MockBoard) ResetApplication() error {
+	_, err := q.Exec(131, 0, 0, 0)
+	return err
+}
+
+// GetApplicationStatus returns the state of the standalone application
+func (q *MockBoard) GetApplicationStatus() (int, error) {
+	return q.Exec(135, 0, 0, 0)
+}
+
+// GetFirmwareVersion returns the binary version as hex string, like tmcl.TMCL does
+func (q *MockBoard) GetFirmwareVersion() (string, error) {
+	v, err := q.Exec(136, 1, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%08X", uint32(v)), nil
+}
+
+// boolInt returns 1 for true
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package tmcltest
+
+import (
+	"time"
+
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/sim"
+)
+
+// Module simulates the module side of the TMCL protocol, see package sim
+type Module = sim.Module
+
+// Option configures a Module when it is created
+type Option = sim.Option
+
+// Switches are the end switches of a simulated motor
+type Switches = sim.Switches
+
+// WithAddress sets the module address (default 1)
+func WithAddress(addr byte) Option {
+	return sim.WithAddress(addr)
+}
+
+// WithHostAddress sets the host address replies are sent to (default 2)
+func WithHostAddress(addr byte) Option {
+	return sim.WithHostAddress(addr)
+}
+
+// WithVersion sets module type and firmware revision (default 351V4.45)
+func WithVersion(v tmcl.Version) Option {
+	return sim.WithVersion(v)
+}
+
+// WithMotors sets the number of motors (default 3)
+func WithMotors(n int) Option {
+	return sim.WithMotors(n)
+}
+
+// WithClock sets the clock the motors move by (default time.Now)
+func WithClock(now func() time.Time) Option {
+	return sim.WithClock(now)
+}
+
+// NewModule returns a simulated module
+func NewModule(opts ...Option) *Module {
+	return sim.NewModule(opts...)
+}
@@ -0,0 +1,23 @@
+// Package tmcltest provides a simulator and a mock of TMCL modules, so that applications can be
+// tested without hardware
+package tmcltest
+
+import tmcl "github.com/raceresult/go-tmcl"
+
+// Simulator is a simulated module behind a TMCL connection, so that it implements tmcl.Board
+// and all other methods of tmcl.TMCL with the checks of a real connection. The motors move
+// in real time with the ramps given by their maximum speed and acceleration, stop at their
+// end switches and report reaching the target position. Module gives access to the
+// simulation, e.g. to set inputs or add end switches.
+type Simulator struct {
+	*tmcl.TMCL
+	Module *Module
+}
+
+var _ tmcl.Board = (*Simulator)(nil)
+
+// NewSimulator returns a simulator of a module configured with opts
+func NewSimulator(opts ...Option) *Simulator {
+	m := NewModule(opts...)
+	return &Simulator{TMCL: m.Connect(), Module: m}
+}
@@ -0,0 +1,19 @@
+package tmcl
+
+import "context"
+
+// Tracer is informed about every request before it waits for the bus, e.g. to start a span
+// of a distributed trace as done by the tmclotel package. It is called without the command
+// lock held.
+type Tracer interface {
+	// Start is called with the context of the caller, the returned function with the reply
+	// value and the error once the request finished
+	Start(ctx context.Context, req Request) (end func(value int, err error))
+}
+
+// WithTracer sets the tracer informed about every request
+func WithTracer(t Tracer) Option {
+	return func(q *TMCL) {
+		q.tracer = t
+	}
+}
@@ -0,0 +1,75 @@
+package tmcl
+
+import "context"
+
+// txn is a transaction. Its job on the writer serves only the commands of the transaction
+// until it ends.
+type txn struct {
+	jobs chan *job
+	done chan struct{}
+}
+
+// WithTransaction holds the bus while fn runs, so that the commands fn issues through tx are
+// not interleaved with commands of other goroutines or other modules of the same Bus. tx
+// shares the connection and all settings with q; commands issued through q or other handles
+// wait until fn returned, after that tx behaves like q.
+func (q *TMCL) WithTransaction(fn func(tx *TMCL) error) error {
+	if err := q.checkUsable(); err != nil {
+		return err
+	}
+	if q.txn != nil {
+		// nested transaction, the bus is held already
+		return fn(q)
+	}
+
+	t := &txn{jobs: make(chan *job), done: make(chan struct{})}
+	started := make(chan struct{})
+	j := q.cmdLock.push(globalKey, func() {
+		close(started)
+		t.serve()
+	})
+	q.cmdLock.jobs <- j
+	<-started
+	defer func() {
+		close(t.done)
+		<-j.done
+	}()
+	return fn(&TMCL{connState: q.connState, txn: t})
+}
+
+// serve runs the commands of the transaction on the writer until it ends
+func (t *txn) serve() {
+	for {
+		select {
+		case j := <-t.jobs:
+			runJob(j)
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// run runs fn within the transaction, returning false if the transaction ended already
+func (t *txn) run(ctx context.Context, fn func()) (bool, error) {
+	j := &job{fn: fn, done: make(chan struct{})}
+	if ok, err := t.send(ctx, j); !ok || err != nil {
+		return ok, err
+	}
+	<-j.done
+	if j.panic != nil {
+		panic(j.panic)
+	}
+	return true, nil
+}
+
+// send hands a job over to the writer serving the transaction
+func (t *txn) send(ctx context.Context, j *job) (bool, error) {
+	select {
+	case t.jobs <- j:
+		return true, nil
+	case <-t.done:
+		return false, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
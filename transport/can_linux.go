@@ -0,0 +1,133 @@
+//go:build linux
+
+package transport
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// canFrameSize is the size of struct can_frame
+const canFrameSize = 16
+
+// canDataSize is the number of data bytes of a TMCL CAN frame
+const canDataSize = 7
+
+// ErrIncompleteTelegram is returned for writes to the CAN transport which are no complete telegram
+var ErrIncompleteTelegram = errors.New("CAN transport can only send complete telegrams")
+
+// socketCAN exchanges TMCL telegrams as CAN frames. Requests are sent with the module address
+// as 11 bit identifier and 7 data bytes (command, type, motor/bank, value), replies have the
+// reply address as identifier and 7 data bytes (module address, status, command, value).
+// Neither has a checksum, so both are converted from and to the serial telegram format.
+type socketCAN struct {
+	file     *os.File
+	moduleID uint32
+	tx       [canFrameSize]byte
+	rx       [canFrameSize]byte
+	reply    [telegramSize]byte
+	pending  []byte
+}
+
+// OpenCAN opens a SocketCAN interface, e.g. "can0". Requests are sent to the CAN identifier
+// given by the address byte of the telegram, or to moduleID if the address is 0. Only frames
+// with identifier replyID are received.
+func OpenCAN(iface string, moduleID uint32, replyID uint32) (Transport, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, unix.CAN_RAW)
+	if err != nil {
+		return nil, errors.Wrap(err, "socket")
+	}
+	filter := []unix.CanFilter{{Id: replyID, Mask: unix.CAN_SFF_MASK | unix.CAN_EFF_FLAG | unix.CAN_RTR_FLAG}}
+	if err := unix.SetsockoptCanRawFilter(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_FILTER, filter); err != nil {
+		_ = unix.Close(fd)
+		return nil, errors.Wrap(err, "filter")
+	}
+	if err := unix.Bind(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		_ = unix.Close(fd)
+		return nil, errors.Wrapf(err, "bind %s", iface)
+	}
+
+	// the non-blocking descriptor is handled by the runtime poller, so deadlines work
+	return &socketCAN{file: os.NewFile(uintptr(fd), iface), moduleID: moduleID}, nil
+}
+
+// Write sends one or several complete request telegrams as CAN frames
+func (q *socketCAN) Write(b []byte) (int, error) {
+	if len(b)%telegramSize != 0 {
+		return 0, ErrIncompleteTelegram
+	}
+	for n := 0; n < len(b); n += telegramSize {
+		t := b[n : n+telegramSize]
+		id := uint32(t[0])
+		if id == 0 {
+			id = q.moduleID
+		}
+		binary.NativeEndian.PutUint32(q.tx[0:4], id)
+		q.tx[4] = canDataSize
+		copy(q.tx[8:], t[1:1+canDataSize])
+		q.tx[15] = 0
+		if _, err := q.file.Write(q.tx[:]); err != nil {
+			return n, err
+		}
+	}
+	return len(b), nil
+}
+
+// Read returns reply telegrams converted to the serial format
+func (q *socketCAN) Read(b []byte) (int, error) {
+	for len(q.pending) == 0 {
+		n, err := q.file.Read(q.rx[:])
+		if err != nil {
+			return 0, err
+		}
+		if n < canFrameSize || q.rx[4] < canDataSize {
+			continue
+		}
+		q.reply[0] = byte(binary.NativeEndian.Uint32(q.rx[0:4]) & unix.CAN_SFF_MASK)
+		copy(q.reply[1:], q.rx[8:8+canDataSize])
+		q.reply[telegramSize-1] = 0
+		for _, x := range q.reply[:telegramSize-1] {
+			q.reply[telegramSize-1] += x
+		}
+		q.pending = q.reply[:]
+	}
+	n := copy(b, q.pending)
+	q.pending = q.pending[n:]
+	return n, nil
+}
+
+// Flush discards a partly read reply and all frames received so far
+func (q *socketCAN) Flush() error {
+	q.pending = nil
+	defer func() { _ = q.file.SetReadDeadline(time.Time{}) }()
+	for {
+		if err := q.file.SetReadDeadline(time.Now().Add(flushTimeout)); err != nil {
+			return err
+		}
+		if _, err := q.file.Read(q.rx[:]); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// SetDeadline sets the deadline for Read
+func (q *socketCAN) SetDeadline(t time.Time) error {
+	return q.file.SetReadDeadline(t)
+}
+
+// Close closes the socket
+func (q *socketCAN) Close() error {
+	return q.file.Close()
+}
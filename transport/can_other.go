@@ -0,0 +1,10 @@
+//go:build !linux
+
+package transport
+
+import "github.com/pkg/errors"
+
+// OpenCAN opens a SocketCAN interface, which is only available on Linux
+func OpenCAN(iface string, moduleID uint32, replyID uint32) (Transport, error) {
+	return nil, errors.New("SocketCAN is only supported on Linux")
+}
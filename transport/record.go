@@ -0,0 +1,226 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrReplayMismatch is returned by Write of a Replay if the data differs from the recording
+var ErrReplayMismatch = errors.New("request differs from recording")
+
+// record directions, the first field of a line of a recording
+const (
+	recordWrite = ">"
+	recordRead  = "<"
+	recordFlush = "!"
+)
+
+// Recorder is a transport that logs all data written to and read from another transport,
+// one line per call: "> " and the hex data for writes, "< " for reads and "!" for flushes.
+// The recording can be served back with NewReplay.
+type Recorder struct {
+	Transport
+
+	mutex sync.Mutex
+	w     io.Writer
+	err   error
+}
+
+// NewRecorder returns a transport recording the traffic of t to w
+func NewRecorder(t Transport, w io.Writer) *Recorder {
+	return &Recorder{Transport: t, w: w}
+}
+
+// Err returns the first error writing the recording
+func (q *Recorder) Err() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.err
+}
+
+// Write writes to the transport and records the data
+func (q *Recorder) Write(b []byte) (int, error) {
+	n, err := q.Transport.Write(b)
+	if n > 0 {
+		q.record(recordWrite, b[:n])
+	}
+	return n, err
+}
+
+// Read reads from the transport and records the data
+func (q *Recorder) Read(b []byte) (int, error) {
+	n, err := q.Transport.Read(b)
+	if n > 0 {
+		q.record(recordRead, b[:n])
+	}
+	return n, err
+}
+
+// Flush flushes the transport and records it
+func (q *Recorder) Flush() error {
+	q.record(recordFlush, nil)
+	return q.Transport.Flush()
+}
+
+// record writes one line of the recording, errors are kept for Err
+func (q *Recorder) record(direction string, data []byte) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.err != nil {
+		return
+	}
+	line := direction
+	if data != nil {
+		line += " " + hex.EncodeToString(data)
+	}
+	_, q.err = fmt.Fprintln(q.w, line)
+}
+
+// recordEntry is one line of a recording
+type recordEntry struct {
+	direction string
+	data      []byte
+}
+
+// Replay is a transport serving a recording: every write must match the next recorded
+// write and makes the reads recorded after it available. A read with nothing left to read
+// fails with os.ErrDeadlineExceeded right away if a deadline is set, so recorded timeouts
+// replay without waiting.
+type Replay struct {
+	mutex    sync.Mutex
+	entries  []recordEntry
+	pending  []byte
+	deadline time.Time
+	closed   bool
+}
+
+// NewReplay reads a recording of a Recorder. Empty lines and lines starting with # are ignored.
+func NewReplay(r io.Reader) (*Replay, error) {
+	q := &Replay{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		e := recordEntry{direction: fields[0]}
+		switch {
+		case e.direction == recordFlush && len(fields) == 1:
+		case (e.direction == recordWrite || e.direction == recordRead) && len(fields) == 2:
+			data, err := hex.DecodeString(fields[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+			e.data = data
+		default:
+			return nil, errors.Errorf("line %d: invalid record %q", line, text)
+		}
+		q.entries = append(q.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Remaining returns the number of recorded writes not replayed yet
+func (q *Replay) Remaining() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var n int
+	for _, e := range q.entries {
+		if e.direction == recordWrite {
+			n++
+		}
+	}
+	return n
+}
+
+// Write compares the data with the next recorded write
+func (q *Replay) Write(b []byte) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return 0, os.ErrClosed
+	}
+	q.skipReads()
+	if len(q.entries) == 0 || q.entries[0].direction != recordWrite {
+		return 0, errors.Wrapf(ErrReplayMismatch, "unexpected write %x", b)
+	}
+	want := q.entries[0].data
+	if !bytes.HasPrefix(want, b) {
+		return 0, errors.Wrapf(ErrReplayMismatch, "write %x, recorded %x", b, want)
+	}
+	if len(b) < len(want) {
+		// the rest of the recorded write follows with the next call
+		q.entries[0].data = want[len(b):]
+		return len(b), nil
+	}
+	q.entries = q.entries[1:]
+	q.skipReads()
+	return len(b), nil
+}
+
+// skipReads makes the reads at the start of the recording available, must be called with
+// the mutex held
+func (q *Replay) skipReads() {
+	for len(q.entries) > 0 && q.entries[0].direction == recordRead {
+		q.pending = append(q.pending, q.entries[0].data...)
+		q.entries = q.entries[1:]
+	}
+}
+
+// Read returns the recorded replies of the writes so far
+func (q *Replay) Read(b []byte) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return 0, os.ErrClosed
+	}
+	if len(q.pending) == 0 {
+		if !q.deadline.IsZero() {
+			return 0, os.ErrDeadlineExceeded
+		}
+		return 0, nil
+	}
+	n := copy(b, q.pending)
+	q.pending = q.pending[n:]
+	return n, nil
+}
+
+// Flush discards the replies not read yet, a recorded flush is skipped
+func (q *Replay) Flush() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.pending = nil
+	if len(q.entries) > 0 && q.entries[0].direction == recordFlush {
+		q.entries = q.entries[1:]
+	}
+	return nil
+}
+
+// SetDeadline sets the deadline for Read, only whether there is one matters
+func (q *Replay) SetDeadline(t time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.deadline = t
+	return nil
+}
+
+// Close closes the replay
+func (q *Replay) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	return nil
+}
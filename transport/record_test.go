@@ -0,0 +1,123 @@
+package transport_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	tmcl "github.com/raceresult/go-tmcl"
+	"github.com/raceresult/go-tmcl/sim"
+	"github.com/raceresult/go-tmcl/transport"
+)
+
+// session is a sequence of commands recorded and replayed
+func session(q *tmcl.TMCL) ([]int, error) {
+	if err := q.SAP(4, 0, 1000); err != nil {
+		return nil, err
+	}
+	if err := q.MVP(tmcl.ABS, 0, 500); err != nil {
+		return nil, err
+	}
+	var values []int
+	for _, p := range []byte{0, 4, 5} {
+		v, err := q.GAP(p, 0)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	var recording bytes.Buffer
+	rec := transport.NewRecorder(sim.NewModule().Conn(), &recording)
+	want, err := session(tmcl.NewWithPort(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := transport.NewReplay(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := session(tmcl.NewWithPort(replay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("value %d replayed as %d instead of %d", i, got[i], want[i])
+		}
+	}
+	if n := replay.Remaining(); n != 0 {
+		t.Errorf("%d recorded writes not replayed", n)
+	}
+
+	// a different request does not match the recording
+	replay, err = transport.NewReplay(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tmcl.NewWithPort(replay).SAP(4, 0, 999); !errors.Is(err, transport.ErrReplayMismatch) {
+		t.Errorf("error %v, want ErrReplayMismatch", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name      string
+		recording string
+		flush     bool
+		writes    []string
+		reads     string
+		err       string
+	}{
+		{name: "reply", recording: "> 0102\n< 0a0b\n", writes: []string{"\x01\x02"}, reads: "\x0a\x0b"},
+		{name: "split write", recording: "> 010203\n< 0a\n", writes: []string{"\x01", "\x02\x03"}, reads: "\x0a"},
+		{name: "comments", recording: "# session\n\n> 01\n< 0a\n< 0b\n", writes: []string{"\x01"}, reads: "\x0a\x0b"},
+		{name: "flush", recording: "!\n> 01\n< 0a\n", flush: true, writes: []string{"\x01"}, reads: "\x0a"},
+		{name: "flush missing", recording: "!\n> 01\n", writes: []string{"\x01"}, err: "unexpected write 01"},
+		{name: "mismatch", recording: "> 01\n", writes: []string{"\x02"}, err: "write 02, recorded 01"},
+		{name: "unexpected write", recording: "> 01\n", writes: []string{"\x01", "\x01"}, err: "unexpected write 01"},
+		{name: "invalid hex", recording: "> 0g\n", err: "line 1"},
+		{name: "invalid record", recording: "> 01\n? 02\n", err: `line 2: invalid record "? 02"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay, err := transport.NewReplay(strings.NewReader(tt.recording))
+			if err == nil && tt.flush {
+				err = replay.Flush()
+			}
+			if err == nil {
+				for _, w := range tt.writes {
+					if _, err = replay.Write([]byte(w)); err != nil {
+						break
+					}
+				}
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf := make([]byte, 16)
+			n, err := replay.Read(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(buf[:n]); got != tt.reads {
+				t.Errorf("read %x, want %x", got, tt.reads)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"os"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// bugSerial is a serial port using go.bug.st/serial
+type bugSerial struct {
+	port        serial.Port
+	readTimeout time.Duration
+	deadline    time.Time
+}
+
+// OpenSerial opens a serial port with 8 data bits, no parity and one stop bit. A read timeout
+// of 0 makes Read block until data is available.
+func OpenSerial(name string, baudRate int, readTimeout time.Duration) (Transport, error) {
+	port, err := serial.Open(name, &serial.Mode{BaudRate: baudRate})
+	if err != nil {
+		return nil, err
+	}
+	s := &bugSerial{port: port, readTimeout: readTimeout}
+	if err := port.SetReadTimeout(s.timeout(0)); err != nil {
+		_ = port.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// timeout returns the read timeout for go.bug.st/serial, limited by max if not 0
+func (q *bugSerial) timeout(max time.Duration) time.Duration {
+	d := q.readTimeout
+	if max > 0 && (d <= 0 || max < d) {
+		d = max
+	}
+	if d <= 0 {
+		return serial.NoTimeout
+	}
+	return d
+}
+
+// Read reads from the port and returns 0 bytes if the read timeout expired
+func (q *bugSerial) Read(b []byte) (int, error) {
+	if !q.deadline.IsZero() {
+		left := time.Until(q.deadline)
+		if left <= 0 {
+			return 0, os.ErrDeadlineExceeded
+		}
+		if err := q.port.SetReadTimeout(q.timeout(left)); err != nil {
+			return 0, err
+		}
+	}
+	return q.port.Read(b)
+}
+
+// Write writes to the port
+func (q *bugSerial) Write(b []byte) (int, error) {
+	return q.port.Write(b)
+}
+
+// Flush discards the input and output buffers of the port
+func (q *bugSerial) Flush() error {
+	if err := q.port.ResetInputBuffer(); err != nil {
+		return err
+	}
+	return q.port.ResetOutputBuffer()
+}
+
+// SetDeadline sets the deadline for Read
+func (q *bugSerial) SetDeadline(t time.Time) error {
+	q.deadline = t
+	if t.IsZero() {
+		return q.port.SetReadTimeout(q.timeout(0))
+	}
+	return nil
+}
+
+// Close closes the port
+func (q *bugSerial) Close() error {
+	return q.port.Close()
+}
+
+// ListSerialPorts returns the names of the serial ports of the system
+func ListSerialPorts() ([]string, error) {
+	return serial.GetPortsList()
+}
@@ -0,0 +1,38 @@
+package transport
+
+import (
+	"os"
+	"time"
+
+	"github.com/tarm/serial"
+)
+
+// tarmSerial is a serial port using github.com/tarm/serial
+type tarmSerial struct {
+	*serial.Port
+	deadline time.Time
+}
+
+// OpenTarmSerial opens a serial port using github.com/tarm/serial. The read timeout cannot be
+// changed after opening, so a deadline is only checked between two reads.
+func OpenTarmSerial(name string, baudRate int, readTimeout time.Duration) (Transport, error) {
+	port, err := serial.OpenPort(&serial.Config{Name: name, Baud: baudRate, ReadTimeout: readTimeout})
+	if err != nil {
+		return nil, err
+	}
+	return &tarmSerial{Port: port}, nil
+}
+
+// Read reads from the port unless the deadline expired
+func (q *tarmSerial) Read(b []byte) (int, error) {
+	if !q.deadline.IsZero() && !time.Now().Before(q.deadline) {
+		return 0, os.ErrDeadlineExceeded
+	}
+	return q.Port.Read(b)
+}
+
+// SetDeadline sets the deadline for Read
+func (q *tarmSerial) SetDeadline(t time.Time) error {
+	q.deadline = t
+	return nil
+}
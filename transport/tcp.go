@@ -0,0 +1,156 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultTCPPort is the port Trinamic Ethernet modules accept TMCL telegrams on
+const DefaultTCPPort = 1500
+
+// flushTimeout is how long Flush waits for more data to discard
+const flushTimeout = time.Millisecond
+
+// ErrNotConnected is returned by Read of a TCP connection that is not established
+var ErrNotConnected = errors.New("not connected")
+
+// tcpConn is a TCP connection that is established on the first write and re-established on
+// the next write after it broke
+type tcpConn struct {
+	address     string
+	dialTimeout time.Duration
+
+	mutex    sync.Mutex
+	conn     net.Conn
+	deadline time.Time
+	closed   bool
+}
+
+// NewTCP returns a TCP connection to the given address (host:port). The connection is
+// established with the first write and, if it breaks, re-established with the next write
+// after that.
+func NewTCP(address string, dialTimeout time.Duration) Transport {
+	return &tcpConn{address: address, dialTimeout: dialTimeout}
+}
+
+// connection returns the current connection, dialing a new one if requested
+func (q *tcpConn) connection(dial bool) (net.Conn, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return nil, os.ErrClosed
+	}
+	if q.conn != nil || !dial {
+		if q.conn == nil {
+			return nil, ErrNotConnected
+		}
+		return q.conn, nil
+	}
+
+	ctx := context.Background()
+	if q.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.dialTimeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", q.address)
+	if err != nil {
+		return nil, err
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetNoDelay(true)
+	}
+	_ = conn.SetReadDeadline(q.deadline)
+	q.conn = conn
+	return conn, nil
+}
+
+// broken drops the connection after an error other than a timeout
+func (q *tcpConn) broken(conn net.Conn, err error) {
+	if err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		return
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.conn == conn {
+		_ = conn.Close()
+		q.conn = nil
+	}
+}
+
+// Write sends data, connecting first if necessary
+func (q *tcpConn) Write(b []byte) (int, error) {
+	conn, err := q.connection(true)
+	if err != nil {
+		return 0, err
+	}
+	n, err := conn.Write(b)
+	q.broken(conn, err)
+	return n, err
+}
+
+// Read receives data, it blocks until data is available or the deadline expired
+func (q *tcpConn) Read(b []byte) (int, error) {
+	conn, err := q.connection(false)
+	if err != nil {
+		return 0, err
+	}
+	n, err := conn.Read(b)
+	q.broken(conn, err)
+	return n, err
+}
+
+// Flush discards data received but not read yet
+func (q *tcpConn) Flush() error {
+	conn, err := q.connection(false)
+	if err != nil {
+		return nil
+	}
+	defer func() {
+		q.mutex.Lock()
+		_ = conn.SetReadDeadline(q.deadline)
+		q.mutex.Unlock()
+	}()
+
+	var buf [64]byte
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(flushTimeout))
+		if _, err := conn.Read(buf[:]); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil
+			}
+			q.broken(conn, err)
+			return err
+		}
+	}
+}
+
+// SetDeadline sets the deadline for Read
+func (q *tcpConn) SetDeadline(t time.Time) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.deadline = t
+	if q.conn == nil {
+		return nil
+	}
+	return q.conn.SetReadDeadline(t)
+}
+
+// Close closes the connection, it is not re-established afterwards
+func (q *tcpConn) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	if q.conn == nil {
+		return nil
+	}
+	err := q.conn.Close()
+	q.conn = nil
+	return err
+}
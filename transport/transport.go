@@ -0,0 +1,22 @@
+// Package transport contains the connections TMCL telegrams are exchanged over
+package transport
+
+import (
+	"io"
+	"time"
+)
+
+// telegramSize is the length of a TMCL telegram on serial links
+const telegramSize = 9
+
+// Transport is a connection to one or several TMCL modules
+type Transport interface {
+	io.ReadWriteCloser
+
+	// Flush discards data received but not read yet and data written but not sent yet
+	Flush() error
+
+	// SetDeadline sets the time after which Read returns os.ErrDeadlineExceeded, a zero value
+	// disables the deadline
+	SetDeadline(t time.Time) error
+}
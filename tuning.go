@@ -0,0 +1,33 @@
+package tmcl
+
+import "time"
+
+// SetReadTimeout sets the read timeout of the serial port. With the default 0 a read blocks
+// until at least one byte was received, which gives the lowest latency. A positive value
+// lets the read return earlier so that the reply timeout is checked more often, but adds up
+// to that duration of latency. Takes effect the next time the port is opened.
+func (q *TMCL) SetReadTimeout(d time.Duration) {
+	q.do(globalKey, func() {
+		q.readTimeout = d
+	})
+}
+
+// SetPollInterval sets the pause between two reads when a read returned no data while
+// waiting for a reply (default 1ms)
+func (q *TMCL) SetPollInterval(d time.Duration) {
+	q.do(globalKey, func() {
+		q.pollInterval = d
+	})
+}
+
+// SetResyncLimit sets the number of bytes that are skipped at most to find the start of a
+// reply after garbage was received, e.g. because a byte was lost on the line (default 18).
+// With 0 a reply with invalid checksum is an error right away.
+func (q *TMCL) SetResyncLimit(n int) {
+	if n < 0 {
+		n = 0
+	}
+	q.do(globalKey, func() {
+		q.resyncLimit = n
+	})
+}
@@ -0,0 +1,133 @@
+package tmcl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SetupExpectations describes the configuration VerifySetup checks for
+type SetupExpectations struct {
+	// ModuleType is the expected module type, e.g. 351, 0 for any
+	ModuleType int
+
+	// MinFirmwareMajor and MinFirmwareMinor are the minimum firmware version
+	MinFirmwareMajor int
+	MinFirmwareMinor int
+
+	// AxisParams are axis parameters which must be within a range
+	AxisParams []ExpectedParam
+
+	// GlobalParams are global parameters which must be within a range, MotorOrBank being the bank
+	GlobalParams []ExpectedParam
+
+	// Switches are limit/reference switch states which must be read, e.g. to detect
+	// missing or inverted wiring while the axis is known to be away from the switches
+	Switches []ExpectedSwitch
+}
+
+// ExpectedParam is a parameter which must be within Min..Max
+type ExpectedParam struct {
+	MotorOrBank byte
+	Index       byte
+	Min         int
+	Max         int
+}
+
+// ExpectedSwitch is the expected state of a switch read via axis parameter 9, 10 or 11
+type ExpectedSwitch struct {
+	Motor byte
+	Index byte
+	State bool
+}
+
+// SetupCheck is the result of a single check of VerifySetup
+type SetupCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// SetupReport is the result of VerifySetup
+type SetupReport struct {
+	Checks []SetupCheck
+}
+
+// OK returns true if all checks passed
+func (r SetupReport) OK() bool {
+	for _, c := range r.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the checks which did not pass
+func (r SetupReport) Failed() []SetupCheck {
+	var failed []SetupCheck
+	for _, c := range r.Checks {
+		if !c.OK {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// add appends a check to the report
+func (r *SetupReport) add(name string, ok bool, detail string) {
+	r.Checks = append(r.Checks, SetupCheck{Name: name, OK: ok, Detail: detail})
+}
+
+// VerifySetup checks communication, firmware version, parameter values and switch states
+// before a machine is started. If the board does not respond, no further checks are made.
+func (q *TMCL) VerifySetup(exp SetupExpectations) SetupReport {
+	var r SetupReport
+
+	// communication and firmware
+	v, err := q.GetVersion()
+	if err != nil {
+		r.add("communication", false, err.Error())
+		return r
+	}
+	r.add("communication", true, "")
+	if exp.ModuleType != 0 {
+		r.add("module type", v.ModuleType == exp.ModuleType,
+			fmt.Sprintf("expected %d, found %d", exp.ModuleType, v.ModuleType))
+	}
+	if exp.MinFirmwareMajor != 0 || exp.MinFirmwareMinor != 0 {
+		r.add("firmware version", v.AtLeast(exp.MinFirmwareMajor, exp.MinFirmwareMinor),
+			fmt.Sprintf("expected at least %d.%02d, found %d.%02d", exp.MinFirmwareMajor, exp.MinFirmwareMinor, v.Major, v.Minor))
+	}
+
+	// parameters
+	for _, p := range exp.AxisParams {
+		name := "axis parameter " + strconv.Itoa(int(p.Index)) + " of motor " + strconv.Itoa(int(p.MotorOrBank))
+		r.checkParam(name, p, func() (int, error) { return q.GAP(p.Index, p.MotorOrBank) })
+	}
+	for _, p := range exp.GlobalParams {
+		name := "global parameter " + strconv.Itoa(int(p.Index)) + " of bank " + strconv.Itoa(int(p.MotorOrBank))
+		r.checkParam(name, p, func() (int, error) { return q.GGP(p.Index, p.MotorOrBank) })
+	}
+
+	// switches
+	for _, s := range exp.Switches {
+		name := "switch " + strconv.Itoa(int(s.Index)) + " of motor " + strconv.Itoa(int(s.Motor))
+		v, err := q.GAP(s.Index, s.Motor)
+		if err != nil {
+			r.add(name, false, err.Error())
+			continue
+		}
+		r.add(name, (v != 0) == s.State, fmt.Sprintf("expected %t, found %t", s.State, v != 0))
+	}
+	return r
+}
+
+// checkParam reads a parameter and checks its range
+func (r *SetupReport) checkParam(name string, p ExpectedParam, read func() (int, error)) {
+	v, err := read()
+	if err != nil {
+		r.add(name, false, err.Error())
+		return
+	}
+	r.add(name, v >= p.Min && v <= p.Max, fmt.Sprintf("expected %d..%d, found %d", p.Min, p.Max, v))
+}
@@ -0,0 +1,118 @@
+package tmcl
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Version is the module type and firmware revision of a module
+type Version struct {
+	ModuleType int
+	Major      int
+	Minor      int
+}
+
+// String formats the version like the module does, e.g. 351V4.45
+func (v Version) String() string {
+	return fmt.Sprintf("%dV%d.%02d", v.ModuleType, v.Major, v.Minor)
+}
+
+// AtLeast returns true if the firmware revision is major.minor or newer
+func (v Version) AtLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// ParseVersion parses a version string as returned by GetVersionString, e.g. 351V445,
+// 351V4.45 or 351V.445
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimRight(s, " \x00")
+	i := strings.IndexByte(s, 'V')
+	if i <= 0 {
+		return Version{}, errors.Errorf("invalid version string %q", s)
+	}
+	moduleType, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return Version{}, errors.Errorf("invalid module type in version string %q", s)
+	}
+	rev := strings.ReplaceAll(s[i+1:], ".", "")
+	if len(rev) < 3 {
+		return Version{}, errors.Errorf("invalid revision in version string %q", s)
+	}
+	major, err1 := strconv.Atoi(rev[:len(rev)-2])
+	minor, err2 := strconv.Atoi(rev[len(rev)-2:])
+	if err1 != nil || err2 != nil {
+		return Version{}, errors.Errorf("invalid revision in version string %q", s)
+	}
+	return Version{ModuleType: moduleType, Major: major, Minor: minor}, nil
+}
+
+// GetVersion returns module type and firmware revision, read in binary format
+func (q *TMCL) GetVersion() (Version, error) {
+	return q.GetVersionContext(context.Background())
+}
+
+// GetVersionContext is GetVersion with a context
+func (q *TMCL) GetVersionContext(ctx context.Context) (Version, error) {
+	v, err := q.ExecContext(ctx, 136, 1, 0, 0)
+	if err != nil {
+		return Version{}, err
+	}
+	return Version{
+		ModuleType: (v >> 16) & 0xFFFF,
+		Major:      (v >> 8) & 0xFF,
+		Minor:      v & 0xFF,
+	}, nil
+}
+
+// GetVersionString returns module type and firmware revision in string format, e.g. 351V445.
+// This reply is no regular telegram: host address and 8 characters without checksum, so it
+// cannot be told apart from replies of other modules on a bus.
+func (q *TMCL) GetVersionString() (string, error) {
+	if err := q.checkUsable(); err != nil {
+		return "", err
+	}
+	var v string
+	var err error
+	q.do(globalKey, func() {
+		if err = q.checkCommandFilter(136, 0); err != nil {
+			return
+		}
+		if err = q.OpenPort(); err != nil {
+			return
+		}
+		if err = q.transactSpecial(Request{Cmd: 136}); err != nil {
+			return
+		}
+		v = strings.TrimRight(string(q.rx[1:]), " \x00")
+	})
+	return v, err
+}
+
+// transactSpecial sends a request whose reply is no regular telegram but the host address
+// and 8 bytes without checksum, left in q.rx. Must be called with the command lock held.
+func (q *TMCL) transactSpecial(req Request) error {
+	q.encodeFrame(q.tx[:], req.Cmd, req.Type, req.MotorBank, req.Value)
+	if err := q.flushInput(); err != nil {
+		return err
+	}
+	sent := time.Now()
+	if err := q.writeFrame(q.tx[:]); err != nil {
+		return err
+	}
+	timeout := q.currentTimeout()
+	if t, ok := q.port.(deadliner); ok {
+		_ = t.SetDeadline(sent.Add(timeout))
+		defer func() { _ = t.SetDeadline(time.Time{}) }()
+	}
+	if err := q.readFull(context.Background(), q.rx[:], 0, sent, timeout); err != nil {
+		q.desynced = true
+		return err
+	}
+	q.rtt.add(time.Since(sent))
+	return nil
+}
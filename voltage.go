@@ -0,0 +1,92 @@
+package tmcl
+
+import (
+	"context"
+	"time"
+)
+
+// VoltageMonitor configures the supervision of the supply voltage
+type VoltageMonitor struct {
+	// Port and Bank of the supply voltage input, on the TMCM-351 port 4 of AnalogInputBank
+	Port byte
+	Bank byte
+
+	// Scale converts the raw value to volts, 0 meaning the raw value is used
+	Scale float64
+
+	// Low and High are the thresholds, 0 disabling the respective check
+	Low  float64
+	High float64
+
+	// Hysteresis the voltage must return inside the thresholds before OnNormal is called
+	Hysteresis float64
+
+	// Interval between two readings
+	Interval time.Duration
+
+	// OnLow and OnHigh are called once when the voltage leaves the allowed range, OnNormal
+	// when it returns into the range
+	OnLow    func(volts float64)
+	OnHigh   func(volts float64)
+	OnNormal func(volts float64)
+
+	// OnError is called if the voltage could not be read
+	OnError func(err error)
+}
+
+// voltageState is the last state reported by the voltage monitor
+type voltageState int
+
+const (
+	voltageNormal voltageState = iota
+	voltageLow
+	voltageHigh
+)
+
+// MonitorSupplyVoltage reads the supply voltage periodically until the context is done and
+// reports brown outs and over voltage via the callbacks
+func (q *TMCL) MonitorSupplyVoltage(ctx context.Context, cfg VoltageMonitor) error {
+	scale := cfg.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	state := voltageNormal
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		raw, err := q.exec(15, cfg.Port, cfg.Bank, 0)
+		if err != nil {
+			if cfg.OnError != nil {
+				cfg.OnError(err)
+			}
+			continue
+		}
+		volts := float64(raw) * scale
+
+		switch {
+		case cfg.Low != 0 && volts < cfg.Low:
+			if state != voltageLow && cfg.OnLow != nil {
+				cfg.OnLow(volts)
+			}
+			state = voltageLow
+		case cfg.High != 0 && volts > cfg.High:
+			if state != voltageHigh && cfg.OnHigh != nil {
+				cfg.OnHigh(volts)
+			}
+			state = voltageHigh
+		case state == voltageLow && volts >= cfg.Low+cfg.Hysteresis,
+			state == voltageHigh && volts <= cfg.High-cfg.Hysteresis:
+			if cfg.OnNormal != nil {
+				cfg.OnNormal(volts)
+			}
+			state = voltageNormal
+		}
+	}
+}
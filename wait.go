@@ -0,0 +1,74 @@
+package tmcl
+
+import (
+	"context"
+	"time"
+)
+
+// defaultWaitPollInterval is the pause between two polls while waiting for a motor
+const defaultWaitPollInterval = 10 * time.Millisecond
+
+// WaitPositionReached polls the target position reached flag (axis parameter 8) of a motor
+// until it is set or the context is done. With an interval of 0 it polls every 10ms.
+func (q *TMCL) WaitPositionReached(ctx context.Context, motor byte, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		pollInterval = defaultWaitPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		v, err := q.GAPContext(ctx, 8, motor)
+		if err != nil {
+			return err
+		}
+		if v != 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// MoveOptions configure MoveAbsWait and MoveRelWait
+type MoveOptions struct {
+	// Timeout limits the time to wait for the target position, 0 waits until the context is done
+	Timeout time.Duration
+
+	// PollInterval is the pause between two polls of the position reached flag, 0 meaning 10ms
+	PollInterval time.Duration
+
+	// StopOnCancel stops the motor if the target is not reached in time or the context is done
+	StopOnCancel bool
+}
+
+// MoveAbsWait moves a motor to an absolute position and waits until it is reached
+func (q *TMCL) MoveAbsWait(ctx context.Context, motor byte, position int, opts MoveOptions) error {
+	return q.moveWait(ctx, ABS, motor, position, opts)
+}
+
+// MoveRelWait moves a motor by an offset and waits until the target is reached
+func (q *TMCL) MoveRelWait(ctx context.Context, motor byte, offset int, opts MoveOptions) error {
+	return q.moveWait(ctx, REL, motor, offset, opts)
+}
+
+// moveWait issues MVP and waits for the target position
+func (q *TMCL) moveWait(ctx context.Context, mode byte, motor byte, value int, opts MoveOptions) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	if err := q.MVPContext(ctx, mode, motor, value); err != nil {
+		return err
+	}
+
+	err := q.WaitPositionReached(ctx, motor, opts.PollInterval)
+	if err != nil && ctx.Err() != nil && opts.StopOnCancel {
+		_ = q.MST(motor)
+	}
+	return err
+}
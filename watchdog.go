@@ -0,0 +1,108 @@
+package tmcl
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// OutputState is the value a digital output is set to
+type OutputState struct {
+	Port  byte
+	Bank  byte
+	Value bool
+}
+
+// WatchdogConfig configures the host watchdog
+type WatchdogConfig struct {
+	// Timeout is the time without KeepAlive or command after which the watchdog fires
+	Timeout time.Duration
+
+	// Motors are stopped when the watchdog fires
+	Motors []byte
+
+	// Outputs are set when the watchdog fires, after the motors were stopped
+	Outputs []OutputState
+
+	// OnTrigger is called after the safe state was applied, err being the first error
+	// that occurred while doing so
+	OnTrigger func(err error)
+}
+
+// StartWatchdog starts a dead-man switch: if neither KeepAlive is called nor a command is
+// executed within the timeout, all configured motors are stopped and the outputs are set to
+// their safe states. The watchdog fires once and is armed again by the next activity.
+func (q *TMCL) StartWatchdog(cfg WatchdogConfig) {
+	q.StopWatchdog()
+	q.KeepAlive()
+
+	q.watchdogMutex.Lock()
+	defer q.watchdogMutex.Unlock()
+	stop := make(chan struct{})
+	q.watchdogStop = stop
+	go q.runWatchdog(cfg, stop)
+}
+
+// StopWatchdog stops the watchdog
+func (q *TMCL) StopWatchdog() {
+	q.watchdogMutex.Lock()
+	defer q.watchdogMutex.Unlock()
+	if q.watchdogStop != nil {
+		close(q.watchdogStop)
+		q.watchdogStop = nil
+	}
+}
+
+// KeepAlive signals that the application is still alive and resets the watchdog
+func (q *TMCL) KeepAlive() {
+	atomic.StoreInt64(&q.lastActivity, time.Now().UnixNano())
+}
+
+// runWatchdog checks for activity until stop is closed
+func (q *TMCL) runWatchdog(cfg WatchdogConfig, stop chan struct{}) {
+	interval := cfg.Timeout / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var fired int64
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		last := atomic.LoadInt64(&q.lastActivity)
+		if last == fired || time.Since(time.Unix(0, last)) < cfg.Timeout {
+			continue
+		}
+		fired = last
+
+		err := q.applySafeState(cfg.Motors, cfg.Outputs)
+		if cfg.OnTrigger != nil {
+			cfg.OnTrigger(err)
+		}
+	}
+}
+
+// applySafeState stops the motors and sets the outputs, returning the first error
+func (q *TMCL) applySafeState(motors []byte, outputs []OutputState) error {
+	var firstErr error
+	for _, motor := range motors {
+		if _, err := q.exec(3, 0, motor, 0); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, o := range outputs {
+		var v int
+		if o.Value {
+			v = 1
+		}
+		if _, err := q.exec(14, o.Port, o.Bank, v); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
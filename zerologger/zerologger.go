@@ -0,0 +1,41 @@
+// Package zerologger writes the communication events of a TMCL connection to a zerolog logger
+package zerologger
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+
+	tmcl "github.com/raceresult/go-tmcl"
+)
+
+// logger implements tmcl.Logger
+type logger struct {
+	l zerolog.Logger
+}
+
+// New returns a tmcl.Logger writing to l: successful commands at debug level, timeouts and
+// retries as warnings and other failures as errors
+func New(l zerolog.Logger) tmcl.Logger {
+	return logger{l: l}
+}
+
+// Command implements tmcl.Logger
+func (q logger) Command(req tmcl.Request, value int, d time.Duration) {
+	q.l.Debug().Stringer("request", req).Int("reply", value).Dur("latency", d).Msg("tmcl command")
+}
+
+// Error implements tmcl.Logger
+func (q logger) Error(req tmcl.Request, err error, d time.Duration) {
+	q.l.Error().Stringer("request", req).Err(err).Dur("latency", d).Msg("tmcl command failed")
+}
+
+// Timeout implements tmcl.Logger
+func (q logger) Timeout(req tmcl.Request, d time.Duration) {
+	q.l.Warn().Stringer("request", req).Dur("timeout", d).Msg("tmcl command timed out")
+}
+
+// Retry implements tmcl.Logger
+func (q logger) Retry(req tmcl.Request, attempt int, err error) {
+	q.l.Warn().Stringer("request", req).Int("attempt", attempt).Err(err).Msg("tmcl command repeated")
+}